@@ -0,0 +1,235 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/jobs"
+	"github.com/market-league/internal/user"
+	"github.com/market-league/internal/utils"
+)
+
+// JobHandler Interface
+type JobHandlerInterface interface {
+	TriggerJob(conn *ws.Connection, rawData json.RawMessage) error
+	GetJobRuns(conn *ws.Connection, rawData json.RawMessage) error
+}
+
+// Compile-time check
+var _ JobHandlerInterface = (*JobHandler)(nil)
+
+// defaultRunsLimit and maxRunsLimit bound how many runs GetJobRuns returns
+const (
+	defaultRunsLimit = 50
+	maxRunsLimit     = 200
+)
+
+// JobHandler defines the handler for admins running background jobs by hand.
+type JobHandler struct {
+	runner   *jobs.Runner
+	runs     *jobs.RunRepository
+	userRepo *user.UserRepository
+}
+
+// NewJobHandler creates a new instance of JobHandler.
+func NewJobHandler(runner *jobs.Runner, runs *jobs.RunRepository, userRepo *user.UserRepository) *JobHandler {
+	return &JobHandler{runner: runner, runs: runs, userRepo: userRepo}
+}
+
+// * Implementation of Interface
+
+// TriggerJob handles an admin running a job and the jobs depending on it now. Each job's start and result
+// is streamed to the caller as it happens, and the results of the whole run are sent once it is done.
+func (h *JobHandler) TriggerJob(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint   `json:"user_id"` // Optional: must be the signed-in user
+		Job      string `json:"job" binding:"required"`
+		Date     string `json:"date"`      // YYYY-MM-DD, a past trading day for a dated job to make up
+		LeagueID uint   `json:"league_id"` // Limits jobs that work league by league to one league
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_TriggerJob, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Only admins may run jobs
+	adminID, err := h.requireAdmin(conn, ws.MessageType_Admin_TriggerJob, request.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Step 4: Check the job and the day
+	if !h.runner.HasJob(request.Job) {
+		ws.SendError(conn, ws.MessageType_Admin_TriggerJob, fmt.Sprintf("Unknown job %s", request.Job))
+		return fmt.Errorf("unknown job %s", request.Job)
+	}
+	run := jobs.ManualRun{LeagueID: request.LeagueID}
+	if request.Date != "" {
+		if !h.runner.IsDated(request.Job) {
+			ws.SendError(conn, ws.MessageType_Admin_TriggerJob, fmt.Sprintf("Job %s does not take a date", request.Job))
+			return fmt.Errorf("job %s does not take a date", request.Job)
+		}
+		runTime, err := dailyRunOn(request.Date)
+		if err != nil {
+			ws.SendError(conn, ws.MessageType_Admin_TriggerJob, err.Error())
+			return fmt.Errorf("invalid date: %v", err)
+		}
+		run.Days = []time.Time{runTime}
+	}
+
+	// Step 5: Run the job in the background so the connection keeps serving, streaming each step
+	run.Progress = func(result jobs.RunResult) {
+		resultJSON, err := json.Marshal(result)
+		if err != nil {
+			log.Printf("Failed to serialize progress of job %s: %v", result.Job, err)
+			return
+		}
+		progress := ws.WebsocketMessage{Type: ws.MessageType_Admin_JobProgress, Data: json.RawMessage(resultJSON)}
+		if err := conn.WriteJSON(progress); err != nil {
+			log.Printf("Failed to send progress of job %s: %v", result.Job, err)
+		}
+	}
+	log.Printf("User %d triggered job %s", adminID, request.Job)
+	go func() {
+		results, err := h.runner.TriggerManual(context.Background(), request.Job, run)
+		if errors.Is(err, jobs.ErrRunningElsewhere) {
+			sendRunError(conn, "The job is running on another server")
+			return
+		}
+		if err != nil {
+			sendRunError(conn, err.Error())
+			return
+		}
+
+		// Step 6: Send the results of the run back via WebSocket
+		resultsJSON, err := json.Marshal(results)
+		if err != nil {
+			sendRunError(conn, "Failed to serialize job results")
+			return
+		}
+		response := ws.WebsocketMessage{Type: ws.MessageType_Admin_TriggerJob, Data: json.RawMessage(resultsJSON)}
+		if err := conn.WriteJSON(response); err != nil {
+			log.Printf("Failed to send results of job %s: %v", request.Job, err)
+		}
+	}()
+
+	return nil
+}
+
+// GetJobRuns handles an admin listing the latest runs of a job, or of every job.
+func (h *JobHandler) GetJobRuns(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID uint   `json:"user_id"` // Optional: must be the signed-in user
+		Job    string `json:"job"`     // Empty for every job
+		Limit  int    `json:"limit"`   // Defaults to 50, at most 200
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_GetJobRuns, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Only admins may see job runs
+	if _, err := h.requireAdmin(conn, ws.MessageType_Admin_GetJobRuns, request.UserID); err != nil {
+		return err
+	}
+
+	// Step 4: Process business logic
+	limit := request.Limit
+	if limit <= 0 {
+		limit = defaultRunsLimit
+	}
+	if limit > maxRunsLimit {
+		limit = maxRunsLimit
+	}
+	runs, err := h.runs.GetRecentRuns(request.Job, limit)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_GetJobRuns, err.Error())
+		return fmt.Errorf("failed to get job runs: %v", err)
+	}
+
+	// Step 5: Marshal the runs into JSON
+	runsJSON, err := json.Marshal(runs)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_GetJobRuns, "Failed to serialize job runs")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 6: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Admin_GetJobRuns,
+		Data: json.RawMessage(runsJSON),
+	}
+	if err := conn.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// * Helper functions
+
+// requireAdmin sends an error of messageType unless the connection signed in as an admin, and returns that
+// admin's user ID. A user ID in the request must name that same user.
+func (h *JobHandler) requireAdmin(conn *ws.Connection, messageType string, userID uint) (uint, error) {
+	userID, err := conn.User(userID)
+	if err != nil {
+		ws.SendError(conn, messageType, err.Error())
+		return 0, err
+	}
+	isAdmin, err := h.userRepo.IsAdmin(userID)
+	if err != nil {
+		ws.SendError(conn, messageType, err.Error())
+		return 0, fmt.Errorf("failed to check admin status: %v", err)
+	}
+	if !isAdmin {
+		ws.SendError(conn, messageType, "Admin access required")
+		return 0, fmt.Errorf("user %d is not an admin", userID)
+	}
+	return userID, nil
+}
+
+// sendRunError sends an error about a triggered run. The run reports from the background, so the error is
+// written under the connection's write lock like its progress.
+func sendRunError(conn *ws.Connection, errorMsg string) {
+	errorJSON, err := json.Marshal(gin.H{"type": ws.MessageType_Error, "message": errorMsg})
+	if err != nil {
+		log.Println("Failed to serialize error data:", err)
+		return
+	}
+	response := ws.WebsocketMessage{Type: ws.MessageType_Admin_TriggerJob, Data: json.RawMessage(errorJSON)}
+	if err := conn.WriteJSON(response); err != nil {
+		log.Println("Failed to send error message:", err)
+	}
+}
+
+// dailyRunOn returns the daily run time of a past trading day given as YYYY-MM-DD
+func dailyRunOn(date string) (time.Time, error) {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	day, err := time.ParseInLocation("2006-01-02", date, location)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("date must be YYYY-MM-DD: %v", err)
+	}
+	if !utils.IsTradingDay(day) {
+		return time.Time{}, fmt.Errorf("%s is not a trading day", date)
+	}
+	runTime := utils.DailyRunTime(day)
+	if runTime.After(utils.Now()) {
+		return time.Time{}, fmt.Errorf("the daily run of %s has not happened yet", date)
+	}
+	return runTime, nil
+}
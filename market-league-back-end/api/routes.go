@@ -1,18 +1,28 @@
 package api
 
 import (
+	"context"
+	"log"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/market-league/internal/auth"
+	corporate_action "github.com/market-league/internal/corporate_action"
 	"github.com/market-league/internal/db"
+	"github.com/market-league/internal/jobs"
 	"github.com/market-league/internal/league"
 	league_portfolio "github.com/market-league/internal/league_portfolio"
+	"github.com/market-league/internal/lineup"
+	"github.com/market-league/internal/marketdata"
 	ownership_history "github.com/market-league/internal/ownership_history"
 	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/scoring"
 	"github.com/market-league/internal/stock"
 	"github.com/market-league/internal/trade"
 	"github.com/market-league/internal/user"
+	"github.com/market-league/internal/utils"
 )
 
 func RegisterRoutes(router *gin.Engine) {
@@ -34,28 +44,72 @@ func RegisterRoutes(router *gin.Engine) {
 
 	// * DEPENDENCIES *
 
+	// Initialize Market Data Provider
+	marketDataProvider, err := marketdata.NewProvider(marketdata.ConfigFromEnv())
+	if err != nil {
+		// Keep serving leagues and recorded prices, only fetching new market data fails
+		log.Printf("Failed to create market data provider, running without market data: %v", err)
+		marketDataProvider = marketdata.NewUnavailableProvider(err)
+	}
+	// A replay runs the whole app on its simulated clock
+	if clock, ok := marketdata.ProviderClock(marketDataProvider); ok {
+		utils.SetClock(clock)
+		log.Printf("Replaying market data from %s", utils.Now().Format(time.RFC3339))
+	}
+
 	// Initialize Stock Dependencies
 	stockRepo := stock.NewStockRepository(database)
-	stockService := stock.NewStockService(stockRepo)
-	stockHandler := stock.NewStockHandler(stockService)
+	stockService := stock.NewStockService(stockRepo, marketDataProvider)
 
 	// Initialize User Dependencies
 	userRepo := user.NewUserRepository(database)
 	userService := user.NewUserService(userRepo)
 	userHandler := user.NewUserHandler(userService)
+	if admins := os.Getenv("ADMIN_USERNAMES"); admins != "" {
+		if _, err := userRepo.GrantAdmin(strings.Split(admins, ",")); err != nil {
+			log.Printf("Error granting admin: %v", err)
+		}
+	}
+	stockHandler := stock.NewStockHandler(stockService, userRepo)
 
 	// Initialize OwnershipHistory
 	ownershipHistoryRepo := ownership_history.NewOwnershipHistoryRepository(database)
 	ownershipHistoryService := ownership_history.NewOwnershipHistoryService(ownershipHistoryRepo, stockRepo)
 
+	// Initialize Corporate Action Dependencies
+	corporateActionRepo := corporate_action.NewCorporateActionRepository(database)
+	corporateActionService := corporate_action.NewCorporateActionService(corporateActionRepo, stockRepo, marketDataProvider)
+	corporateActionHandler := corporate_action.NewCorporateActionHandler(corporateActionService)
+	if path := os.Getenv("CORPORATE_ACTIONS_CSV"); path != "" {
+		count, err := corporateActionService.ImportCSVFile(path)
+		if err != nil {
+			log.Printf("Error importing corporate actions from %s: %v", path, err)
+		} else {
+			log.Printf("Imported %d corporate actions from %s", count, path)
+		}
+	}
+
+	// Initialize Lineup Dependencies
+	lineupRepo := lineup.NewLineupRepository(database)
+	lineupService := lineup.NewLineupService(lineupRepo, stockRepo)
+	lineupHandler := lineup.NewLineupHandler(lineupService)
+	if err := lineupService.BackfillScoringWindows(); err != nil {
+		log.Printf("Error backfilling scoring windows: %v", err)
+	}
+
 	// Initialize Portfolio Dependencies
 	portfolioRepo := portfolio.NewPortfolioRepository(database)
-	portfolioService := portfolio.NewPortfolioService(portfolioRepo, ownershipHistoryRepo)
+	portfolioService := portfolio.NewPortfolioService(portfolioRepo, ownershipHistoryRepo, lineupRepo)
 	portfolioHandler := portfolio.NewPortfolioHandler(portfolioService)
 
+	// Initialize Scoring Dependencies
+	scoringRepo := scoring.NewScoringRepository(database)
+	scoringService := scoring.NewScoringService(scoringRepo)
+	scoringHandler := scoring.NewScoringHandler(scoringService, userRepo)
+
 	// Initialize Trade Dependencies
 	tradeRepo := trade.NewTradeRepository(database)
-	tradeService := trade.NewTradeService(tradeRepo, stockRepo, portfolioRepo, userRepo, ownershipHistoryService)
+	tradeService := trade.NewTradeService(tradeRepo, stockRepo, portfolioRepo, userRepo, ownershipHistoryService, lineupService)
 	tradeHandler := trade.NewTradeHandler(tradeService)
 
 	// Initialize League and LeaguePortfolio Dependencies
@@ -63,12 +117,22 @@ func RegisterRoutes(router *gin.Engine) {
 	leaguePortfolioRepository := league_portfolio.NewLeaguePortfolioRepository(database)
 
 	leagueService := league.NewLeagueService(leagueRepo, userRepo, portfolioRepo, nil)
-	leaguePortfolioService := league_portfolio.NewLeaguePortfolioService(leaguePortfolioRepository, stockRepo, portfolioRepo, ownershipHistoryService, leagueService)
+	leaguePortfolioService := league_portfolio.NewLeaguePortfolioService(leaguePortfolioRepository, stockRepo, portfolioRepo, ownershipHistoryService, lineupService, leagueService, userRepo)
 	leagueService.SetLeaguePortfolioService(leaguePortfolioService)
+	leagueService.SetInviteLinkBase(os.Getenv("INVITE_LINK_BASE_URL"))
 
 	leagueHandler := league.NewLeagueHandler(leagueService, portfolioService, leaguePortfolioService)
 	leaguePortfolioHandler := league_portfolio.NewLeaguePortfolioHandler(leaguePortfolioService)
 
+	// Initialize Job Dependencies, the scheduler registers its jobs on the runner admins trigger them through
+	schedulerLocation, err := SchedulerLocation()
+	if err != nil {
+		log.Fatalf("Failed to load scheduler timezone: %v", err)
+	}
+	jobRunner := jobs.NewRunner(schedulerLocation)
+	jobRunRepo := jobs.NewRunRepository(database)
+	jobHandler := NewJobHandler(jobRunner, jobRunRepo, userRepo)
+
 	webSocketHandler := NewWebSocketHandler(
 		portfolioHandler,
 		stockHandler,
@@ -76,6 +140,11 @@ func RegisterRoutes(router *gin.Engine) {
 		tradeHandler,
 		leaguePortfolioHandler,
 		leagueHandler,
+		lineupHandler,
+		corporateActionHandler,
+		scoringHandler,
+		jobHandler,
+		authService,
 	)
 
 	// WebSocket endpoint
@@ -86,9 +155,24 @@ func RegisterRoutes(router *gin.Engine) {
 		db:                      database,
 		StockService:            stockService,
 		stockRepo:               stockRepo,
+		marketData:              marketDataProvider,
+		quoteFetcher:            marketdata.NewQuoteFetcher(marketDataProvider, marketdata.FetcherConfigFromEnv()),
 		ownershipHistoryService: ownershipHistoryService,
+		lineupService:           lineupService,
+		corporateActionService:  corporateActionService,
 		portfolioService:        portfolioService,
+		scoringService:          scoringService,
+		leagueService:           leagueService,
+		runStore:                jobRunRepo,
+		locker:                  jobs.NewAdvisoryLocker(database),
+		runner:                  jobRunner,
+	}
+	intradayInterval := time.Duration(0)
+	if os.Getenv("INTRADAY_POLLING") == "true" {
+		intradayInterval = IntradayPollInterval()
+	}
+	if err := scheduler.Start(context.Background(), intradayInterval); err != nil {
+		log.Fatalf("Failed to start scheduler: %v", err)
 	}
-	scheduler.StartDailyTask()
 
 }
@@ -1,16 +1,27 @@
 package api
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
 	// "github.com/market-league/internal/models"
+	ws "github.com/market-league/api/websocket"
+	corporate_action "github.com/market-league/internal/corporate_action"
+	"github.com/market-league/internal/jobs"
+	"github.com/market-league/internal/league"
+	"github.com/market-league/internal/lineup"
+	"github.com/market-league/internal/marketdata"
 	"github.com/market-league/internal/models"
 	ownership_history "github.com/market-league/internal/ownership_history"
 	"github.com/market-league/internal/portfolio"
-	"github.com/market-league/internal/services"
+	"github.com/market-league/internal/scoring"
 	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -18,131 +29,445 @@ type Scheduler struct {
 	db                      *gorm.DB
 	StockService            *stock.StockService
 	stockRepo               *stock.StockRepository
+	marketData              marketdata.MarketDataProvider
+	quoteFetcher            *marketdata.QuoteFetcher
 	ownershipHistoryService ownership_history.OwnershipHistoryServiceInterface
+	lineupService           lineup.LineupServiceInterface
+	corporateActionService  corporate_action.CorporateActionServiceInterface
 	portfolioService        *portfolio.PortfolioService
+	scoringService          scoring.ScoringServiceInterface
+	leagueService           *league.LeagueService
+	runStore                *jobs.RunRepository
+	locker                  jobs.Locker
+	runner                  *jobs.Runner
 }
 
-func (s *Scheduler) StartDailyTask() {
-	go func() {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Printf("Scheduler recovered from panic: %v", r)
-			}
-		}()
-
-		for {
-			// Load the timezone
-			location, err := time.LoadLocation("America/Chicago")
-			if err != nil {
-				log.Printf("Error loading time location: %v", err)
-				return
-			}
+// SchedulerLocation is the timezone job schedules are read in, SCHEDULER_TIMEZONE or the market timezone
+func SchedulerLocation() (*time.Location, error) {
+	if name := os.Getenv("SCHEDULER_TIMEZONE"); name != "" {
+		return time.LoadLocation(name)
+	}
+	return utils.MarketLocation()
+}
 
-			// Get the current time
-			now := time.Now().In(location)
+// Job names, the daily run is a chain of them so each step only runs once the steps it needs have succeeded
+const (
+	JobLeagueStatuses    = "league-statuses"
+	JobLeagueLifecycle   = "league-lifecycle"
+	JobCorporateActions  = "corporate-actions"
+	JobDailyPrices       = "daily-prices"
+	JobOwnershipValues   = "ownership-values"
+	JobScoringWindows    = "scoring-windows"
+	JobPortfolioTotals   = "portfolio-totals"
+	JobPointsBackfill    = "points-backfill"
+	JobPriceCompaction   = "price-compaction"
+	JobIntradayPrices    = "intraday-prices"
+	dailyPricesTimeout   = 30 * time.Minute
+	dailyDatabaseTimeout = 10 * time.Minute
+)
 
-			// Check if today is a weekday (Monday=1, Sunday=7)
-			if now.Weekday() == time.Saturday || now.Weekday() == time.Sunday {
-				log.Printf("Skipping task execution as today is a weekend: %s", now.Weekday())
-				// Sleep until the next day
-				time.Sleep(24 * time.Hour)
-				continue
-			}
+// dailyRunSpec is when the daily run starts, after the close, on trading days only
+var dailyRunSpec = fmt.Sprintf("%d %d * * *", utils.DailyRunMinute, utils.DailyRunHour)
 
-			// Calculate the next run time (set to the next day at 9:00 AM)
-			nextRun := time.Date(now.Year(), now.Month(), now.Day(), 9, 0, 0, 0, location)
-			if now.After(nextRun) {
-				nextRun = nextRun.Add(24 * time.Hour)
-			}
+// leagueCompletionSpec is when ended leagues are completed, an hour after the daily run
+var leagueCompletionSpec = fmt.Sprintf("%d %d * * *", utils.DailyRunMinute, utils.DailyRunHour+1)
 
-			log.Printf("Current time: %s, Next run at: %s", now.Format("15:04:05"), nextRun.Format("15:04:05"))
+// leagueLifecycleInterval is how often drafts due to start and rosters due to lock are looked for
+const leagueLifecycleInterval = time.Minute
 
-			// Wait until the next scheduled time
-			time.Sleep(time.Until(nextRun))
+// priceCompactionSpec is when old prices are compacted, at night when nothing else runs
+const priceCompactionSpec = "0 2 * * *"
 
-			// Update league statuses
-			s.updateLeagueStatuses(location)
+// defaultCatchUpDays is how far back missed runs are made up unless JOB_CATCH_UP_DAYS says otherwise
+const defaultCatchUpDays = 10
 
-			// Fetch companies from the database
-			companies, err := s.stockRepo.GetAllStocks()
-			if err != nil {
-				log.Printf("Error fetching stocks from database: %v", err)
-				continue
-			}
+// CatchUpWindow reads JOB_CATCH_UP_DAYS, falling back to the default
+func CatchUpWindow() time.Duration {
+	days, err := strconv.Atoi(os.Getenv("JOB_CATCH_UP_DAYS"))
+	if err != nil || days < 0 {
+		days = defaultCatchUpDays
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
 
-			log.Printf("Total companies to process: %d", len(companies))
+// Start registers the background jobs on the scheduler's runner and runs them on their schedules until ctx is done.
+// Intraday polling is only registered when intradayInterval is above zero.
+func (s *Scheduler) Start(ctx context.Context, intradayInterval time.Duration) error {
+	marketLocation, err := utils.MarketLocation()
+	if err != nil {
+		return fmt.Errorf("failed to load market timezone: %w", err)
+	}
+	// The daily run follows the market close, wherever the scheduler's timezone puts it
+	dailyRun := jobs.TradingDays(jobs.In(marketLocation, jobs.MustParseCron(dailyRunSpec)))
 
-			// Process each company
-			for _, company := range companies {
-				quote, err := services.GetTestStock(company.TickerSymbol)
+	if s.runStore != nil {
+		s.runner.SetStore(s.runStore, CatchUpWindow())
+	}
+	// With several servers on one database, each job runs on whichever takes its lock
+	if s.locker != nil {
+		s.runner.SetLocker(s.locker)
+	}
+	registered := []jobs.Job{
+		{
+			// Split-adjust prices and credit dividends before new quotes arrive
+			Name:     JobCorporateActions,
+			Schedule: dailyRun,
+			Timeout:  dailyDatabaseTimeout,
+			CatchUp:  true,
+			Run:      func(ctx context.Context) error { return s.processCorporateActions(marketLocation) },
+		},
+		{
+			// Record the day's official prices, stamped at the day's run time so a recompute reads them
+			// back as that day's prices rather than the last intraday tick before them, and a retry
+			// replaces them. Days missed while the server was down, or given to a manual run, get their
+			// closing prices instead.
+			Name:      JobDailyPrices,
+			DependsOn: []string{JobCorporateActions},
+			Timeout:   dailyPricesTimeout,
+			Dated:     true,
+			Run: func(ctx context.Context) error {
+				info := jobs.Info(ctx)
+				if len(info.Missed) > 0 {
+					return s.backfillPrices(ctx, marketLocation, info.Missed)
+				}
+				runTime := dailyPriceTime(info, marketLocation)
+				return s.updatePrices(ctx, marketLocation, &runTime)
+			},
+		},
+		{
+			Name:      JobOwnershipValues,
+			DependsOn: []string{JobDailyPrices},
+			Timeout:   dailyDatabaseTimeout,
+			Run: func(ctx context.Context) error {
+				return s.ownershipHistoryService.UpdateActiveOwnershipHistoryCurrentPrices()
+			},
+		},
+		{
+			Name:      JobScoringWindows,
+			DependsOn: []string{JobDailyPrices},
+			Timeout:   dailyDatabaseTimeout,
+			Run:       func(ctx context.Context) error { return s.lineupService.UpdateActiveScoringWindowPrices() },
+		},
+		{
+			Name:      JobPortfolioTotals,
+			DependsOn: []string{JobOwnershipValues, JobScoringWindows},
+			Timeout:   dailyDatabaseTimeout,
+			Run: func(ctx context.Context) error {
+				return s.portfolioService.CalculateAllPortfolioTotalValues(jobs.DayKey(ctx))
+			},
+		},
+		{
+			// Complete the leagues whose last day has ended, with a final scoring and their awards. It runs on
+			// its own after the daily run has had time to finish, so a price outage does not hold leagues open.
+			Name:     JobLeagueStatuses,
+			Schedule: jobs.In(marketLocation, jobs.MustParseCron(leagueCompletionSpec)),
+			Timeout:  dailyDatabaseTimeout,
+			CatchUp:  true,
+			Run:      func(ctx context.Context) error { return s.completeLeagues(ctx, marketLocation) },
+		},
+		{
+			// Start drafts whose draft time has come and lock the rosters of leagues that have started. Most
+			// runs find nothing to do and are left out of the run history.
+			Name:         JobLeagueLifecycle,
+			Schedule:     jobs.Every(leagueLifecycleInterval),
+			Timeout:      leagueLifecycleInterval,
+			SkipIdleRuns: true,
+			Run:          func(ctx context.Context) error { return s.advanceLeagues(ctx) },
+		},
+		{
+			// Fill in the points of days missed while the server was down, from the backfilled prices.
+			// A manual run rescores the days it is given, or today, for one league or all of them.
+			Name:      JobPointsBackfill,
+			DependsOn: []string{JobPortfolioTotals},
+			Timeout:   dailyPricesTimeout,
+			Dated:     true,
+			Run: func(ctx context.Context) error {
+				info := jobs.Info(ctx)
+				days := info.Missed
+				if info.Trigger == jobs.TriggerManual && len(days) == 0 {
+					days = []time.Time{info.ScheduledFor}
+				}
+				return s.backfillPoints(ctx, marketLocation, days, info.LeagueID)
+			},
+		},
+		{
+			// Roll raw prices past the retention window into daily bars
+			Name:     JobPriceCompaction,
+			Schedule: jobs.MustParseCron(priceCompactionSpec),
+			Timeout:  dailyPricesTimeout,
+			CatchUp:  true,
+			Run: func(ctx context.Context) error {
+				result, err := s.StockService.CompactPriceHistory(stock.PriceHistoryRetentionDays())
 				if err != nil {
-					log.Printf("Error fetching stock data for %s: %v", company.TickerSymbol, err)
-					continue
+					return err
 				}
+				log.Printf("Price history: %s", result)
+				return nil
+			},
+		},
+	}
+	if intradayInterval > 0 {
+		// Each poll writes price history and active ownership values and pushes ticks to subscribed
+		// clients. Points are still only recorded by the daily run after the close.
+		registered = append(registered, jobs.Job{
+			Name:     JobIntradayPrices,
+			Schedule: jobs.WhileMarketOpen(jobs.Every(intradayInterval)),
+			Timeout:  intradayInterval,
+			Run: func(ctx context.Context) error {
+				// A split going ex today is applied before its first quote, which would otherwise be checked
+				// against the pre-split price and quarantined until the daily run
+				if err := s.corporateActionService.ApplyPendingActions(); err != nil {
+					log.Printf("Error applying corporate actions before polling: %v", err)
+				}
+				if err := s.updatePrices(ctx, marketLocation, nil); err != nil {
+					return err
+				}
+				return s.ownershipHistoryService.UpdateActiveOwnershipHistoryCurrentPrices()
+			},
+		})
+	}
 
-				log.Printf("Fetched stock data for %s: Current Price: %.2f", company.TickerSymbol, *quote.C)
+	for _, job := range registered {
+		if err := s.runner.Register(job); err != nil {
+			return err
+		}
+	}
+	return s.runner.Start(ctx)
+}
 
-				// Update stock price in the database
-				updated_time := time.Now().In(location)
-				err = s.StockService.UpdateStockPrice(company.ID, float64(*quote.C), &updated_time)
-				if err != nil {
-					log.Printf("Failed to update stock price for %s: %v", company.TickerSymbol, err)
-				}
+// dailyPriceTime is the time a run of the daily prices job stamps prices with. A manual run after the close
+// of a trading day stamps them like that day's scheduled run, so it replaces its prices.
+func dailyPriceTime(info jobs.RunInfo, location *time.Location) time.Time {
+	at := info.ScheduledFor.In(location)
+	if info.Trigger != jobs.TriggerManual || !utils.IsTradingDay(at) {
+		return at
+	}
+	if runTime := utils.DailyRunTime(at); !at.Before(runTime) {
+		return runTime
+	}
+	return at
+}
+
+// defaultIntradayPollInterval is how often prices are polled while the market is open unless
+// INTRADAY_POLL_MINUTES says otherwise
+const defaultIntradayPollInterval = 5 * time.Minute
+
+// IntradayPollInterval reads INTRADAY_POLL_MINUTES, falling back to the default
+func IntradayPollInterval() time.Duration {
+	if minutes, err := strconv.Atoi(os.Getenv("INTRADAY_POLL_MINUTES")); err == nil && minutes > 0 {
+		return time.Duration(minutes) * time.Minute
+	}
+	return defaultIntradayPollInterval
+}
+
+// updatePrices quotes every stock, records the prices and pushes them to subscribed clients. Prices are
+// stamped with timestamp, or with the time each quote arrived when it is nil. It fails if no stock got a price.
+func (s *Scheduler) updatePrices(ctx context.Context, location *time.Location, timestamp *time.Time) error {
+	// Fetch companies from the database, delisted ones have no quotes
+	companies, err := s.stockRepo.GetActiveStocks()
+	if err != nil {
+		return fmt.Errorf("error fetching stocks from database: %w", err)
+	}
+
+	log.Printf("Total companies to process: %d, provider: %s", len(companies), s.marketData.Name())
+
+	// Fetch every company's quote concurrently, within the provider's rate limit
+	stockIDs := make(map[string]uint, len(companies))
+	symbols := make([]string, 0, len(companies))
+	for _, company := range companies {
+		stockIDs[company.TickerSymbol] = company.ID
+		symbols = append(symbols, company.TickerSymbol)
+	}
+
+	var mutex sync.Mutex
+	ticks := make([]models.PriceTick, 0, len(companies))
+	summary := s.quoteFetcher.FetchQuotes(ctx, symbols, func(symbol string, quote *marketdata.Quote) error {
+		// Update stock price in the database
+		updatedTime := utils.Now().In(location)
+		if timestamp != nil {
+			updatedTime = *timestamp
+		}
+		// Suspicious quotes are held back for review and count as failures
+		if _, err := s.StockService.RecordQuote(stockIDs[symbol], quote.Current, &updatedTime); err != nil {
+			return err
+		}
+
+		mutex.Lock()
+		ticks = append(ticks, newPriceTick(stockIDs[symbol], symbol, quote, updatedTime))
+		mutex.Unlock()
+		return nil
+	})
+	log.Printf("Price update: %s", summary)
+	for _, failure := range summary.Failures {
+		log.Printf("No price for %s after %d attempts: %s", failure.Symbol, failure.Attempts, failure.Error)
+	}
+
+	ws.Manager.BroadcastPriceTicks(ticks)
+	jobs.AddItems(ctx, summary.Succeeded)
+	if summary.Requested > 0 && summary.Succeeded == 0 {
+		return fmt.Errorf("no prices recorded: %s", summary)
+	}
+	return nil
+}
+
+// backfillPrices records the closing price of every stock on each missed day from the provider's daily
+// candles, stamped at the missed run time. Days that already have a price at that time are left alone.
+func (s *Scheduler) backfillPrices(ctx context.Context, location *time.Location, missed []time.Time) error {
+	if len(missed) == 0 {
+		return nil
+	}
+	companies, err := s.stockRepo.GetActiveStocks()
+	if err != nil {
+		return fmt.Errorf("error fetching stocks from database: %w", err)
+	}
+	stockIDs := make(map[string]uint, len(companies))
+	symbols := make([]string, 0, len(companies))
+	for _, company := range companies {
+		stockIDs[company.TickerSymbol] = company.ID
+		symbols = append(symbols, company.TickerSymbol)
+	}
+
+	// Missed run times by trading day
+	runTimes := make(map[string]time.Time, len(missed))
+	for _, runTime := range missed {
+		runTimes[runTime.In(location).Format(marketdata.DateFormat)] = runTime
+	}
+	// Providers stamp daily candles at midnight UTC or midnight market time, so start a day early
+	first := missed[0].In(location)
+	from := time.Date(first.Year(), first.Month(), first.Day()-1, 0, 0, 0, 0, location)
+	to := missed[len(missed)-1]
+
+	log.Printf("Backfilling closing prices of %d stocks for %d missed days", len(symbols), len(missed))
+	summary := s.quoteFetcher.FetchCandles(ctx, symbols, marketdata.ResolutionDay, from, to, func(symbol string, candles []marketdata.Candle) error {
+		for _, candle := range candles {
+			// Either way the UTC date of the stamp is the trading day
+			runTime, ok := runTimes[candle.Timestamp.UTC().Format(marketdata.DateFormat)]
+			if !ok || candle.Close <= 0 {
+				continue
 			}
-			err = s.ownershipHistoryService.UpdateActiveOwnershipHistoryCurrentPrices()
+			added, err := s.stockRepo.RecordHistoricalPrice(stockIDs[symbol], candle.Close, runTime)
 			if err != nil {
-				fmt.Printf("unable to update active ownershipHistory prices! %v", err)
+				return err
 			}
-
-			err = s.portfolioService.CalculateAllPortfolioTotalValues()
-			if err != nil {
-				fmt.Printf("unable to update total portfolio values! %v", err)
+			if added {
+				jobs.AddItems(ctx, 1)
 			}
-
-			log.Printf("Task completed. Waiting for the next interval.")
 		}
-	}()
+		return nil
+	})
+	log.Printf("Price backfill: %s", summary)
+	for _, failure := range summary.Failures {
+		log.Printf("No closing prices for %s after %d attempts: %s", failure.Symbol, failure.Attempts, failure.Error)
+	}
+	if summary.Requested > 0 && summary.Succeeded == 0 {
+		return fmt.Errorf("no closing prices backfilled: %s", summary)
+	}
+	return nil
 }
 
-func (s *Scheduler) updateLeagueStatuses(location *time.Location) {
-	// Get the current date (without time component)
-	now := time.Now().In(location)
-	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+// backfillPoints rebuilds the points history of the leagues that were running on the missed days, or of one
+// league when leagueID is set. Completed leagues keep their final points.
+func (s *Scheduler) backfillPoints(ctx context.Context, location *time.Location, missed []time.Time, leagueID uint) error {
+	if len(missed) == 0 {
+		return nil
+	}
+	from := missed[0].In(location)
+	to := missed[len(missed)-1].In(location)
 
-	// Query to find leagues whose end date is today
-	var leagues []struct {
-		ID          int
-		LeagueName  string
-		EndDate     time.Time
-		LeagueState models.LeagueState
+	var leagueIDs []uint
+	query := s.db.Table("leagues").
+		Where("start_date <= ? AND end_date >= ?", to, time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, location)).
+		Where("league_state != ?", models.Completed)
+	if leagueID != 0 {
+		query = query.Where("id = ?", leagueID)
+	}
+	if err := query.Pluck("id", &leagueIDs).Error; err != nil {
+		return fmt.Errorf("error finding leagues to backfill: %w", err)
 	}
 
-	result := s.db.Table("leagues").
-		Select("id, league_name, end_date, league_state").
-		Where("DATE(end_date) <= DATE(?)", today).
-		Find(&leagues)
+	// One league failing does not hold up the rest
+	var failed error
+	for _, leagueID := range leagueIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		result, err := s.scoringService.Recompute(leagueID, from.Format(scoring.RecomputeDateFormat), to.Format(scoring.RecomputeDateFormat), false)
+		if err != nil {
+			log.Printf("Error backfilling points of league %d: %v", leagueID, err)
+			failed = fmt.Errorf("error backfilling points of league %d: %w", leagueID, err)
+			continue
+		}
+		log.Printf("Backfilled %d days of points for league %d", result.Days, leagueID)
+		jobs.AddItems(ctx, 1)
+	}
+	return failed
+}
 
-	if result.Error != nil {
-		log.Printf("Error finding leagues to update: %v", result.Error)
-		return
+// newPriceTick describes a recorded quote for clients
+func newPriceTick(stockID uint, symbol string, quote *marketdata.Quote, at time.Time) models.PriceTick {
+	tick := models.PriceTick{
+		StockID:       stockID,
+		TickerSymbol:  symbol,
+		Price:         quote.Current,
+		PreviousClose: quote.PreviousClose,
+		Timestamp:     at,
 	}
+	if quote.PreviousClose > 0 {
+		tick.Change = quote.Current - quote.PreviousClose
+		tick.PercentChange = tick.Change / quote.PreviousClose * 100
+	}
+	return tick
+}
 
-	// Update leagues that end today
-	for _, league := range leagues {
-		if league.LeagueState != models.Completed {
-			log.Printf("Updating league '%s' (ID: %d) status to 'completed' as its end date is today",
-				league.LeagueName, league.ID)
+// completeLeagues completes the leagues whose end date is on or before the day of the run. The final scoring
+// takes the place of the day's portfolio totals rather than adding a second entry for the same day.
+func (s *Scheduler) completeLeagues(ctx context.Context, location *time.Location) error {
+	scheduledFor := jobs.Info(ctx).ScheduledFor
+	day := scheduledFor.In(location)
+	nextMidnight := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, location)
+	runKey := jobs.DayKeyFor(JobPortfolioTotals, scheduledFor)
+	completed, err := s.leagueService.CompleteDueLeagues(nextMidnight, runKey, s.portfolioService)
+	jobs.AddItems(ctx, completed)
+	return err
+}
 
-			// Update the league status
-			updateResult := s.db.Table("leagues").
-				Where("id = ?", league.ID).
-				Update("league_state", models.Completed)
+// advanceLeagues starts the drafts and locks the rosters that are due, one failing does not hold up the other
+func (s *Scheduler) advanceLeagues(ctx context.Context) error {
+	now := utils.Now()
+	started, draftErr := s.leagueService.StartDueDrafts(now)
+	jobs.AddItems(ctx, started)
+	locked, lockErr := s.leagueService.LockDueRosters(now)
+	jobs.AddItems(ctx, locked)
+	if draftErr != nil {
+		return draftErr
+	}
+	return lockErr
+}
 
-			if updateResult.Error != nil {
-				log.Printf("Error updating league status: %v", updateResult.Error)
-			} else {
-				log.Printf("Successfully updated league '%s' status to 'completed'", league.LeagueName)
-			}
+// corporateActionLookback is how far back the provider is asked for dividends and splits each run
+const corporateActionLookback = 7 * 24 * time.Hour
+
+// processCorporateActions records new dividends and splits and applies the ones gone ex. A failed provider
+// sync is only logged, since actions imported by other means can still be applied.
+func (s *Scheduler) processCorporateActions(location *time.Location) error {
+	// Provider sync is opt-in since dividend and split endpoints are not on every provider plan
+	if os.Getenv("CORPORATE_ACTIONS_SYNC") == "true" {
+		now := utils.Now().In(location)
+		count, err := s.corporateActionService.SyncFromProvider(now.Add(-corporateActionLookback), now)
+		if err != nil {
+			log.Printf("Error syncing corporate actions: %v", err)
+		} else {
+			log.Printf("Recorded %d new corporate actions", count)
 		}
 	}
+
+	if err := s.corporateActionService.ApplyPendingActions(); err != nil {
+		return fmt.Errorf("error applying corporate actions: %w", err)
+	}
+
+	// Ticker changes and delistings announced ahead take effect before the day's quotes are fetched
+	if err := s.StockService.ApplyPendingUniverseChanges(); err != nil {
+		return fmt.Errorf("error applying universe changes: %w", err)
+	}
+	return nil
 }
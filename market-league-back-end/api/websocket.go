@@ -4,13 +4,17 @@ import (
 	"encoding/json"
 	"log"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 	ws "github.com/market-league/api/websocket"
+	corporate_action "github.com/market-league/internal/corporate_action"
 	"github.com/market-league/internal/league"
 	league_portfolio "github.com/market-league/internal/league_portfolio"
+	"github.com/market-league/internal/lineup"
 	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/scoring"
 	"github.com/market-league/internal/stock"
 	"github.com/market-league/internal/trade"
 	"github.com/market-league/internal/user"
@@ -24,6 +28,16 @@ type WebSocketHandler struct {
 	tradeHandler           trade.TradeHandlerInterface
 	leaguePortfolioHandler league_portfolio.LeaguePortfolioHandlerInterface
 	leagueHandler          league.LeagueHandlerInterface
+	lineupHandler          lineup.LineupHandlerInterface
+	corporateActionHandler corporate_action.CorporateActionHandlerInterface
+	scoringHandler         scoring.ScoringHandlerInterface
+	jobHandler             JobHandlerInterface
+	tokenParser            TokenParser
+}
+
+// TokenParser reads the user ID out of the token a user signed in with
+type TokenParser interface {
+	ParseJWT(tokenString string) (uint, error)
 }
 
 func NewWebSocketHandler(
@@ -33,6 +47,11 @@ func NewWebSocketHandler(
 	tradeHandler trade.TradeHandlerInterface,
 	leaguePortfolioHandler league_portfolio.LeaguePortfolioHandlerInterface,
 	leagueHandler league.LeagueHandlerInterface,
+	lineupHandler lineup.LineupHandlerInterface,
+	corporateActionHandler corporate_action.CorporateActionHandlerInterface,
+	scoringHandler scoring.ScoringHandlerInterface,
+	jobHandler JobHandlerInterface,
+	tokenParser TokenParser,
 ) *WebSocketHandler {
 	return &WebSocketHandler{
 		portfolioHandler:       portfolioHandler,
@@ -41,6 +60,11 @@ func NewWebSocketHandler(
 		tradeHandler:           tradeHandler,
 		leaguePortfolioHandler: leaguePortfolioHandler,
 		leagueHandler:          leagueHandler,
+		lineupHandler:          lineupHandler,
+		corporateActionHandler: corporateActionHandler,
+		scoringHandler:         scoringHandler,
+		jobHandler:             jobHandler,
+		tokenParser:            tokenParser,
 	}
 }
 
@@ -61,6 +85,8 @@ func (h *WebSocketHandler) routeTransmission(conn *ws.Connection, message ws.Web
 		return h.portfolioHandler.RemoveStockFromPortfolio(conn, message.Data)
 	case ws.MessageType_Portfolio_GetPortfolioPointsHistory:
 		return h.portfolioHandler.GetPortfolioPointsHistory(conn, message.Data)
+	case ws.MessageType_Portfolio_GetPointsBreakdown:
+		return h.portfolioHandler.GetPointsBreakdown(conn, message.Data)
 	case ws.MessageType_Portfolio_GetStocksValueChange:
 		return h.portfolioHandler.GetStocksValueChange(conn, message.Data)
 
@@ -73,8 +99,14 @@ func (h *WebSocketHandler) routeTransmission(conn *ws.Connection, message ws.Web
 		return h.stockHandler.UpdatePrice(conn, message.Data)
 	case ws.MessageType_Stock_GetStockInformation:
 		return h.stockHandler.GetStockInfo(conn, message.Data)
+	case ws.MessageType_Stock_GetPriceCandles:
+		return h.stockHandler.GetPriceCandles(conn, message.Data)
 	case ws.MessageType_Stock_GetAllStocks:
 		return h.stockHandler.GetAllStocks(conn, message.Data)
+	case ws.MessageType_Stock_SubscribeToPrices:
+		return h.stockHandler.SubscribeToPrices(conn, message.Data)
+	case ws.MessageType_Stock_UnsubscribeToPrices:
+		return h.stockHandler.UnsubscribeToPrices(conn, message.Data)
 
 	// User Routes
 	case ws.MessageType_User_UserInfo:
@@ -99,6 +131,32 @@ func (h *WebSocketHandler) routeTransmission(conn *ws.Connection, message ws.Web
 		return h.leaguePortfolioHandler.DraftStock(conn, message.Data)
 	case ws.MessageType_LeaguePortfolio_GetLeaguePortfolioInfo:
 		return h.leaguePortfolioHandler.GetLeaguePortfolioInfo(conn, message.Data)
+	case ws.MessageType_LeaguePortfolio_SetDraftPool:
+		return h.leaguePortfolioHandler.SetDraftPool(conn, message.Data)
+
+	// Corporate Action Routes
+	case ws.MessageType_CorporateAction_GetStockActions:
+		return h.corporateActionHandler.GetStockActions(conn, message.Data)
+
+	// Admin Routes
+	case ws.MessageType_Admin_RecomputeScores:
+		return h.scoringHandler.RecomputeScores(conn, message.Data)
+	case ws.MessageType_Admin_ImportUniverse:
+		return h.stockHandler.ImportUniverse(conn, message.Data)
+	case ws.MessageType_Admin_GetQuarantinedQuotes:
+		return h.stockHandler.GetQuarantinedQuotes(conn, message.Data)
+	case ws.MessageType_Admin_ReviewQuarantinedQuote:
+		return h.stockHandler.ReviewQuarantinedQuote(conn, message.Data)
+	case ws.MessageType_Admin_TriggerJob:
+		return h.jobHandler.TriggerJob(conn, message.Data)
+	case ws.MessageType_Admin_GetJobRuns:
+		return h.jobHandler.GetJobRuns(conn, message.Data)
+
+	// Lineup Routes
+	case ws.MessageType_Lineup_GetLineup:
+		return h.lineupHandler.GetLineup(conn, message.Data)
+	case ws.MessageType_Lineup_SwapStocks:
+		return h.lineupHandler.SwapStocks(conn, message.Data)
 
 	// League Routes
 	case ws.MessageType_League_CreateLeague:
@@ -107,16 +165,42 @@ func (h *WebSocketHandler) routeTransmission(conn *ws.Connection, message ws.Web
 		return h.leagueHandler.RemoveLeague(conn, message.Data)
 	case ws.MessageType_League_AddUserToLeague:
 		return h.leagueHandler.AddUserToLeague(conn, message.Data)
+	case ws.MessageType_League_CreateInvite:
+		return h.leagueHandler.CreateInvite(conn, message.Data)
+	case ws.MessageType_League_GetInvites:
+		return h.leagueHandler.GetInvites(conn, message.Data)
+	case ws.MessageType_League_RevokeInvite:
+		return h.leagueHandler.RevokeInvite(conn, message.Data)
+	case ws.MessageType_League_RedeemInvite:
+		return h.leagueHandler.RedeemInvite(conn, message.Data)
+	case ws.MessageType_League_LeaveLeague:
+		return h.leagueHandler.LeaveLeague(conn, message.Data)
+	case ws.MessageType_League_KickMember:
+		return h.leagueHandler.KickMember(conn, message.Data)
+	case ws.MessageType_League_TransferOwnership:
+		return h.leagueHandler.TransferOwnership(conn, message.Data)
+	case ws.MessageType_League_UpdateSettings:
+		return h.leagueHandler.UpdateSettings(conn, message.Data)
 	case ws.MessageType_League_GetDetails:
 		return h.leagueHandler.GetLeagueDetails(conn, message.Data)
 	case ws.MessageType_League_GetLeaderboard:
 		return h.leagueHandler.GetLeaderboard(conn, message.Data)
+	case ws.MessageType_League_GetResults:
+		return h.leagueHandler.GetLeagueResults(conn, message.Data)
 	case ws.MessageType_League_QueueUp:
 		return h.leagueHandler.QueueUp(conn, message.Data)
 	case ws.MessageType_League_Portfolios:
 		return h.leagueHandler.GetPlayerPortfoliosInLeague(conn, message.Data)
 	case ws.MessageType_League_GetAllLeagues:
 		return h.leagueHandler.GetAllLeagues(conn, message.Data)
+	case ws.MessageType_League_RequestToJoin:
+		return h.leagueHandler.RequestToJoin(conn, message.Data)
+	case ws.MessageType_League_GetJoinRequests:
+		return h.leagueHandler.GetJoinRequests(conn, message.Data)
+	case ws.MessageType_League_ApproveJoinRequest:
+		return h.leagueHandler.ApproveJoinRequest(conn, message.Data)
+	case ws.MessageType_League_DenyJoinRequest:
+		return h.leagueHandler.DenyJoinRequest(conn, message.Data)
 	case ws.MessageType_League_SubscribeToLeague:
 		return h.leagueHandler.SubscribeToLeague(conn, message.Data)
 	case ws.MessageType_League_UnsubscribeToLeague:
@@ -141,6 +225,18 @@ var upgrader = websocket.Upgrader{
 
 // HandleWebSocket - Handles incoming WebSocket connections
 func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
+	// Identify the user from their token before upgrading. Browsers cannot set headers on a WebSocket, so the
+	// token can also come as a query parameter. Without a token the connection can only read public data.
+	var userID uint
+	if token := websocketToken(c); token != "" {
+		parsedID, err := h.tokenParser.ParseJWT(token)
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			return
+		}
+		userID = parsedID
+	}
+
 	// Upgrade HTTP request to WebSocket.
 	rawConn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -150,8 +246,10 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 
 	// Create our custom Connection with an empty subscriptions map.
 	conn := &ws.Connection{
-		Ws:            rawConn,
-		Subscriptions: make(map[uint]bool),
+		Ws:                 rawConn,
+		Subscriptions:      make(map[uint]bool),
+		PriceSubscriptions: make(map[uint]bool),
+		UserID:             userID,
 	}
 
 	// Close handler
@@ -193,3 +291,11 @@ func (h *WebSocketHandler) HandleWebSocket(c *gin.Context) {
 		}
 	}
 }
+
+// websocketToken returns the token of a WebSocket request from its Authorization header or token query parameter
+func websocketToken(c *gin.Context) string {
+	if header := c.GetHeader("Authorization"); strings.HasPrefix(header, "Bearer ") {
+		return strings.TrimPrefix(header, "Bearer ")
+	}
+	return c.Query("token")
+}
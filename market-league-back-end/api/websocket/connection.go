@@ -1,6 +1,7 @@
 package ws
 
 import (
+	"errors"
 	"sync"
 
 	"github.com/gorilla/websocket"
@@ -11,6 +12,29 @@ type Connection struct {
 	Ws            *websocket.Conn
 	Subscriptions map[uint]bool // key: leagueID, value: true if subscribed
 	writeMutex    sync.Mutex    // Mutex to protect writes to this connection
+
+	PriceSubscriptions map[uint]bool // key: stockID, guarded by the manager's mutex
+
+	UserID uint // The user whose token opened the connection, 0 when it connected without one
+}
+
+// User returns the user a request acts as, which is always the user the connection signed in as. A request
+// may still name its user, as long as it names the signed-in one; a userID of 0 names nobody.
+func (c *Connection) User(userID uint) (uint, error) {
+	if c.UserID == 0 {
+		return 0, errors.New("sign in to do that")
+	}
+	if userID != 0 && userID != c.UserID {
+		return 0, errors.New("the request is for a different user than the one signed in")
+	}
+	return c.UserID, nil
+}
+
+// WriteJSON writes a message under the connection's write lock, for replies sent from outside the read loop
+func (c *Connection) WriteJSON(message interface{}) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	return c.Ws.WriteJSON(message)
 }
 
 // Then update the BroadcastToLeague method in manager.go
@@ -1,7 +1,11 @@
 package ws
 
 import (
+	"encoding/json"
+	"log"
 	"sync"
+
+	"github.com/market-league/internal/models"
 )
 
 type WebSocketManager struct {
@@ -25,3 +29,70 @@ func (m *WebSocketManager) Unregister(conn *Connection) {
 	delete(m.connections, conn)
 	conn.Ws.Close()
 }
+
+// SubscribeToPrices adds stocks to the price ticks a connection receives
+func (m *WebSocketManager) SubscribeToPrices(conn *Connection, stockIDs []uint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if conn.PriceSubscriptions == nil {
+		conn.PriceSubscriptions = make(map[uint]bool)
+	}
+	for _, stockID := range stockIDs {
+		conn.PriceSubscriptions[stockID] = true
+	}
+}
+
+// UnsubscribeToPrices removes stocks from the price ticks a connection receives, or every stock when none are given
+func (m *WebSocketManager) UnsubscribeToPrices(conn *Connection, stockIDs []uint) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if len(stockIDs) == 0 {
+		conn.PriceSubscriptions = make(map[uint]bool)
+		return
+	}
+	for _, stockID := range stockIDs {
+		delete(conn.PriceSubscriptions, stockID)
+	}
+}
+
+// BroadcastPriceTicks sends each connection the ticks of the stocks it subscribed to, in one message
+func (m *WebSocketManager) BroadcastPriceTicks(ticks []models.PriceTick) {
+	if len(ticks) == 0 {
+		return
+	}
+
+	// Pick out each connection's ticks while holding the lock, then write without it
+	m.mutex.Lock()
+	outgoing := make(map[*Connection][]models.PriceTick)
+	for conn := range m.connections {
+		for _, tick := range ticks {
+			if conn.PriceSubscriptions[tick.StockID] {
+				outgoing[conn] = append(outgoing[conn], tick)
+			}
+		}
+	}
+	m.mutex.Unlock()
+
+	for conn, connTicks := range outgoing {
+		ticksJSON, err := json.Marshal(connTicks)
+		if err != nil {
+			log.Println("Failed to serialize price ticks:", err)
+			continue
+		}
+		message := WebsocketMessage{
+			Type: MessageType_Stock_PriceTicks,
+			Data: json.RawMessage(ticksJSON),
+		}
+
+		conn.writeMutex.Lock()
+		err = conn.Ws.WriteJSON(message)
+		conn.writeMutex.Unlock()
+
+		if err != nil {
+			m.mutex.Lock()
+			delete(m.connections, conn)
+			m.mutex.Unlock()
+			conn.Ws.Close()
+		}
+	}
+}
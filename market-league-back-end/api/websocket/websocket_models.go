@@ -21,6 +21,7 @@ const (
 	MessageType_Portfolio_RemoveStock               = "MessageType_Portfolio_RemoveStock"
 	MessageType_Portfolio_GetPortfolioPointsHistory = "MessageType_Portfolio_GetPortfolioPointsHistory"
 	MessageType_Portfolio_GetStocksValueChange      = "MessageType_Portfolio_GetStocksValueChange"
+	MessageType_Portfolio_GetPointsBreakdown        = "MessageType_Portfolio_GetPointsBreakdown"
 
 	// Stock Routes
 	MessageType_Stock_CreateStock             = "MessageType_Stock_CreateStock"
@@ -28,6 +29,10 @@ const (
 	MessageType_Stock_GetStockInformation     = "MessageType_Stock_GetStockInformation"
 	MessageType_Stock_UpdateCurrentStockPrice = "MessageType_Stock_UpdateCurrentStockPrice"
 	MessageType_Stock_GetAllStocks            = "MessageType_Stock_GetAllStocks"
+	MessageType_Stock_SubscribeToPrices       = "MessageType_Stock_SubscribeToPrices"
+	MessageType_Stock_UnsubscribeToPrices     = "MessageType_Stock_UnsubscribeToPrices"
+	MessageType_Stock_PriceTicks              = "MessageType_Stock_PriceTicks"
+	MessageType_Stock_GetPriceCandles         = "MessageType_Stock_GetPriceCandles"
 
 	// User Routes
 	MessageType_User_UserInfo       = "MessageType_User_UserInfo"
@@ -43,6 +48,23 @@ const (
 	// League Portfolio Routes
 	MessageType_LeaguePortfolio_DraftStock             = "MessageType_LeaguePortfolio_DraftStock"
 	MessageType_LeaguePortfolio_GetLeaguePortfolioInfo = "MessageType_LeaguePortfolio_GetLeaguePortfolioInfo"
+	MessageType_LeaguePortfolio_SetDraftPool           = "MessageType_LeaguePortfolio_SetDraftPool"
+
+	// Corporate Action Routes
+	MessageType_CorporateAction_GetStockActions = "MessageType_CorporateAction_GetStockActions"
+
+	// Admin Routes
+	MessageType_Admin_RecomputeScores        = "MessageType_Admin_RecomputeScores"
+	MessageType_Admin_ImportUniverse         = "MessageType_Admin_ImportUniverse"
+	MessageType_Admin_GetQuarantinedQuotes   = "MessageType_Admin_GetQuarantinedQuotes"
+	MessageType_Admin_ReviewQuarantinedQuote = "MessageType_Admin_ReviewQuarantinedQuote"
+	MessageType_Admin_TriggerJob             = "MessageType_Admin_TriggerJob"
+	MessageType_Admin_JobProgress            = "MessageType_Admin_JobProgress"
+	MessageType_Admin_GetJobRuns             = "MessageType_Admin_GetJobRuns"
+
+	// Lineup Routes
+	MessageType_Lineup_GetLineup  = "MessageType_Lineup_GetLineup"
+	MessageType_Lineup_SwapStocks = "MessageType_Lineup_SwapStocks"
 
 	// League Routes
 	MessageType_League_CreateLeague        = "MessageType_League_CreateLeague"
@@ -57,6 +79,19 @@ const (
 	MessageType_League_GetAllLeagues       = "MessageType_League_GetAllLeagues"
 	MessageType_League_SubscribeToLeague   = "MessageType_League_SubscribeToLeague"
 	MessageType_League_UnsubscribeToLeague = "MessageType_League_UnsubscribeToLeague"
+	MessageType_League_GetResults          = "MessageType_League_GetResults"
+	MessageType_League_CreateInvite        = "MessageType_League_CreateInvite"
+	MessageType_League_GetInvites          = "MessageType_League_GetInvites"
+	MessageType_League_RevokeInvite        = "MessageType_League_RevokeInvite"
+	MessageType_League_RedeemInvite        = "MessageType_League_RedeemInvite"
+	MessageType_League_RequestToJoin       = "MessageType_League_RequestToJoin"
+	MessageType_League_GetJoinRequests     = "MessageType_League_GetJoinRequests"
+	MessageType_League_ApproveJoinRequest  = "MessageType_League_ApproveJoinRequest"
+	MessageType_League_DenyJoinRequest     = "MessageType_League_DenyJoinRequest"
+	MessageType_League_LeaveLeague         = "MessageType_League_LeaveLeague"
+	MessageType_League_KickMember          = "MessageType_League_KickMember"
+	MessageType_League_TransferOwnership   = "MessageType_League_TransferOwnership"
+	MessageType_League_UpdateSettings      = "MessageType_League_UpdateSettings"
 
 	// Error Message
 	MessageType_Error = "MessageType_Error"
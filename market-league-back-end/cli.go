@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/market-league/internal/db"
+	"github.com/market-league/internal/scoring"
+	"github.com/market-league/internal/stock"
+)
+
+// runCommand runs a maintenance subcommand instead of the server, returning false if args name none
+func runCommand(args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, nil
+	}
+
+	switch args[0] {
+	case "recompute":
+		return true, runRecompute(args[1:])
+	case "import-universe":
+		return true, runImportUniverse(args[1:])
+	case "compact-prices":
+		return true, runCompactPrices(args[1:])
+	default:
+		return false, nil
+	}
+}
+
+// runRecompute rebuilds the points history of a league and prints the changes as JSON
+func runRecompute(args []string) error {
+	flags := flag.NewFlagSet("recompute", flag.ContinueOnError)
+	leagueID := flags.Uint("league", 0, "ID of the league to recompute")
+	from := flags.String("from", "", "first day to recompute (YYYY-MM-DD)")
+	to := flags.String("to", "", "last day to recompute (YYYY-MM-DD)")
+	dryRun := flags.Bool("dry-run", false, "print the changes without writing them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *leagueID == 0 || *from == "" || *to == "" {
+		flags.Usage()
+		return fmt.Errorf("-league, -from and -to are required")
+	}
+
+	db.InitDB()
+	service := scoring.NewScoringService(scoring.NewScoringRepository(db.GetDB()))
+	result, err := service.Recompute(*leagueID, *from, *to, *dryRun)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// runImportUniverse loads a universe of stocks from a CSV file and prints what changed as JSON
+func runImportUniverse(args []string) error {
+	flags := flag.NewFlagSet("import-universe", flag.ContinueOnError)
+	universe := flags.String("universe", "", "name of the universe, such as sp500")
+	file := flags.String("file", "", "CSV file of the universe's stocks")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *universe == "" || *file == "" {
+		flags.Usage()
+		return fmt.Errorf("-universe and -file are required")
+	}
+
+	db.InitDB()
+	service := stock.NewStockService(stock.NewStockRepository(db.GetDB()), nil)
+	result, err := service.ImportUniverseFile(*universe, *file)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
+
+// runCompactPrices rolls raw prices older than the retention window into daily bars and prints what was compacted as JSON
+func runCompactPrices(args []string) error {
+	flags := flag.NewFlagSet("compact-prices", flag.ContinueOnError)
+	retentionDays := flags.Int("retention-days", stock.PriceHistoryRetentionDays(), "days of raw prices to keep")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *retentionDays < stock.StockInfoHistoryDays {
+		flags.Usage()
+		return fmt.Errorf("-retention-days must be at least %d", stock.StockInfoHistoryDays)
+	}
+
+	db.InitDB()
+	service := stock.NewStockService(stock.NewStockRepository(db.GetDB()), nil)
+	result, err := service.CompactPriceHistory(*retentionDays)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(result)
+}
@@ -0,0 +1,67 @@
+package corporateaction
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ws "github.com/market-league/api/websocket"
+)
+
+// CorporateActionHandler Interface
+type CorporateActionHandlerInterface interface {
+	GetStockActions(conn *ws.Connection, rawData json.RawMessage) error
+}
+
+// Compile-time check
+var _ CorporateActionHandlerInterface = (*CorporateActionHandler)(nil)
+
+// CorporateActionHandler defines the handler for corporate action operations.
+type CorporateActionHandler struct {
+	service CorporateActionServiceInterface
+}
+
+// NewCorporateActionHandler creates a new instance of CorporateActionHandler.
+func NewCorporateActionHandler(service CorporateActionServiceInterface) *CorporateActionHandler {
+	return &CorporateActionHandler{service: service}
+}
+
+// * Implementation of Interface
+
+// GetStockActions handles fetching the dividends and splits of a stock.
+func (h *CorporateActionHandler) GetStockActions(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		StockID uint `json:"stock_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_CorporateAction_GetStockActions, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	actions, err := h.service.GetActionsForStock(request.StockID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_CorporateAction_GetStockActions, err.Error())
+		return fmt.Errorf("failed to retrieve corporate actions: %v", err)
+	}
+
+	// Step 4: Marshal the actions into JSON
+	actionsJSON, err := json.Marshal(actions)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_CorporateAction_GetStockActions, "Failed to serialize corporate actions")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_CorporateAction_GetStockActions,
+		Data: json.RawMessage(actionsJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,137 @@
+package corporateaction
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// CorporateActionRepositoryInterface defines the interface for database operations
+type CorporateActionRepositoryInterface interface {
+	CreateIfNotExists(action *models.CorporateAction) (bool, error)
+	GetPendingActions(asOf time.Time) ([]models.CorporateAction, error)
+	GetActionsForStock(stockID uint) ([]models.CorporateAction, error)
+	ApplySplit(action *models.CorporateAction) error
+	ApplyDividend(action *models.CorporateAction) error
+}
+
+// corporateActionRepository implements CorporateActionRepositoryInterface
+type corporateActionRepository struct {
+	db *gorm.DB
+}
+
+// NewCorporateActionRepository creates a new repository
+func NewCorporateActionRepository(db *gorm.DB) CorporateActionRepositoryInterface {
+	return &corporateActionRepository{db: db}
+}
+
+// CreateIfNotExists inserts a corporate action unless the same stock, type and ex-date is already recorded
+func (r *corporateActionRepository) CreateIfNotExists(action *models.CorporateAction) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(action)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to create corporate action: %w", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetPendingActions gets the actions that have gone ex but have not been applied, oldest first
+func (r *corporateActionRepository) GetPendingActions(asOf time.Time) ([]models.CorporateAction, error) {
+	var actions []models.CorporateAction
+	err := r.db.
+		Where("applied_at IS NULL AND ex_date <= ?", asOf).
+		Order("ex_date ASC, type ASC").
+		Find(&actions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve pending corporate actions: %w", err)
+	}
+	return actions, nil
+}
+
+// GetActionsForStock gets every corporate action recorded for a stock, newest first
+func (r *corporateActionRepository) GetActionsForStock(stockID uint) ([]models.CorporateAction, error) {
+	var actions []models.CorporateAction
+	err := r.db.Where("stock_id = ?", stockID).Order("ex_date DESC").Find(&actions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve corporate actions for stock %d: %w", stockID, err)
+	}
+	return actions, nil
+}
+
+// ApplySplit restates every price recorded before the ex-date in post-split terms, in a transaction
+func (r *corporateActionRepository) ApplySplit(action *models.CorporateAction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// If no post-split quote has arrived yet, the current price is still pre-split
+		var postSplitQuotes int64
+		if err := tx.Model(&models.PriceHistory{}).
+			Where("stock_id = ? AND timestamp >= ?", action.StockID, action.ExDate).
+			Count(&postSplitQuotes).Error; err != nil {
+			return err
+		}
+		currentPriceIsStale := postSplitQuotes == 0
+
+		if err := tx.Exec(`
+			UPDATE price_histories SET price = price / ?
+			WHERE stock_id = ? AND timestamp < ?`, action.Ratio, action.StockID, action.ExDate).Error; err != nil {
+			return err
+		}
+
+		// Days already compacted into daily bars
+		if err := tx.Exec(`
+			UPDATE daily_price_bars SET open = open / ?, high = high / ?, low = low / ?, close = close / ?
+			WHERE stock_id = ? AND close_at < ?`,
+			action.Ratio, action.Ratio, action.Ratio, action.Ratio, action.StockID, action.ExDate).Error; err != nil {
+			return err
+		}
+
+		if currentPriceIsStale {
+			if err := tx.Exec("UPDATE stocks SET current_price = current_price / ? WHERE id = ?", action.Ratio, action.StockID).Error; err != nil {
+				return err
+			}
+		}
+
+		// Ownership windows and scoring windows store prices the same way
+		for _, table := range []string{"ownership_histories", "scoring_windows"} {
+			// Entry prices and dividends already credited were per pre-split share
+			if err := tx.Exec(`
+				UPDATE `+table+` SET starting_value = starting_value / ?, dividends = dividends / ?
+				WHERE stock_id = ? AND start_date < ?`, action.Ratio, action.Ratio, action.StockID, action.ExDate).Error; err != nil {
+				return err
+			}
+
+			// Exit prices recorded before the split, or open windows still tracking the stale price
+			if err := tx.Exec(`
+				UPDATE `+table+` SET current_value = current_value / ?
+				WHERE stock_id = ? AND ((end_date IS NOT NULL AND end_date < ?) OR (end_date IS NULL AND ?))`,
+				action.Ratio, action.StockID, action.ExDate, currentPriceIsStale).Error; err != nil {
+				return err
+			}
+		}
+
+		return markApplied(tx, action)
+	})
+}
+
+// ApplyDividend credits a dividend to every ownership and scoring window holding the stock on its ex-date, in a transaction
+func (r *corporateActionRepository) ApplyDividend(action *models.CorporateAction) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, table := range []string{"ownership_histories", "scoring_windows"} {
+			if err := tx.Exec(`
+				UPDATE `+table+` SET dividends = dividends + ?
+				WHERE stock_id = ? AND start_date < ? AND (end_date IS NULL OR end_date >= ?)`,
+				action.Amount, action.StockID, action.ExDate, action.ExDate).Error; err != nil {
+				return err
+			}
+		}
+
+		return markApplied(tx, action)
+	})
+}
+
+func markApplied(tx *gorm.DB, action *models.CorporateAction) error {
+	now := time.Now()
+	action.AppliedAt = &now
+	return tx.Model(action).Update("applied_at", now).Error
+}
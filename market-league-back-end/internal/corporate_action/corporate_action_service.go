@@ -0,0 +1,265 @@
+package corporateaction
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/marketdata"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+)
+
+// CorporateActionServiceInterface defines the interface for business logic
+type CorporateActionServiceInterface interface {
+	ImportCSV(reader io.Reader) (int, error)
+	ImportCSVFile(path string) (int, error)
+	SyncFromProvider(from time.Time, to time.Time) (int, error)
+	ApplyPendingActions() error
+	GetActionsForStock(stockID uint) ([]models.CorporateAction, error)
+}
+
+// corporateActionService implements CorporateActionServiceInterface
+type corporateActionService struct {
+	repo      CorporateActionRepositoryInterface
+	stockRepo *stock.StockRepository
+	provider  marketdata.MarketDataProvider
+}
+
+// NewCorporateActionService creates a new service
+func NewCorporateActionService(
+	repo CorporateActionRepositoryInterface,
+	stockRepo *stock.StockRepository,
+	provider marketdata.MarketDataProvider,
+) CorporateActionServiceInterface {
+	return &corporateActionService{
+		repo:      repo,
+		stockRepo: stockRepo,
+		provider:  provider,
+	}
+}
+
+// ImportCSV loads corporate actions from CSV with a header row of
+// ticker,type,ex_date,amount,ratio where type is "dividend" or "split",
+// ex_date is YYYY-MM-DD, amount is the cash per share of a dividend and
+// ratio is a split ratio such as "4" or "4:1". It returns how many new actions were recorded.
+func (s *corporateActionService) ImportCSV(reader io.Reader) (int, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("unable to read corporate actions header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	for _, required := range []string{"ticker", "type", "ex_date"} {
+		if _, ok := columns[required]; !ok {
+			return 0, fmt.Errorf("corporate actions CSV is missing the %s column", required)
+		}
+	}
+
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return 0, fmt.Errorf("error loading time location: %v", err)
+	}
+
+	created := 0
+	line := 1
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return created, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		stock, err := s.stockRepo.GetStockByTicker(strings.ToUpper(field("ticker")))
+		if err != nil {
+			log.Printf("Skipping corporate action on line %d: %v", line, err)
+			continue
+		}
+
+		exDate, err := time.ParseInLocation(marketdata.DateFormat, field("ex_date"), location)
+		if err != nil {
+			return created, fmt.Errorf("line %d: invalid ex_date: %v", line, err)
+		}
+
+		action := &models.CorporateAction{
+			StockID: stock.ID,
+			Type:    models.CorporateActionType(strings.ToLower(field("type"))),
+			ExDate:  exDate,
+		}
+		switch action.Type {
+		case models.DividendAction:
+			action.Amount, err = strconv.ParseFloat(field("amount"), 64)
+		case models.SplitAction:
+			action.Ratio, err = parseSplitRatio(field("ratio"))
+		default:
+			err = fmt.Errorf("unknown type %q", field("type"))
+		}
+		if err != nil {
+			return created, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		ok, err := s.create(action)
+		if err != nil {
+			return created, fmt.Errorf("line %d: %v", line, err)
+		}
+		if ok {
+			created++
+		}
+	}
+
+	return created, nil
+}
+
+// ImportCSVFile loads corporate actions from a CSV file on disk
+func (s *corporateActionService) ImportCSVFile(path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("unable to open corporate actions file: %v", err)
+	}
+	defer file.Close()
+
+	return s.ImportCSV(file)
+}
+
+// SyncFromProvider records the dividends and splits of every stock with an ex-date between from and to
+func (s *corporateActionService) SyncFromProvider(from time.Time, to time.Time) (int, error) {
+	stocks, err := s.stockRepo.GetActiveStocks()
+	if err != nil {
+		return 0, fmt.Errorf("error fetching stocks from database: %v", err)
+	}
+
+	ctx := context.Background()
+	created := 0
+	for _, stock := range stocks {
+		dividends, err := s.provider.GetDividends(ctx, stock.TickerSymbol, from, to)
+		if err != nil {
+			log.Printf("Error fetching dividends for %s: %v", stock.TickerSymbol, err)
+		}
+		for _, dividend := range dividends {
+			ok, err := s.create(&models.CorporateAction{
+				StockID: stock.ID,
+				Type:    models.DividendAction,
+				ExDate:  dividend.ExDate,
+				Amount:  dividend.Amount,
+			})
+			if err != nil {
+				return created, err
+			}
+			if ok {
+				created++
+			}
+		}
+
+		splits, err := s.provider.GetSplits(ctx, stock.TickerSymbol, from, to)
+		if err != nil {
+			log.Printf("Error fetching splits for %s: %v", stock.TickerSymbol, err)
+		}
+		for _, split := range splits {
+			ok, err := s.create(&models.CorporateAction{
+				StockID: stock.ID,
+				Type:    models.SplitAction,
+				ExDate:  split.ExDate,
+				Ratio:   split.Ratio,
+			})
+			if err != nil {
+				return created, err
+			}
+			if ok {
+				created++
+			}
+		}
+	}
+
+	return created, nil
+}
+
+// ApplyPendingActions split-adjusts prices and credits dividends for every action that has gone ex
+func (s *corporateActionService) ApplyPendingActions() error {
+	actions, err := s.repo.GetPendingActions(utils.Now())
+	if err != nil {
+		return err
+	}
+
+	for i := range actions {
+		action := &actions[i]
+		switch action.Type {
+		case models.SplitAction:
+			err = s.repo.ApplySplit(action)
+		case models.DividendAction:
+			err = s.repo.ApplyDividend(action)
+		default:
+			err = fmt.Errorf("unknown corporate action type %q", action.Type)
+		}
+		if err != nil {
+			return fmt.Errorf("unable to apply corporate action ID %d: %v", action.ID, err)
+		}
+		log.Printf("Applied %s for stock %d with ex-date %s", action.Type, action.StockID, action.ExDate.Format(marketdata.DateFormat))
+	}
+
+	return nil
+}
+
+// GetActionsForStock gets the dividends and splits recorded for a stock
+func (s *corporateActionService) GetActionsForStock(stockID uint) ([]models.CorporateAction, error) {
+	return s.repo.GetActionsForStock(stockID)
+}
+
+// * Helper Functions
+
+func (s *corporateActionService) create(action *models.CorporateAction) (bool, error) {
+	switch action.Type {
+	case models.DividendAction:
+		if action.Amount <= 0 {
+			return false, errors.New("dividend amount must be positive")
+		}
+	case models.SplitAction:
+		if action.Ratio <= 0 || action.Ratio == 1 {
+			return false, errors.New("split ratio must be positive and not 1")
+		}
+	}
+	return s.repo.CreateIfNotExists(action)
+}
+
+// parseSplitRatio accepts "4" or "4:1" style split ratios
+func parseSplitRatio(value string) (float64, error) {
+	parts := strings.Split(value, ":")
+	if len(parts) > 2 {
+		return 0, fmt.Errorf("invalid split ratio %q", value)
+	}
+	to, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid split ratio %q", value)
+	}
+	if len(parts) == 1 {
+		return to, nil
+	}
+	from, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil || from == 0 {
+		return 0, fmt.Errorf("invalid split ratio %q", value)
+	}
+	return to / from, nil
+}
@@ -38,6 +38,7 @@ func InitDB() {
 		&models.League{},
 		&models.Portfolio{},
 		&models.PortfolioPointsHistory{},
+		&models.PointsBreakdown{},
 		&models.Stock{},
 		&models.PriceHistory{},
 		&models.LeaguePortfolio{},
@@ -45,6 +46,19 @@ func InitDB() {
 		&models.Trade{},
 		&models.User{},
 		&models.OwnershipHistory{},
+		&models.ScoringWindow{},
+		&models.RosterPosition{},
+		&models.CorporateAction{},
+		&models.UniverseMember{},
+		&models.TickerChange{},
+		&models.DailyPriceBar{},
+		&models.CompactedRange{},
+		&models.QuarantinedQuote{},
+		&models.JobRun{},
+		&models.LeagueAward{},
+		&models.LeagueStanding{},
+		&models.LeagueInvite{},
+		&models.LeagueJoinRequest{},
 	)
 
 	if err != nil {
@@ -0,0 +1,67 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Compile-time check
+var _ Locker = (*AdvisoryLocker)(nil)
+
+// AdvisoryLocker elects the server that runs a job with Postgres advisory locks. A lock belongs to the
+// database session that took it, so it is freed when the server holding it dies.
+type AdvisoryLocker struct {
+	db *gorm.DB
+}
+
+// NewAdvisoryLocker creates a locker on the database the servers share
+func NewAdvisoryLocker(db *gorm.DB) *AdvisoryLocker {
+	return &AdvisoryLocker{db: db}
+}
+
+// TryLock takes the lock of a job on a connection of its own, which is kept until unlock is called.
+// Databases other than Postgres are not shared by several servers, so there the lock is always granted.
+func (l *AdvisoryLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	if l.db.Dialector.Name() != "postgres" {
+		return func() {}, true, nil
+	}
+	sqlDB, err := l.db.DB()
+	if err != nil {
+		return nil, false, err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get a connection for the lock of job %s: %w", name, err)
+	}
+
+	key := lockKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, false, fmt.Errorf("failed to take the lock of job %s: %w", name, err)
+	}
+	if !acquired {
+		conn.Close()
+		return nil, false, nil
+	}
+
+	unlock := func() {
+		// Unlocking uses its own context since the run's may be done by now
+		if _, err := conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", key); err != nil {
+			log.Printf("Error releasing the lock of job %s: %v", name, err)
+		}
+		conn.Close()
+	}
+	return unlock, true, nil
+}
+
+// lockKey turns a job name into the number Postgres locks on
+func lockKey(name string) int64 {
+	hash := fnv.New64a()
+	hash.Write([]byte("market-league/jobs/" + name))
+	return int64(hash.Sum64())
+}
@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"gorm.io/gorm"
+)
+
+// maxErrorLength keeps error summaries of stored runs short
+const maxErrorLength = 1000
+
+// Compile-time check
+var _ RunStore = (*RunRepository)(nil)
+
+// RunRepository stores job runs in the database
+type RunRepository struct {
+	db *gorm.DB
+}
+
+// NewRunRepository creates a new run repository
+func NewRunRepository(db *gorm.DB) *RunRepository {
+	return &RunRepository{db: db}
+}
+
+// SaveRun stores the result of a run
+func (r *RunRepository) SaveRun(result RunResult) error {
+	summary := result.Error
+	if len(summary) > maxErrorLength {
+		summary = summary[:maxErrorLength] + "..."
+	}
+	run := models.JobRun{
+		Job:          result.Job,
+		Key:          result.Key,
+		Trigger:      string(result.Trigger),
+		ScheduledFor: result.ScheduledFor,
+		Status:       string(result.Status),
+		Error:        summary,
+		Items:        result.Items,
+		StartedAt:    result.StartedAt,
+		FinishedAt:   result.FinishedAt,
+	}
+	if err := r.db.Create(&run).Error; err != nil {
+		return fmt.Errorf("failed to save run of job %s: %w", result.Job, err)
+	}
+	return nil
+}
+
+// LastScheduledRun returns the latest scheduled time a scheduled or catch-up run of a job succeeded for.
+// Manual runs do not count, they happen outside the schedule.
+func (r *RunRepository) LastScheduledRun(job string) (time.Time, bool, error) {
+	var run models.JobRun
+	found := r.db.
+		Where("job = ? AND status = ? AND triggered_by IN ?", job, RunSucceeded, []Trigger{TriggerSchedule, TriggerCatchUp}).
+		Order("scheduled_for DESC").
+		Limit(1).
+		Find(&run)
+	if found.Error != nil {
+		return time.Time{}, false, fmt.Errorf("failed to fetch last run of job %s: %w", job, found.Error)
+	}
+	return run.ScheduledFor, found.RowsAffected > 0, nil
+}
+
+// Succeeded reports whether a run with the idempotency key succeeded
+func (r *RunRepository) Succeeded(key string) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.JobRun{}).Where("run_key = ? AND status = ?", key, RunSucceeded).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check runs with key %s: %w", key, err)
+	}
+	return count > 0, nil
+}
+
+// LastRun returns the latest run of a job
+func (r *RunRepository) LastRun(job string) (RunResult, bool, error) {
+	runs, err := r.GetRecentRuns(job, 1)
+	if err != nil || len(runs) == 0 {
+		return RunResult{}, false, err
+	}
+	run := runs[0]
+	return RunResult{
+		Job:          run.Job,
+		Key:          run.Key,
+		Trigger:      Trigger(run.Trigger),
+		ScheduledFor: run.ScheduledFor,
+		Status:       RunStatus(run.Status),
+		Error:        run.Error,
+		Items:        run.Items,
+		StartedAt:    run.StartedAt,
+		FinishedAt:   run.FinishedAt,
+	}, true, nil
+}
+
+// GetRecentRuns gets the latest runs of a job, or of every job for an empty name, newest first
+func (r *RunRepository) GetRecentRuns(job string, limit int) ([]models.JobRun, error) {
+	query := r.db.Order("started_at DESC, id DESC").Limit(limit)
+	if job != "" {
+		query = query.Where("job = ?", job)
+	}
+	var runs []models.JobRun
+	if err := query.Find(&runs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch job runs: %w", err)
+	}
+	return runs, nil
+}
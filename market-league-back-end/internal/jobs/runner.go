@@ -0,0 +1,617 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/market-league/internal/utils"
+)
+
+// Job is a named unit of background work
+type Job struct {
+	Name      string
+	Schedule  Schedule      // When the job runs, nil for a job that only runs after its dependencies
+	DependsOn []string      // Jobs that have to succeed first, the job runs after each run of them
+	Timeout   time.Duration // Longest a run may take, 0 for no limit
+	CatchUp   bool          // Scheduled runs missed while the server was down are made up on start
+	// SkipIdleRuns leaves scheduled runs that succeeded without doing anything out of the run history,
+	// for jobs that check often whether there is work
+	SkipIdleRuns bool
+	// Dated jobs make up the past days a manual run gives them, other jobs cannot be given days
+	Dated bool
+	Run   func(ctx context.Context) error
+}
+
+// Trigger says why a job ran
+type Trigger string
+
+const (
+	TriggerSchedule Trigger = "schedule"
+	TriggerCatchUp  Trigger = "catch_up"
+	TriggerManual   Trigger = "manual"
+)
+
+// RunInfo describes the run a job is part of, jobs running after their dependencies share it
+type RunInfo struct {
+	Trigger      Trigger
+	ScheduledFor time.Time   // Scheduled time the run covers, the latest missed one for a catch-up
+	Missed       []time.Time // Every scheduled time a catch-up or a dated manual run makes up, oldest first
+	LeagueID     uint        // League a manual run is limited to, 0 for every league
+}
+
+type runInfoKey struct{}
+type itemsKey struct{}
+type jobNameKey struct{}
+
+// Info returns the run a job's context belongs to
+func Info(ctx context.Context) RunInfo {
+	info, _ := ctx.Value(runInfoKey{}).(RunInfo)
+	return info
+}
+
+// DayKey returns an idempotency key for what a job writes on the day its run covers. A retry of the job
+// for the same day gets the same key, so writes made under it can replace the earlier ones.
+func DayKey(ctx context.Context) string {
+	name, _ := ctx.Value(jobNameKey{}).(string)
+	return DayKeyFor(name, Info(ctx).ScheduledFor)
+}
+
+// DayKeyFor is the DayKey of another job's run on the day of t, for a job that rewrites what that job wrote
+func DayKeyFor(job string, t time.Time) string {
+	return fmt.Sprintf("%s:%s", job, t.Format("2006-01-02"))
+}
+
+// RunKey is the idempotency key of the run of a job for a scheduled time
+func RunKey(job string, scheduledFor time.Time) string {
+	return fmt.Sprintf("%s@%s", job, scheduledFor.UTC().Format(time.RFC3339))
+}
+
+// AddItems counts items a run processed, such as prices recorded, for its run history
+func AddItems(ctx context.Context, count int) {
+	if items, ok := ctx.Value(itemsKey{}).(*int64); ok {
+		atomic.AddInt64(items, int64(count))
+	}
+}
+
+// RunStore keeps the history of job runs
+type RunStore interface {
+	SaveRun(result RunResult) error
+	// LastScheduledRun returns the latest scheduled time a scheduled or catch-up run of a job succeeded for
+	LastScheduledRun(job string) (time.Time, bool, error)
+	// Succeeded reports whether a run with the idempotency key succeeded
+	Succeeded(key string) (bool, error)
+	// LastRun returns the latest run of a job
+	LastRun(job string) (RunResult, bool, error)
+}
+
+// Locker makes sure only one server runs a job at a time when several share a database.
+// TryLock does not wait, ok is false while another server holds the lock.
+type Locker interface {
+	TryLock(ctx context.Context, name string) (unlock func(), ok bool, err error)
+}
+
+type RunStatus string
+
+const (
+	RunSucceeded RunStatus = "succeeded"
+	RunFailed    RunStatus = "failed"
+	RunTimedOut  RunStatus = "timed_out"
+	RunSkipped   RunStatus = "skipped" // A dependency failed or the job was still running
+	RunRunning   RunStatus = "running" // Only reported as progress, while the job runs
+)
+
+// ErrRunningElsewhere is returned for a run another server is running or already ran
+var ErrRunningElsewhere = errors.New("the job is running or already ran on another server")
+
+// ManualRun narrows a run triggered by hand
+type ManualRun struct {
+	Days     []time.Time     // Past scheduled times to make up, like a catch-up does
+	LeagueID uint            // League to limit the run to, for jobs that work league by league
+	Progress func(RunResult) // Called as each job of the run starts and finishes
+}
+
+// RunResult is the outcome of one run of a job
+type RunResult struct {
+	Job          string    `json:"job"`
+	Key          string    `json:"key"` // Job and scheduled time, shared by retries of the same scheduled run
+	Trigger      Trigger   `json:"trigger"`
+	ScheduledFor time.Time `json:"scheduled_for"`
+	Status       RunStatus `json:"status"`
+	Error        string    `json:"error,omitempty"`
+	Items        int       `json:"items"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
+
+// Runner runs jobs on their schedules, in a timezone, on the market clock. A failing, panicking or
+// hanging run only affects that run and the jobs depending on it.
+type Runner struct {
+	location      *time.Location
+	store         RunStore
+	locker        Locker
+	catchUpWindow time.Duration
+	mutex         sync.Mutex
+	jobs          map[string]*Job
+	dependents    map[string][]string
+	running       map[string]bool
+	lastResult    map[string]RunResult
+	started       bool
+}
+
+// NewRunner creates a runner whose schedules are read in location
+func NewRunner(location *time.Location) *Runner {
+	return &Runner{
+		location:   location,
+		jobs:       make(map[string]*Job),
+		dependents: make(map[string][]string),
+		running:    make(map[string]bool),
+		lastResult: make(map[string]RunResult),
+	}
+}
+
+// SetStore persists every run to store and turns on catch-up of missed runs no older than window
+func (r *Runner) SetStore(store RunStore, window time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.store = store
+	r.catchUpWindow = window
+}
+
+// SetLocker runs every job under a lock from locker, so of several servers only the one holding it runs the job
+func (r *Runner) SetLocker(locker Locker) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.locker = locker
+}
+
+// Register adds a job. Dependencies may be registered after the jobs that need them, Start checks them.
+func (r *Runner) Register(job Job) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if job.Name == "" || job.Run == nil {
+		return errors.New("a job needs a name and a run function")
+	}
+	if _, exists := r.jobs[job.Name]; exists {
+		return fmt.Errorf("job %s is already registered", job.Name)
+	}
+	if r.started {
+		return fmt.Errorf("job %s registered after the runner started", job.Name)
+	}
+	r.jobs[job.Name] = &job
+	for _, dependency := range job.DependsOn {
+		r.dependents[dependency] = append(r.dependents[dependency], job.Name)
+	}
+	return nil
+}
+
+// Start checks the dependencies and starts a loop for every scheduled job, until ctx is done
+func (r *Runner) Start(ctx context.Context) error {
+	if err := r.validate(); err != nil {
+		return err
+	}
+	r.mutex.Lock()
+	r.started = true
+	r.mutex.Unlock()
+
+	for _, name := range r.JobNames() {
+		job := r.jobs[name]
+		if job.Schedule != nil {
+			go func() {
+				if job.CatchUp {
+					r.catchUp(ctx, job)
+				}
+				r.loop(ctx, job)
+			}()
+		}
+	}
+	return nil
+}
+
+// validate rejects unknown dependencies and cycles
+func (r *Runner) validate() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			return fmt.Errorf("job %s depends on itself", name)
+		case done:
+			return nil
+		}
+		state[name] = visiting
+		for _, dependency := range r.jobs[name].DependsOn {
+			if _, ok := r.jobs[dependency]; !ok {
+				return fmt.Errorf("job %s depends on unknown job %s", name, dependency)
+			}
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		state[name] = done
+		return nil
+	}
+	for name := range r.jobs {
+		if err := visit(name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loop waits for each scheduled time of a job and runs it with its dependents
+func (r *Runner) loop(ctx context.Context, job *Job) {
+	for ctx.Err() == nil {
+		now := utils.Now().In(r.location)
+		next := job.Schedule.Next(now)
+		if next.IsZero() {
+			log.Printf("Job %s has no more scheduled runs", job.Name)
+			return
+		}
+		log.Printf("Job %s next runs at %s", job.Name, next.Format("2006-01-02 15:04:05 MST"))
+		utils.Sleep(next.Sub(now))
+		if ctx.Err() != nil {
+			return
+		}
+		r.trigger(ctx, job.Name, RunInfo{Trigger: TriggerSchedule, ScheduledFor: next}, nil)
+	}
+}
+
+// catchUp makes up the scheduled runs of a job missed since its last successful run, in one run
+// that lists them all. A job that has never run has nothing to make up.
+func (r *Runner) catchUp(ctx context.Context, job *Job) {
+	missed, err := r.missedRuns(job, utils.Now().In(r.location))
+	if err != nil {
+		log.Printf("Job %s could not check for missed runs: %v", job.Name, err)
+		return
+	}
+	if len(missed) == 0 {
+		return
+	}
+	log.Printf("Job %s missed %d runs since %s, catching up", job.Name, len(missed), missed[0].Format(time.RFC3339))
+	r.trigger(ctx, job.Name, RunInfo{Trigger: TriggerCatchUp, ScheduledFor: missed[len(missed)-1], Missed: missed}, nil)
+}
+
+// missedRuns lists the scheduled times of a job after its last successful run up to now, within the catch-up window
+func (r *Runner) missedRuns(job *Job, now time.Time) ([]time.Time, error) {
+	if r.store == nil {
+		return nil, nil
+	}
+	last, ok, err := r.store.LastScheduledRun(job.Name)
+	if err != nil || !ok {
+		return nil, err
+	}
+	if earliest := now.Add(-r.catchUpWindow); last.Before(earliest) {
+		last = earliest
+	}
+	var missed []time.Time
+	for next := job.Schedule.Next(last.In(r.location)); !next.IsZero() && !next.After(now); next = job.Schedule.Next(next) {
+		missed = append(missed, next)
+	}
+	return missed, nil
+}
+
+// Trigger runs a job now by hand, then every job depending on it once all of their dependencies have
+// succeeded. Dependencies outside the run count if their last run succeeded. It returns the result of
+// every job it considered, in the order they ran.
+func (r *Runner) Trigger(ctx context.Context, name string) ([]RunResult, error) {
+	return r.TriggerManual(ctx, name, ManualRun{})
+}
+
+// TriggerManual runs a job and its dependents by hand like Trigger, for past days or a league
+func (r *Runner) TriggerManual(ctx context.Context, name string, run ManualRun) ([]RunResult, error) {
+	if len(run.Days) > 0 && r.HasJob(name) && !r.IsDated(name) {
+		return nil, fmt.Errorf("job %s does not make up past days", name)
+	}
+	days := append([]time.Time(nil), run.Days...)
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	info := RunInfo{Trigger: TriggerManual, ScheduledFor: utils.Now().In(r.location), Missed: days, LeagueID: run.LeagueID}
+	return r.trigger(ctx, name, info, run.Progress)
+}
+
+// trigger runs a job and its dependents as part of one run, reporting each job to progress if it is set
+func (r *Runner) trigger(ctx context.Context, name string, info RunInfo, progress func(RunResult)) ([]RunResult, error) {
+	if progress == nil {
+		progress = func(RunResult) {}
+	}
+	ctx = context.WithValue(ctx, runInfoKey{}, info)
+	order, err := r.runOrder(name)
+	if err != nil {
+		return nil, err
+	}
+	inRun := make(map[string]bool, len(order))
+	for _, job := range order {
+		inRun[job] = true
+	}
+
+	results := make([]RunResult, 0, len(order))
+	succeeded := make(map[string]bool, len(order))
+	for _, jobName := range order {
+		job := r.jobs[jobName]
+		if blocker := r.failedDependency(job, inRun, succeeded); blocker != "" {
+			result := r.finish(ctx, RunResult{
+				Job:    jobName,
+				Status: RunSkipped,
+				Error:  fmt.Sprintf("dependency %s did not succeed", blocker),
+			})
+			progress(result)
+			results = append(results, result)
+			continue
+		}
+		progress(RunResult{Job: jobName, Trigger: info.Trigger, ScheduledFor: info.ScheduledFor, Status: RunRunning, StartedAt: utils.Now()})
+		result, elsewhere := r.run(ctx, job)
+		if elsewhere && jobName == name {
+			// The server that ran the job runs its dependents too
+			return results, ErrRunningElsewhere
+		}
+		progress(result)
+		succeeded[jobName] = result.Status == RunSucceeded
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// runOrder lists a job and everything depending on it, each after all of its dependencies
+func (r *Runner) runOrder(name string) ([]string, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if _, ok := r.jobs[name]; !ok {
+		return nil, fmt.Errorf("unknown job %s", name)
+	}
+
+	// Collect the job and its dependents
+	reached := map[string]bool{name: true}
+	queue := []string{name}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, dependent := range r.dependents[current] {
+			if !reached[dependent] {
+				reached[dependent] = true
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	// Order them so dependencies come first, ties by name so runs are repeatable
+	var order []string
+	placed := make(map[string]bool, len(reached))
+	for len(order) < len(reached) {
+		var ready []string
+		for job := range reached {
+			if placed[job] {
+				continue
+			}
+			isReady := true
+			for _, dependency := range r.jobs[job].DependsOn {
+				if reached[dependency] && !placed[dependency] {
+					isReady = false
+					break
+				}
+			}
+			if isReady {
+				ready = append(ready, job)
+			}
+		}
+		if len(ready) == 0 {
+			return nil, fmt.Errorf("jobs depending on %s form a cycle", name)
+		}
+		sort.Strings(ready)
+		for _, job := range ready {
+			placed[job] = true
+			order = append(order, job)
+		}
+	}
+	return order, nil
+}
+
+// failedDependency returns a dependency of job that has not succeeded, or an empty string
+func (r *Runner) failedDependency(job *Job, inRun, succeeded map[string]bool) string {
+	for _, dependency := range job.DependsOn {
+		if inRun[dependency] {
+			if !succeeded[dependency] {
+				return dependency
+			}
+		} else if !r.lastSucceeded(dependency) {
+			return dependency
+		}
+	}
+	return ""
+}
+
+// lastSucceeded reports whether the latest run of a job succeeded. Jobs that have not run on this server,
+// since it started or because another server ran them, are looked up in the run history.
+func (r *Runner) lastSucceeded(name string) bool {
+	r.mutex.Lock()
+	result, ok := r.lastResult[name]
+	store := r.store
+	r.mutex.Unlock()
+	if !ok && store != nil {
+		var err error
+		if result, ok, err = store.LastRun(name); err != nil {
+			log.Printf("Job %s could not look up its last run: %v", name, err)
+			return false
+		}
+	}
+	return ok && result.Status == RunSucceeded
+}
+
+// run runs one job with its timeout, turning a panic into a failed run. It returns elsewhere instead of
+// running the job when another server is running it or already ran it for the same scheduled time.
+func (r *Runner) run(ctx context.Context, job *Job) (result RunResult, elsewhere bool) {
+	r.mutex.Lock()
+	if r.running[job.Name] {
+		r.mutex.Unlock()
+		return r.finish(ctx, RunResult{Job: job.Name, Status: RunSkipped, Error: "the previous run is still going"}), false
+	}
+	r.running[job.Name] = true
+	locker, store := r.locker, r.store
+	r.mutex.Unlock()
+
+	// The job counts as running, and the lock is held, until the result is stored and the job has returned.
+	// A run abandoned after a timeout keeps both until it actually returns, so the next run cannot overlap it.
+	unlock := func() {}
+	exited := make(chan struct{})
+	defer func() {
+		release := func() {
+			unlock()
+			r.mutex.Lock()
+			delete(r.running, job.Name)
+			r.mutex.Unlock()
+		}
+		select {
+		case <-exited:
+			release()
+		default:
+			go func() {
+				<-exited
+				release()
+			}()
+		}
+	}()
+	if locker != nil {
+		release, ok, err := locker.TryLock(ctx, job.Name)
+		if err != nil {
+			close(exited)
+			return r.finish(ctx, RunResult{Job: job.Name, Status: RunSkipped, Error: fmt.Sprintf("failed to take the job lock: %v", err)}), false
+		}
+		if !ok {
+			close(exited)
+			log.Printf("Job %s is running on another server", job.Name)
+			return RunResult{Job: job.Name, Status: RunSkipped}, true
+		}
+		unlock = release
+	}
+
+	// A scheduled run another server finished before this one took the lock is not run again
+	info := Info(ctx)
+	key := RunKey(job.Name, info.ScheduledFor)
+	if store != nil && info.Trigger != TriggerManual {
+		done, err := store.Succeeded(key)
+		if err != nil {
+			log.Printf("Job %s could not check for an earlier run: %v", job.Name, err)
+		} else if done {
+			close(exited)
+			log.Printf("Job %s already ran for %s", job.Name, info.ScheduledFor.Format(time.RFC3339))
+			return RunResult{Job: job.Name, Key: key, Status: RunSkipped}, true
+		}
+	}
+
+	var items int64
+	runCtx, cancel := context.WithValue(context.WithValue(ctx, itemsKey{}, &items), jobNameKey{}, job.Name), context.CancelFunc(func() {})
+	if job.Timeout > 0 {
+		runCtx, cancel = context.WithTimeout(runCtx, job.Timeout)
+	}
+	defer cancel()
+
+	result = RunResult{Job: job.Name, StartedAt: utils.Now()}
+	log.Printf("Job %s started", job.Name)
+	done := make(chan error, 1)
+	go func() {
+		defer close(exited)
+		defer func() {
+			if recovered := recover(); recovered != nil {
+				log.Printf("Job %s panicked: %v\n%s", job.Name, recovered, debug.Stack())
+				done <- fmt.Errorf("panic: %v", recovered)
+			}
+		}()
+		done <- job.Run(runCtx)
+	}()
+
+	select {
+	case err := <-done:
+		<-exited
+		result.Status = RunSucceeded
+		if err != nil {
+			result.Status = RunFailed
+			result.Error = err.Error()
+		}
+	case <-runCtx.Done():
+		// The run is abandoned, whatever it still does no longer holds up the jobs after it
+		result.Status = RunTimedOut
+		result.Error = runCtx.Err().Error()
+	}
+	result.Items = int(atomic.LoadInt64(&items))
+	return r.finish(ctx, result), false
+}
+
+// finish records and logs the result of a run
+func (r *Runner) finish(ctx context.Context, result RunResult) RunResult {
+	info := Info(ctx)
+	result.Trigger, result.ScheduledFor = info.Trigger, info.ScheduledFor
+	result.Key = RunKey(result.Job, info.ScheduledFor)
+	if result.StartedAt.IsZero() {
+		result.StartedAt = utils.Now()
+	}
+	result.FinishedAt = utils.Now()
+	r.mutex.Lock()
+	r.lastResult[result.Job] = result
+	store := r.store
+	idle := r.jobs[result.Job] != nil && r.jobs[result.Job].SkipIdleRuns &&
+		result.Status == RunSucceeded && result.Items == 0 && result.Trigger != TriggerManual
+	r.mutex.Unlock()
+	if idle {
+		return result
+	}
+	if store != nil {
+		if err := store.SaveRun(result); err != nil {
+			log.Printf("Error saving run of job %s: %v", result.Job, err)
+		}
+	}
+
+	if result.Error != "" {
+		log.Printf("Job %s %s: %s", result.Job, result.Status, result.Error)
+	} else {
+		log.Printf("Job %s %s in %s", result.Job, result.Status, result.FinishedAt.Sub(result.StartedAt).Round(time.Millisecond))
+	}
+	return result
+}
+
+// LastResult returns the result of the latest run of a job
+func (r *Runner) LastResult(name string) (RunResult, bool) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	result, ok := r.lastResult[name]
+	return result, ok
+}
+
+// HasJob reports whether a job is registered
+func (r *Runner) HasJob(name string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	_, ok := r.jobs[name]
+	return ok
+}
+
+// IsDated reports whether a job makes up the past days a manual run gives it
+func (r *Runner) IsDated(name string) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	job, ok := r.jobs[name]
+	return ok && job.Dated
+}
+
+// JobNames lists the registered jobs by name
+func (r *Runner) JobNames() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	names := make([]string, 0, len(r.jobs))
+	for name := range r.jobs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,232 @@
+package jobs
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/utils"
+)
+
+// Schedule decides when a job runs next
+type Schedule interface {
+	// Next returns the first run time after t, in the location of t
+	Next(t time.Time) time.Time
+}
+
+// maxCronSearch bounds the search for a matching time, so an impossible spec such as February 30th ends
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+// cronSchedule matches times against the five fields of a cron spec
+type cronSchedule struct {
+	spec    string
+	minute  uint64
+	hour    uint64
+	day     uint64
+	month   uint64
+	weekday uint64
+	anyDay  bool // Day of month was *
+	anyWeek bool // Day of week was *
+}
+
+// ParseCron parses a cron spec of five fields: minute, hour, day of month, month and day of week.
+// Fields take *, numbers, ranges (1-5), lists (1,3,5) and steps (*/15 or 8-14/2). Sunday is 0 or 7.
+// As in cron, when both day fields are restricted a time matching either one runs.
+func ParseCron(spec string) (Schedule, error) {
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("cron spec %q needs 5 fields, found %d", spec, len(fields))
+	}
+	schedule := &cronSchedule{spec: spec, anyDay: fields[2] == "*", anyWeek: fields[4] == "*"}
+	bounds := []struct {
+		target   *uint64
+		min, max int
+	}{
+		{&schedule.minute, 0, 59},
+		{&schedule.hour, 0, 23},
+		{&schedule.day, 1, 31},
+		{&schedule.month, 1, 12},
+		{&schedule.weekday, 0, 7},
+	}
+	for i, field := range fields {
+		bits, err := parseCronField(field, bounds[i].min, bounds[i].max)
+		if err != nil {
+			return nil, fmt.Errorf("cron spec %q: %w", spec, err)
+		}
+		*bounds[i].target = bits
+	}
+	// Sunday can be written as 7
+	if schedule.weekday&(1<<7) != 0 {
+		schedule.weekday |= 1
+	}
+	return schedule, nil
+}
+
+// MustParseCron is ParseCron for specs written in code, it panics on a bad spec
+func MustParseCron(spec string) Schedule {
+	schedule, err := ParseCron(spec)
+	if err != nil {
+		panic(err)
+	}
+	return schedule
+}
+
+// parseCronField returns a bit set of the values a field matches
+func parseCronField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rangePart, step := part, 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			value, err := strconv.Atoi(part[i+1:])
+			if err != nil || value < 1 {
+				return 0, fmt.Errorf("bad step in %q", part)
+			}
+			rangePart, step = part[:i], value
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			bounds := strings.SplitN(rangePart, "-", 2)
+			var err error
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("bad value in %q", part)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("bad value in %q", part)
+				}
+			} else if step > 1 {
+				// 5/15 means from 5 to the end in steps of 15
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is outside %d-%d", part, min, max)
+		}
+		for value := low; value <= high; value += step {
+			bits |= 1 << uint(value)
+		}
+	}
+	return bits, nil
+}
+
+func (c *cronSchedule) Next(t time.Time) time.Time {
+	// Cron runs on whole minutes, strictly after t
+	next := t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.Add(maxCronSearch)
+	for next.Before(limit) {
+		if c.month&(1<<uint(next.Month())) == 0 {
+			next = time.Date(next.Year(), next.Month()+1, 1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if !c.matchesDay(next) {
+			next = time.Date(next.Year(), next.Month(), next.Day()+1, 0, 0, 0, 0, next.Location())
+			continue
+		}
+		if c.hour&(1<<uint(next.Hour())) == 0 {
+			next = time.Date(next.Year(), next.Month(), next.Day(), next.Hour()+1, 0, 0, 0, next.Location())
+			continue
+		}
+		if c.minute&(1<<uint(next.Minute())) == 0 {
+			next = next.Add(time.Minute)
+			continue
+		}
+		return next
+	}
+	return time.Time{}
+}
+
+func (c *cronSchedule) matchesDay(t time.Time) bool {
+	day := c.day&(1<<uint(t.Day())) != 0
+	weekday := c.weekday&(1<<uint(t.Weekday())) != 0
+	if c.anyDay || c.anyWeek {
+		return day && weekday
+	}
+	return day || weekday
+}
+
+func (c *cronSchedule) String() string {
+	return c.spec
+}
+
+// in reads a schedule in a fixed location
+type in struct {
+	location *time.Location
+	schedule Schedule
+}
+
+// In reads a schedule in location rather than the runner's, such as a cron spec written in market time
+func In(location *time.Location, schedule Schedule) Schedule {
+	return in{location: location, schedule: schedule}
+}
+
+func (s in) Next(t time.Time) time.Time {
+	next := s.schedule.Next(t.In(s.location))
+	if next.IsZero() {
+		return next
+	}
+	return next.In(t.Location())
+}
+
+// tradingDays runs a schedule only on trading days of the market calendar
+type tradingDays struct {
+	schedule Schedule
+}
+
+// TradingDays skips the times of a schedule that fall on weekends or market holidays
+func TradingDays(schedule Schedule) Schedule {
+	return tradingDays{schedule: schedule}
+}
+
+func (s tradingDays) Next(t time.Time) time.Time {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		location = t.Location()
+	}
+	for next := s.schedule.Next(t); !next.IsZero(); next = s.schedule.Next(next) {
+		if utils.IsTradingDay(next.In(location)) {
+			return next
+		}
+	}
+	return time.Time{}
+}
+
+// every runs at a fixed interval
+type every struct {
+	interval time.Duration
+}
+
+// Every runs a job on each multiple of interval on the clock, such as every five minutes on the five. Every
+// server lands on the same times whenever it started, so runs of a job on several servers share a run key.
+func Every(interval time.Duration) Schedule {
+	return every{interval: interval}
+}
+
+func (s every) Next(t time.Time) time.Time {
+	return t.Truncate(s.interval).Add(s.interval)
+}
+
+// whileMarketOpen runs a schedule only during regular trading hours
+type whileMarketOpen struct {
+	schedule Schedule
+}
+
+// WhileMarketOpen keeps the times of a schedule that fall within trading hours, and runs at the
+// next open in place of the times that do not
+func WhileMarketOpen(schedule Schedule) Schedule {
+	return whileMarketOpen{schedule: schedule}
+}
+
+func (s whileMarketOpen) Next(t time.Time) time.Time {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		location = t.Location()
+	}
+	next := s.schedule.Next(t)
+	if next.IsZero() || utils.IsMarketOpen(next.In(location)) {
+		return next
+	}
+	return utils.NextMarketOpen(next.In(location)).In(t.Location())
+}
@@ -0,0 +1,186 @@
+package league
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// LeagueSettingsUpdate holds the settings the league owner changes. Nil fields stay as they are.
+type LeagueSettingsUpdate struct {
+	LeagueName     *string
+	Description    *string
+	Public         *bool
+	StartDate      *time.Time // Only before the draft
+	EndDate        *time.Time // Until the league completes, and not in the past
+	DraftTime      *time.Time // Only before the draft
+	ClearDraftTime bool       // Wait for everyone to queue up again, only before the draft
+	LockRosters    *bool      // Until the rosters lock
+	MinPlayers     *int       // Only before the draft
+	MaxPlayers     *int       // Only before the draft, 0 removes the limit
+}
+
+// KickMember lets the league owner or an admin remove a player from a league. Any stocks the player drafted go
+// back to the draft pool.
+func (s *LeagueService) KickMember(leagueID, userID, memberID uint) error {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "remove players"); err != nil {
+		return err
+	}
+	if memberID == league.OwnerID {
+		return fmt.Errorf("the league owner cannot be removed, transfer ownership first")
+	}
+	if err := checkCanRemoveMember(league); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveMember(leagueID, memberID, utils.Now()); err != nil {
+		return err
+	}
+	log.Printf("League %d: user %d removed by %d", leagueID, memberID, userID)
+
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", leagueID, err)
+	}
+	return nil
+}
+
+// TransferOwnership lets the league owner or an admin hand the league to another of its players
+func (s *LeagueService) TransferOwnership(leagueID, userID, newOwnerID uint) error {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "transfer ownership"); err != nil {
+		return err
+	}
+	if newOwnerID == league.OwnerID {
+		return fmt.Errorf("user %d already owns the league", newOwnerID)
+	}
+	member, err := s.repo.IsMember(leagueID, newOwnerID)
+	if err != nil {
+		return fmt.Errorf("failed to check membership: %v", err)
+	}
+	if !member {
+		return fmt.Errorf("the new owner must be a player in the league")
+	}
+
+	if err := s.repo.SetOwner(leagueID, newOwnerID); err != nil {
+		return fmt.Errorf("failed to transfer ownership: %v", err)
+	}
+	log.Printf("League %d: ownership moved from %d to %d", leagueID, league.OwnerID, newOwnerID)
+
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", leagueID, err)
+	}
+	return nil
+}
+
+// UpdateSettings lets the league owner or an admin rename a league and change its dates and settings. The name,
+// description and listing can change until the league completes, and the end date as long as it is not moved
+// into the past. The start date, draft time and player limits are fixed once the draft starts, and rosters
+// cannot be unlocked once they locked.
+func (s *LeagueService) UpdateSettings(leagueID, userID uint, update LeagueSettingsUpdate) (*LeagueResponse, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "change its settings"); err != nil {
+		return nil, err
+	}
+	if league.LeagueState == models.Completed {
+		return nil, fmt.Errorf("the league has completed")
+	}
+	preDraft := league.LeagueState == models.PreDraft
+
+	// Step 1: Listing
+	if update.LeagueName != nil {
+		name := strings.TrimSpace(*update.LeagueName)
+		if name == "" {
+			return nil, fmt.Errorf("the league name cannot be empty")
+		}
+		league.LeagueName = name
+	}
+	if update.Description != nil {
+		if len(*update.Description) > maxDescriptionLength {
+			return nil, fmt.Errorf("the description can be at most %d characters", maxDescriptionLength)
+		}
+		league.Description = *update.Description
+	}
+	if update.Public != nil {
+		league.Public = *update.Public
+	}
+
+	// Step 2: Schedule
+	if (update.StartDate != nil || update.DraftTime != nil || update.ClearDraftTime) && !preDraft {
+		return nil, fmt.Errorf("the start date and draft time are fixed once the draft starts")
+	}
+	if update.StartDate != nil {
+		league.StartDate = *update.StartDate
+	}
+	if update.EndDate != nil {
+		if !update.EndDate.After(utils.Now()) {
+			return nil, fmt.Errorf("the end date cannot be moved into the past")
+		}
+		league.EndDate = *update.EndDate
+	}
+	if update.ClearDraftTime {
+		league.DraftTime = nil
+	}
+	if update.DraftTime != nil {
+		league.DraftTime = update.DraftTime
+	}
+	if !league.EndDate.After(league.StartDate) {
+		return nil, fmt.Errorf("the end date must be after the start date")
+	}
+	if league.DraftTime != nil && !league.DraftTime.Before(league.EndDate) {
+		return nil, fmt.Errorf("the draft time must be before the end date")
+	}
+	if update.LockRosters != nil {
+		if league.RostersLockedAt != nil && !*update.LockRosters {
+			return nil, fmt.Errorf("the rosters are already locked")
+		}
+		league.LockRosters = *update.LockRosters
+	}
+
+	// Step 3: Player limits
+	if (update.MinPlayers != nil || update.MaxPlayers != nil) && !preDraft {
+		return nil, fmt.Errorf("the player limits are fixed once the draft starts")
+	}
+	if update.MinPlayers != nil {
+		league.MinPlayers = *update.MinPlayers
+	}
+	if update.MaxPlayers != nil {
+		league.MaxPlayers = update.MaxPlayers
+		if *update.MaxPlayers == 0 {
+			league.MaxPlayers = nil
+		}
+	}
+	if err := validatePlayerLimits(league.MinPlayers, league.MaxPlayers); err != nil {
+		return nil, err
+	}
+	if league.MaxPlayers != nil && len(league.Users) > *league.MaxPlayers {
+		return nil, fmt.Errorf("the league already has %d players", len(league.Users))
+	}
+
+	// Step 4: Save, unless the league moved on while it was being edited
+	saved, err := s.repo.UpdateLeagueSettings(league)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update league settings: %v", err)
+	}
+	if !saved {
+		return nil, fmt.Errorf("the league changed while it was being edited, try again")
+	}
+
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", leagueID, err)
+	}
+	return newLeagueResponse(league), nil
+}
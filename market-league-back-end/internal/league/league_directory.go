@@ -0,0 +1,136 @@
+package league
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// maxDescriptionLength is the longest description a league can have in the directory
+const maxDescriptionLength = 500
+
+// Page size of the league directory
+const (
+	DefaultDirectoryLimit = 25
+	MaxDirectoryLimit     = 100
+)
+
+// GetDirectory lists the public leagues a user can ask to join: leagues waiting on their draft with room for
+// another player that the user is not already in. A userID of 0 leaves nobody's leagues out.
+func (s *LeagueService) GetDirectory(search string, userID uint, limit, offset int) ([]models.DirectoryLeague, error) {
+	if limit <= 0 {
+		limit = DefaultDirectoryLimit
+	}
+	if limit > MaxDirectoryLimit {
+		limit = MaxDirectoryLimit
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	leagues, err := s.repo.GetDirectory(strings.TrimSpace(search), userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the league directory: %v", err)
+	}
+	return leagues, nil
+}
+
+// RequestToJoin asks the owner of a public league to let a user in
+func (s *LeagueService) RequestToJoin(leagueID, userID uint, message string) (*models.LeagueJoinRequest, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if !league.Public {
+		return nil, fmt.Errorf("the league is not public, ask its owner for an invite")
+	}
+	if err := checkCanJoin(league, len(league.Users)); err != nil {
+		return nil, err
+	}
+	if len(message) > maxDescriptionLength {
+		return nil, fmt.Errorf("the message can be at most %d characters", maxDescriptionLength)
+	}
+
+	member, err := s.repo.IsMember(leagueID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check membership: %v", err)
+	}
+	if member {
+		return nil, fmt.Errorf("user already in league")
+	}
+	pending, err := s.repo.HasPendingJoinRequest(leagueID, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check join requests: %v", err)
+	}
+	if pending {
+		return nil, fmt.Errorf("you have already asked to join this league")
+	}
+
+	request := &models.LeagueJoinRequest{
+		LeagueID: leagueID,
+		UserID:   userID,
+		Message:  message,
+		Status:   models.JoinRequest_Pending,
+	}
+	if err := s.repo.CreateJoinRequest(request); err != nil {
+		return nil, fmt.Errorf("failed to create join request: %v", err)
+	}
+	return request, nil
+}
+
+// GetJoinRequests lets the league owner or an admin list the requests to join a league waiting on an answer
+func (s *LeagueService) GetJoinRequests(leagueID, userID uint) ([]models.LeagueJoinRequest, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "see its join requests"); err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.GetPendingJoinRequests(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch join requests: %v", err)
+	}
+	return requests, nil
+}
+
+// DecideJoinRequest lets the league owner or an admin approve or deny a request to join. An approved user is
+// added to the league with their LeaguePlayer record and portfolio.
+func (s *LeagueService) DecideJoinRequest(requestID, userID uint, approve bool) (*models.LeagueJoinRequest, error) {
+	request, err := s.repo.GetJoinRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+	league, err := s.repo.GetLeague(request.LeagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "answer its join requests"); err != nil {
+		return nil, err
+	}
+	if approve {
+		if err := checkCanJoin(league, len(league.Users)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.DecideJoinRequest(requestID, userID, approve, utils.Now()); err != nil {
+		return nil, err
+	}
+	request, err = s.repo.GetJoinRequest(requestID)
+	if err != nil {
+		return nil, err
+	}
+
+	if approve {
+		log.Printf("League %d: user %d joined by request", league.ID, request.UserID)
+		if err := s.BroadcastLeagueDetails(league.ID); err != nil {
+			log.Printf("Error broadcasting league %d: %v", league.ID, err)
+		}
+	}
+	return request, nil
+}
@@ -11,18 +11,32 @@ import (
 	league_portfolio "github.com/market-league/internal/league_portfolio"
 	"github.com/market-league/internal/models"
 	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/utils"
 )
 
 // LeagueHandler Interface
 type LeagueHandlerInterface interface {
 	CreateLeague(conn *ws.Connection, rawData json.RawMessage) error
 	AddUserToLeague(conn *ws.Connection, rawData json.RawMessage) error
+	CreateInvite(conn *ws.Connection, rawData json.RawMessage) error
+	GetInvites(conn *ws.Connection, rawData json.RawMessage) error
+	RevokeInvite(conn *ws.Connection, rawData json.RawMessage) error
+	RedeemInvite(conn *ws.Connection, rawData json.RawMessage) error
+	LeaveLeague(conn *ws.Connection, rawData json.RawMessage) error
+	KickMember(conn *ws.Connection, rawData json.RawMessage) error
+	TransferOwnership(conn *ws.Connection, rawData json.RawMessage) error
+	UpdateSettings(conn *ws.Connection, rawData json.RawMessage) error
 	GetLeagueDetails(conn *ws.Connection, rawData json.RawMessage) error
 	GetLeaderboard(conn *ws.Connection, rawData json.RawMessage) error
+	GetLeagueResults(conn *ws.Connection, rawData json.RawMessage) error
 	RemoveLeague(conn *ws.Connection, rawData json.RawMessage) error
 	QueueUp(conn *ws.Connection, rawData json.RawMessage) error
 	GetPlayerPortfoliosInLeague(conn *ws.Connection, rawData json.RawMessage) error
 	GetAllLeagues(conn *ws.Connection, rawData json.RawMessage) error
+	RequestToJoin(conn *ws.Connection, rawData json.RawMessage) error
+	GetJoinRequests(conn *ws.Connection, rawData json.RawMessage) error
+	ApproveJoinRequest(conn *ws.Connection, rawData json.RawMessage) error
+	DenyJoinRequest(conn *ws.Connection, rawData json.RawMessage) error
 	SubscribeToLeague(conn *ws.Connection, rawData json.RawMessage) error
 	UnsubscribeToLeague(conn *ws.Connection, rawData json.RawMessage) error
 	HandleDisconnect(leagueID uint, conn *ws.Connection) error
@@ -57,9 +71,20 @@ func NewLeagueHandler(
 func (h *LeagueHandler) CreateLeague(conn *ws.Connection, rawData json.RawMessage) error {
 	// Step 1: Parse the WebSocket message
 	var request struct {
-		LeagueName string `json:"league_name" binding:"required"`
-		OwnerUser  uint   `json:"owner_user" binding:"required"`
-		EndDate    string `json:"end_date" binding:"required"`
+		LeagueName      string                  `json:"league_name" binding:"required"`
+		OwnerUser       uint                    `json:"owner_user"` // Optional: must be the signed-in user
+		EndDate         string                  `json:"end_date" binding:"required"`
+		StartingSlots   *int                    `json:"starting_slots"`   // Optional: defaults to models.DefaultStartingSlots
+		BenchSlots      *int                    `json:"bench_slots"`      // Optional: defaults to models.DefaultBenchSlots
+		RosterPositions []models.RosterPosition `json:"roster_positions"` // Optional: sector requirements such as 1 Technology
+		DraftPool       models.StockSelection   `json:"draft_pool"`       // Optional: defaults to every stock still trading
+		StartDate       string                  `json:"start_date"`       // Optional: RFC3339, defaults to now
+		DraftTime       string                  `json:"draft_time"`       // Optional: RFC3339, the draft starts on its own at this time
+		LockRosters     bool                    `json:"lock_rosters"`     // Optional: trades close at the start date
+		Public          bool                    `json:"public"`           // Optional: list the league in the directory
+		Description     string                  `json:"description"`      // Optional: shown in the directory
+		MinPlayers      *int                    `json:"min_players"`      // Optional: defaults to models.DefaultMinPlayers
+		MaxPlayers      *int                    `json:"max_players"`      // Optional: no limit when empty
 	}
 
 	// Step 2: Parse data from WebSocket JSON payload
@@ -67,26 +92,73 @@ func (h *LeagueHandler) CreateLeague(conn *ws.Connection, rawData json.RawMessag
 		ws.SendError(conn, ws.MessageType_League_CreateLeague, "Invalid input: "+err.Error())
 		return fmt.Errorf("invalid input: %v", err)
 	}
+	ownerID, err := conn.User(request.OwnerUser)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_CreateLeague, err.Error())
+		return err
+	}
 
 	// Step 3: Process business logic (reuse the service layer)
 
 	// Step 3a: Pass the values to the service to create the league
-	startDate := time.Now().Format(time.RFC3339) // Set the start date to the current date and time
-	league, err := h.service.CreateLeague(request.LeagueName, request.OwnerUser, startDate, request.EndDate)
+	startDate := request.StartDate
+	if startDate == "" {
+		startDate = utils.Now().Format(time.RFC3339) // Set the start date to the current date and time
+	}
+	settings := LeagueSettings{
+		StartingSlots:   models.DefaultStartingSlots,
+		BenchSlots:      models.DefaultBenchSlots,
+		RosterPositions: request.RosterPositions,
+		LockRosters:     request.LockRosters,
+		Public:          request.Public,
+		Description:     request.Description,
+		MinPlayers:      models.DefaultMinPlayers,
+		MaxPlayers:      request.MaxPlayers,
+	}
+	if request.DraftTime != "" {
+		draftTime, err := time.Parse(time.RFC3339, request.DraftTime)
+		if err != nil {
+			ws.SendError(conn, ws.MessageType_League_CreateLeague, "Invalid draft time format: "+err.Error())
+			return fmt.Errorf("invalid draft time: %v", err)
+		}
+		settings.DraftTime = &draftTime
+	}
+	if request.StartingSlots != nil {
+		settings.StartingSlots = *request.StartingSlots
+	}
+	if request.BenchSlots != nil {
+		settings.BenchSlots = *request.BenchSlots
+	}
+	if request.MinPlayers != nil {
+		settings.MinPlayers = *request.MinPlayers
+	}
+
+	// Step 3a-1: Check the draft pool can fill a roster before anything is created
+	rosterSettings := &models.League{
+		StartingSlots:   settings.StartingSlots,
+		BenchSlots:      settings.BenchSlots,
+		RosterPositions: settings.RosterPositions,
+	}
+	if _, err := h.leaguePortfolioService.SelectDraftPool(rosterSettings, request.DraftPool); err != nil {
+		ws.SendError(conn, ws.MessageType_League_CreateLeague, err.Error())
+		return fmt.Errorf("invalid draft pool: %v", err)
+	}
+
+	league, err := h.service.CreateLeague(request.LeagueName, ownerID, startDate, request.EndDate, settings)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_CreateLeague, err.Error())
 		return fmt.Errorf("failed to retrieve portfolio with ID: %v", err)
 	}
 
 	// Step 3b: Create a portfolio for the user in the league
-	portfolio, err := h.portfolioService.CreatePortfolio(request.OwnerUser, league.ID)
+	portfolio, err := h.portfolioService.CreatePortfolio(ownerID, league.ID)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_CreateLeague, err.Error())
 		return fmt.Errorf("failed to retrieve portfolio with ID: %v", err)
 	}
 
 	// Step 3c: Create a league portfolio using the new LeaguePortfolioService
-	leaguePortfolio, err := h.leaguePortfolioService.CreateLeaguePortfolio(league.ID)
+	leaguePortfolio, err := h.leaguePortfolioService.CreateLeaguePortfolio(league.ID, request.DraftPool)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_CreateLeague, err.Error())
 		return fmt.Errorf("failed to retrieve portfolio with ID: %v", err)
@@ -119,12 +191,13 @@ func (h *LeagueHandler) CreateLeague(conn *ws.Connection, rawData json.RawMessag
 
 }
 
-// AddUserToLeague handles adding a user to a league.
+// AddUserToLeague handles the league owner or an admin adding a user to a league.
 func (h *LeagueHandler) AddUserToLeague(conn *ws.Connection, rawData json.RawMessage) error {
 	// Step 1: Parse the WebSocket message
 	var request struct {
-		UserID   uint `json:"user_id" binding:"required"`
-		LeagueID uint `json:"league_id" binding:"required"`
+		RequesterID uint `json:"requester_id"` // Optional: must be the signed-in user
+		UserID      uint `json:"user_id" binding:"required"`
+		LeagueID    uint `json:"league_id" binding:"required"`
 	}
 
 	// Step 2: Parse data from WebSocket JSON payload
@@ -132,20 +205,17 @@ func (h *LeagueHandler) AddUserToLeague(conn *ws.Connection, rawData json.RawMes
 		ws.SendError(conn, ws.MessageType_League_AddUserToLeague, "Invalid input: "+err.Error())
 		return fmt.Errorf("invalid input: %v", err)
 	}
-
-	// Step 3: Process business logic (reuse the service layer)
-
-	// Step 3a: Process business logic (reuse the service layer)
-	if err := h.service.AddUserToLeague(request.UserID, request.LeagueID); err != nil {
+	requesterID, err := conn.User(request.RequesterID)
+	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_AddUserToLeague, err.Error())
-		return fmt.Errorf("failed to retrieve portfolio with ID: %v", err)
+		return err
 	}
 
-	// Step 3b: Process business logic (reuse the service layer)
-	portfolio, err := h.portfolioService.CreatePortfolio(request.UserID, request.LeagueID)
+	// Step 3: Process business logic (reuse the service layer)
+	portfolio, err := h.service.AddUserToLeague(requesterID, request.UserID, request.LeagueID)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_AddUserToLeague, err.Error())
-		return fmt.Errorf("failed to retrieve portfolio with ID: %v", err)
+		return fmt.Errorf("failed to add user to league: %v", err)
 	}
 
 	// Step 4: Marshal the portfolio into JSON
@@ -168,6 +238,393 @@ func (h *LeagueHandler) AddUserToLeague(conn *ws.Connection, rawData json.RawMes
 
 }
 
+// CreateInvite handles the league owner creating a shareable invite code.
+func (h *LeagueHandler) CreateInvite(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID    uint   `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID  uint   `json:"league_id" binding:"required"`
+		ExpiresAt string `json:"expires_at"` // Optional: RFC3339, never expires when empty
+		MaxUses   *int   `json:"max_uses"`   // Optional: unlimited when empty
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_CreateInvite, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_CreateInvite, err.Error())
+		return err
+	}
+	var expiresAt *time.Time
+	if request.ExpiresAt != "" {
+		parsed, err := time.Parse(time.RFC3339, request.ExpiresAt)
+		if err != nil {
+			ws.SendError(conn, ws.MessageType_League_CreateInvite, "Invalid expiry format: "+err.Error())
+			return fmt.Errorf("invalid expiry: %v", err)
+		}
+		expiresAt = &parsed
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	invite, err := h.service.CreateInvite(request.LeagueID, userID, expiresAt, request.MaxUses)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_CreateInvite, err.Error())
+		return fmt.Errorf("failed to create invite: %v", err)
+	}
+
+	// Step 4: Marshal the invite into JSON
+	inviteJSON, err := json.Marshal(invite)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_CreateInvite, "Failed to serialize invite")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_CreateInvite,
+		Data: json.RawMessage(inviteJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// GetInvites handles the league owner listing the invites that can still be redeemed.
+func (h *LeagueHandler) GetInvites(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID uint `json:"league_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetInvites, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetInvites, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	invites, err := h.service.GetInvites(request.LeagueID, userID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetInvites, err.Error())
+		return fmt.Errorf("failed to retrieve invites: %v", err)
+	}
+
+	// Step 4: Marshal the invites into JSON
+	invitesJSON, err := json.Marshal(invites)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetInvites, "Failed to serialize invites")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_GetInvites,
+		Data: json.RawMessage(invitesJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// RevokeInvite handles the league owner revoking an invite.
+func (h *LeagueHandler) RevokeInvite(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
+		InviteID uint `json:"invite_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_RevokeInvite, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RevokeInvite, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	invite, err := h.service.RevokeInvite(request.InviteID, userID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RevokeInvite, err.Error())
+		return fmt.Errorf("failed to revoke invite: %v", err)
+	}
+
+	// Step 4: Marshal the invite into JSON
+	inviteJSON, err := json.Marshal(invite)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RevokeInvite, "Failed to serialize invite")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_RevokeInvite,
+		Data: json.RawMessage(inviteJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// RedeemInvite handles a user joining a league with an invite code.
+func (h *LeagueHandler) RedeemInvite(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID uint   `json:"user_id"` // Optional: must be the signed-in user
+		Code   string `json:"code" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_RedeemInvite, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RedeemInvite, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	portfolio, err := h.service.RedeemInvite(request.Code, userID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RedeemInvite, err.Error())
+		return fmt.Errorf("failed to redeem invite: %v", err)
+	}
+
+	// Step 4: Marshal the portfolio into JSON
+	portfolioJSON, err := json.Marshal(portfolio)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RedeemInvite, "Failed to serialize portfolio")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_RedeemInvite,
+		Data: json.RawMessage(portfolioJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// LeaveLeague handles a player leaving a league.
+func (h *LeagueHandler) LeaveLeague(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID uint `json:"league_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_LeaveLeague, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_LeaveLeague, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	if err := h.service.LeaveLeague(request.LeagueID, userID); err != nil {
+		ws.SendError(conn, ws.MessageType_League_LeaveLeague, err.Error())
+		return fmt.Errorf("failed to leave league: %v", err)
+	}
+
+	// Step 4: Send success response (no data, just confirmation)
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_LeaveLeague,
+		Data: json.RawMessage(`{"message": "Left the league successfully"}`), // Simple JSON message
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// KickMember handles the league owner removing a player from the league.
+func (h *LeagueHandler) KickMember(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID uint `json:"league_id" binding:"required"`
+		MemberID uint `json:"member_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_KickMember, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_KickMember, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	if err := h.service.KickMember(request.LeagueID, userID, request.MemberID); err != nil {
+		ws.SendError(conn, ws.MessageType_League_KickMember, err.Error())
+		return fmt.Errorf("failed to remove member: %v", err)
+	}
+
+	// Step 4: Send success response (no data, just confirmation)
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_KickMember,
+		Data: json.RawMessage(`{"message": "Player removed successfully"}`), // Simple JSON message
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// TransferOwnership handles the league owner handing the league to another player.
+func (h *LeagueHandler) TransferOwnership(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID     uint `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID   uint `json:"league_id" binding:"required"`
+		NewOwnerID uint `json:"new_owner_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_TransferOwnership, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_TransferOwnership, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	if err := h.service.TransferOwnership(request.LeagueID, userID, request.NewOwnerID); err != nil {
+		ws.SendError(conn, ws.MessageType_League_TransferOwnership, err.Error())
+		return fmt.Errorf("failed to transfer ownership: %v", err)
+	}
+
+	// Step 4: Send success response (no data, just confirmation)
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_TransferOwnership,
+		Data: json.RawMessage(`{"message": "Ownership transferred successfully"}`), // Simple JSON message
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateSettings handles the league owner renaming the league or changing its dates and settings.
+func (h *LeagueHandler) UpdateSettings(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message, fields left out stay as they are
+	var request struct {
+		UserID      uint    `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID    uint    `json:"league_id" binding:"required"`
+		LeagueName  *string `json:"league_name"`
+		Description *string `json:"description"`
+		Public      *bool   `json:"public"`
+		StartDate   *string `json:"start_date"` // RFC3339
+		EndDate     *string `json:"end_date"`   // RFC3339
+		DraftTime   *string `json:"draft_time"` // RFC3339, empty to wait for everyone to queue up
+		LockRosters *bool   `json:"lock_rosters"`
+		MinPlayers  *int    `json:"min_players"`
+		MaxPlayers  *int    `json:"max_players"` // 0 removes the limit
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_UpdateSettings, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_UpdateSettings, err.Error())
+		return err
+	}
+	update := LeagueSettingsUpdate{
+		LeagueName:  request.LeagueName,
+		Description: request.Description,
+		Public:      request.Public,
+		LockRosters: request.LockRosters,
+		MinPlayers:  request.MinPlayers,
+		MaxPlayers:  request.MaxPlayers,
+	}
+	dates := []struct {
+		name   string
+		value  *string
+		target **time.Time
+	}{
+		{"start date", request.StartDate, &update.StartDate},
+		{"end date", request.EndDate, &update.EndDate},
+		{"draft time", request.DraftTime, &update.DraftTime},
+	}
+	for _, date := range dates {
+		if date.value == nil || *date.value == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, *date.value)
+		if err != nil {
+			ws.SendError(conn, ws.MessageType_League_UpdateSettings, "Invalid "+date.name+" format: "+err.Error())
+			return fmt.Errorf("invalid %s: %v", date.name, err)
+		}
+		*date.target = &parsed
+	}
+	update.ClearDraftTime = request.DraftTime != nil && *request.DraftTime == ""
+
+	// Step 3: Process business logic (reuse the service layer)
+	league, err := h.service.UpdateSettings(request.LeagueID, userID, update)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_UpdateSettings, err.Error())
+		return fmt.Errorf("failed to update league settings: %v", err)
+	}
+
+	// Step 4: Marshal the league into JSON
+	leagueJSON, err := json.Marshal(league)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_UpdateSettings, "Failed to serialize league")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_UpdateSettings,
+		Data: json.RawMessage(leagueJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
 // GetLeagueDetails handles fetching the details of a specific league.
 func (h *LeagueHandler) GetLeagueDetails(conn *ws.Connection, rawData json.RawMessage) error {
 	// Step 1: Parse the WebSocket message
@@ -189,16 +646,8 @@ func (h *LeagueHandler) GetLeagueDetails(conn *ws.Connection, rawData json.RawMe
 	}
 
 	// Step 4: Marshal the portfolio into JSON
-	data := gin.H{
-		"id":             league.ID,
-		"league_name":    league.LeagueName,
-		"start_date":     league.StartDate,
-		"end_date":       league.EndDate,
-		"league_state":   league.LeagueState,
-		"users":          users,
-		"max_players":    league.MaxPlayers,
-		"league_players": league.LeaguePlayers,
-	}
+	data := leagueDetails(league)
+	data["users"] = users
 	// Construct response with sanitized user details
 	dataJSON, err := json.Marshal(data)
 	if err != nil {
@@ -258,10 +707,50 @@ func (h *LeagueHandler) GetLeaderboard(conn *ws.Connection, rawData json.RawMess
 
 }
 
+// GetLeagueResults handles fetching the final standings and awards of a completed league.
+func (h *LeagueHandler) GetLeagueResults(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		LeagueID uint `json:"league_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetResults, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	results, err := h.service.GetLeagueResults(request.LeagueID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetResults, err.Error())
+		return fmt.Errorf("failed to retrieve league results: %v", err)
+	}
+
+	// Step 4: Marshal the results into JSON
+	resultsJSON, err := json.Marshal(results)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetResults, "Failed to serialize league results")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_GetResults,
+		Data: json.RawMessage(resultsJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
 // RemoveLeague handles the removal of a league and all associated records
 func (h *LeagueHandler) RemoveLeague(conn *ws.Connection, rawData json.RawMessage) error {
 	// Step 1: Parse the WebSocket message
 	var request struct {
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
 		LeagueID uint `json:"league_id" binding:"required"`
 	}
 
@@ -270,9 +759,14 @@ func (h *LeagueHandler) RemoveLeague(conn *ws.Connection, rawData json.RawMessag
 		ws.SendError(conn, ws.MessageType_League_RemoveLeague, "Invalid input: "+err.Error())
 		return fmt.Errorf("invalid input: %v", err)
 	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RemoveLeague, err.Error())
+		return err
+	}
 
 	// Step 3: Process business logic (reuse the service layer)
-	if err := h.service.RemoveLeague(request.LeagueID); err != nil {
+	if err := h.service.RemoveLeague(request.LeagueID, userID); err != nil {
 		ws.SendError(conn, ws.MessageType_League_RemoveLeague, err.Error())
 		return fmt.Errorf("failed to retrieve portfolio with ID: %v", err)
 	}
@@ -333,22 +827,40 @@ func (h *LeagueHandler) GetPlayerPortfoliosInLeague(conn *ws.Connection, rawData
 	return nil
 }
 
+// GetAllLeagues handles browsing the league directory: the public leagues waiting on their draft that have room
+// for another player. Private leagues are only reachable through an invite.
 func (h *LeagueHandler) GetAllLeagues(conn *ws.Connection, rawData json.RawMessage) error {
-	// Step 2: Process business logic (use the service layer)
-	leagues, err := h.service.GetAllLeagues()
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID uint   `json:"user_id"` // Optional: leaves out leagues the user is already in
+		Search string `json:"search"`  // Optional: matches the league name or description
+		Limit  int    `json:"limit"`   // Optional: defaults to DefaultDirectoryLimit
+		Offset int    `json:"offset"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if len(rawData) > 0 {
+		if err := json.Unmarshal(rawData, &request); err != nil {
+			ws.SendError(conn, ws.MessageType_League_GetAllLeagues, "Invalid input: "+err.Error())
+			return fmt.Errorf("invalid input: %v", err)
+		}
+	}
+
+	// Step 3: Process business logic (use the service layer)
+	leagues, err := h.service.GetDirectory(request.Search, request.UserID, request.Limit, request.Offset)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_GetAllLeagues, err.Error())
 		return fmt.Errorf("failed to retrieve leagues: %v", err)
 	}
 
-	// Step 3: Marshal the leagues into JSON
+	// Step 4: Marshal the leagues into JSON
 	leaguesJSON, err := json.Marshal(leagues)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_League_GetAllLeagues, "Failed to serialize leagues")
 		return fmt.Errorf("serialization error: %v", err)
 	}
 
-	// Step 4: Send success response back via WebSocket
+	// Step 5: Send success response back via WebSocket
 	response := ws.WebsocketMessage{
 		Type: ws.MessageType_League_GetAllLeagues,
 		Data: json.RawMessage(leaguesJSON),
@@ -360,6 +872,107 @@ func (h *LeagueHandler) GetAllLeagues(conn *ws.Connection, rawData json.RawMessa
 	return nil
 }
 
+// RequestToJoin handles a user asking the owner of a public league to let them in.
+func (h *LeagueHandler) RequestToJoin(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint   `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID uint   `json:"league_id" binding:"required"`
+		Message  string `json:"message"` // Optional: shown to the owner
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_RequestToJoin, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RequestToJoin, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	joinRequest, err := h.service.RequestToJoin(request.LeagueID, userID, request.Message)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RequestToJoin, err.Error())
+		return fmt.Errorf("failed to request to join: %v", err)
+	}
+
+	// Step 4: Marshal the join request into JSON
+	joinRequestJSON, err := json.Marshal(joinRequest)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_RequestToJoin, "Failed to serialize join request")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_RequestToJoin,
+		Data: json.RawMessage(joinRequestJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// GetJoinRequests handles the league owner listing the requests to join waiting on an answer.
+func (h *LeagueHandler) GetJoinRequests(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID uint `json:"league_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetJoinRequests, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetJoinRequests, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	joinRequests, err := h.service.GetJoinRequests(request.LeagueID, userID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetJoinRequests, err.Error())
+		return fmt.Errorf("failed to retrieve join requests: %v", err)
+	}
+
+	// Step 4: Marshal the join requests into JSON
+	joinRequestsJSON, err := json.Marshal(joinRequests)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_League_GetJoinRequests, "Failed to serialize join requests")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_League_GetJoinRequests,
+		Data: json.RawMessage(joinRequestsJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// ApproveJoinRequest handles the league owner letting a user in.
+func (h *LeagueHandler) ApproveJoinRequest(conn *ws.Connection, rawData json.RawMessage) error {
+	return h.decideJoinRequest(conn, rawData, ws.MessageType_League_ApproveJoinRequest, true)
+}
+
+// DenyJoinRequest handles the league owner turning a user away.
+func (h *LeagueHandler) DenyJoinRequest(conn *ws.Connection, rawData json.RawMessage) error {
+	return h.decideJoinRequest(conn, rawData, ws.MessageType_League_DenyJoinRequest, false)
+}
+
 // QueueUp handles a player's queue-up action via WebSocket.
 func (h *LeagueHandler) QueueUp(conn *ws.Connection, rawData json.RawMessage) error {
 	var request struct {
@@ -488,3 +1101,50 @@ func (h *LeagueHandler) HandleDisconnect(leagueID uint, conn *ws.Connection) err
 
 	return nil
 }
+
+// * Helper functions
+
+// decideJoinRequest answers a join request with the reply sent as the given message type
+func (h *LeagueHandler) decideJoinRequest(conn *ws.Connection, rawData json.RawMessage, messageType string, approve bool) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID    uint `json:"user_id"` // Optional: must be the signed-in user
+		RequestID uint `json:"request_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, messageType, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, messageType, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	joinRequest, err := h.service.DecideJoinRequest(request.RequestID, userID, approve)
+	if err != nil {
+		ws.SendError(conn, messageType, err.Error())
+		return fmt.Errorf("failed to answer join request: %v", err)
+	}
+
+	// Step 4: Marshal the join request into JSON
+	joinRequestJSON, err := json.Marshal(joinRequest)
+	if err != nil {
+		ws.SendError(conn, messageType, "Failed to serialize join request")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: messageType,
+		Data: json.RawMessage(joinRequestJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
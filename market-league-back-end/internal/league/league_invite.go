@@ -0,0 +1,163 @@
+package league
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// inviteCodeAlphabet leaves out characters that are easy to misread, such as O and 0 or I and 1
+const inviteCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+// inviteCodeLength is the number of characters in an invite code
+const inviteCodeLength = 8
+
+// CreateInvite lets the league owner or an admin create an invite code. A nil expiresAt never expires and a nil
+// maxUses can be redeemed any number of times.
+func (s *LeagueService) CreateInvite(leagueID, userID uint, expiresAt *time.Time, maxUses *int) (*models.LeagueInvite, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "invite players"); err != nil {
+		return nil, err
+	}
+	if err := checkJoinOpen(league); err != nil {
+		return nil, err
+	}
+	if expiresAt != nil && !expiresAt.After(utils.Now()) {
+		return nil, fmt.Errorf("the invite must expire in the future")
+	}
+	if maxUses != nil && *maxUses < 1 {
+		return nil, fmt.Errorf("an invite needs at least one use")
+	}
+
+	code, err := generateInviteCode()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite code: %v", err)
+	}
+	invite := &models.LeagueInvite{
+		LeagueID:  leagueID,
+		Code:      code,
+		CreatedBy: userID,
+		ExpiresAt: expiresAt,
+		MaxUses:   maxUses,
+	}
+	if err := s.repo.CreateInvite(invite); err != nil {
+		return nil, fmt.Errorf("failed to create invite: %v", err)
+	}
+	s.setInviteLink(invite)
+	return invite, nil
+}
+
+// GetInvites lets the league owner or an admin list the invites of a league that can still be redeemed
+func (s *LeagueService) GetInvites(leagueID, userID uint) ([]models.LeagueInvite, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "see its invites"); err != nil {
+		return nil, err
+	}
+
+	invites, err := s.repo.GetOutstandingInvites(leagueID, utils.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch invites: %v", err)
+	}
+	for index := range invites {
+		s.setInviteLink(&invites[index])
+	}
+	return invites, nil
+}
+
+// RevokeInvite lets the league owner or an admin revoke an invite so it can no longer be redeemed
+func (s *LeagueService) RevokeInvite(inviteID, userID uint) (*models.LeagueInvite, error) {
+	invite, err := s.repo.GetInvite(inviteID)
+	if err != nil {
+		return nil, err
+	}
+	league, err := s.repo.GetLeague(invite.LeagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "revoke its invites"); err != nil {
+		return nil, err
+	}
+
+	now := utils.Now()
+	revoked, err := s.repo.RevokeInvite(inviteID, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke invite: %v", err)
+	}
+	if !revoked {
+		return nil, fmt.Errorf("the invite has already been revoked")
+	}
+	invite.RevokedAt = &now
+	s.setInviteLink(invite)
+	return invite, nil
+}
+
+// RedeemInvite adds the user redeeming an invite code to its league and returns their new portfolio
+func (s *LeagueService) RedeemInvite(code string, userID uint) (*models.Portfolio, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	if code == "" {
+		return nil, fmt.Errorf("an invite code is required")
+	}
+
+	portfolio, err := s.repo.RedeemInvite(code, userID, utils.Now())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("League %d: user %d joined with invite %s", portfolio.LeagueID, userID, code)
+
+	// Let the other players see who joined
+	if err := s.BroadcastLeagueDetails(portfolio.LeagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", portfolio.LeagueID, err)
+	}
+	return portfolio, nil
+}
+
+// * Helper functions
+
+// checkOwner returns an error unless the user owns the league or is an admin. The action completes the
+// sentence "only the league owner can ...".
+func (s *LeagueService) checkOwner(league *models.League, userID uint, action string) error {
+	if league.OwnerID == userID {
+		return nil
+	}
+	isAdmin, err := s.userRepo.IsAdmin(userID)
+	if err != nil {
+		return err
+	}
+	if !isAdmin {
+		return fmt.Errorf("only the league owner can %s", action)
+	}
+	return nil
+}
+
+// setInviteLink fills in the shareable link of an invite when an invite link base is configured
+func (s *LeagueService) setInviteLink(invite *models.LeagueInvite) {
+	if s.inviteLinkBase != "" {
+		invite.Link = s.inviteLinkBase + "/" + invite.Code
+	}
+}
+
+// generateInviteCode returns a random invite code
+func generateInviteCode() (string, error) {
+	code := make([]byte, inviteCodeLength)
+	max := big.NewInt(int64(len(inviteCodeAlphabet)))
+	for index := range code {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		code[index] = inviteCodeAlphabet[n.Int64()]
+	}
+	return string(code), nil
+}
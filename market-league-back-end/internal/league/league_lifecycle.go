@@ -0,0 +1,345 @@
+package league
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/utils"
+)
+
+// LeagueEvent moves a league from one state to the next, whether a player or a scheduled job caused it
+type LeagueEvent string
+
+const (
+	Event_StartDraft  LeagueEvent = "start_draft"
+	Event_FinishDraft LeagueEvent = "finish_draft"
+	Event_Complete    LeagueEvent = "complete"
+)
+
+// leagueTransition is the states an event moves a league out of and the state it moves it to
+type leagueTransition struct {
+	from []models.LeagueState
+	to   models.LeagueState
+}
+
+var leagueTransitions = map[LeagueEvent]leagueTransition{
+	Event_StartDraft:  {from: []models.LeagueState{models.PreDraft}, to: models.InDraft},
+	Event_FinishDraft: {from: []models.LeagueState{models.InDraft}, to: models.PostDraft},
+	// A league mid draft finishes its draft first, completing it would leave the draft loop running
+	Event_Complete: {from: []models.LeagueState{models.PreDraft, models.PostDraft}, to: models.Completed},
+}
+
+// Transition applies an event to a league. It reports false without an error when the league is not in a
+// state the event moves it out of, such as a draft another caller already started.
+func (s *LeagueService) Transition(leagueID uint, event LeagueEvent) (bool, error) {
+	transition, ok := leagueTransitions[event]
+	if !ok {
+		return false, fmt.Errorf("unknown league event %s", event)
+	}
+	moved, err := s.repo.TransitionLeague(leagueID, transition.from, transition.to)
+	if err != nil {
+		return false, fmt.Errorf("failed to %s league %d: %w", event, leagueID, err)
+	}
+	if moved {
+		log.Printf("League %d: %s, now %s", leagueID, event, transition.to)
+	}
+	return moved, nil
+}
+
+// StartDueDrafts starts the drafts whose draft time has come, putting the players who have not queued up
+// on autopick. It returns how many drafts started.
+func (s *LeagueService) StartDueDrafts(now time.Time) (int, error) {
+	leagues, err := s.repo.GetLeaguesDueForDraft(now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find drafts to start: %w", err)
+	}
+
+	// One league failing does not hold up the rest
+	started := 0
+	var failed error
+	for _, league := range leagues {
+		moved, err := s.startDraft(league.ID, true)
+		if err != nil {
+			log.Printf("Error starting draft of league %d: %v", league.ID, err)
+			failed = err
+			continue
+		}
+		if moved {
+			started++
+		}
+	}
+	return started, failed
+}
+
+// LockDueRosters locks the rosters of drafted leagues that lock them once they start. It returns how many locked.
+func (s *LeagueService) LockDueRosters(now time.Time) (int, error) {
+	leagues, err := s.repo.GetLeaguesDueForRosterLock(now)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find rosters to lock: %w", err)
+	}
+
+	locked := 0
+	var failed error
+	for _, league := range leagues {
+		moved, err := s.repo.LockRosters(league.ID, []models.LeagueState{models.PostDraft}, now)
+		if err != nil {
+			log.Printf("Error locking rosters of league %d: %v", league.ID, err)
+			failed = fmt.Errorf("failed to lock rosters of league %d: %w", league.ID, err)
+			continue
+		}
+		if !moved {
+			continue
+		}
+		locked++
+		log.Printf("League %d: rosters locked", league.ID)
+		if err := s.BroadcastLeagueDetails(league.ID); err != nil {
+			log.Printf("Error broadcasting league %d: %v", league.ID, err)
+		}
+	}
+	return locked, failed
+}
+
+// CompleteDueLeagues completes the leagues whose end date is before a time, scoring them under runKey. It
+// returns how many completed.
+func (s *LeagueService) CompleteDueLeagues(before time.Time, runKey string, portfolioService *portfolio.PortfolioService) (int, error) {
+	leagues, err := s.repo.GetLeaguesEndingBefore(before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find leagues to complete: %w", err)
+	}
+
+	completed := 0
+	var failed error
+	for _, league := range leagues {
+		moved, err := s.CompleteLeague(league.ID, runKey, portfolioService)
+		if err != nil {
+			log.Printf("Error completing league %d: %v", league.ID, err)
+			failed = err
+			continue
+		}
+		if moved {
+			completed++
+		}
+	}
+	return completed, failed
+}
+
+// CompleteLeague finalizes a league's season and marks it completed. Holdings are closed at the end date, the
+// league is scored one last time and its final standings and awards are stored. Every step can be repeated, so
+// a completion that failed part way is finished by the next run. The final scoring is logged under runKey, the
+// key of the day's scoring, so it replaces that day's points history entry instead of adding another.
+func (s *LeagueService) CompleteLeague(leagueID uint, runKey string, portfolioService *portfolio.PortfolioService) (bool, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get league %d: %w", leagueID, err)
+	}
+	if league.LeagueState == models.Completed {
+		return false, nil
+	}
+	if league.LeagueState == models.InDraft {
+		return false, fmt.Errorf("league %d is still drafting", leagueID)
+	}
+
+	// Step 1: Close every holding at the end date so later prices no longer move it
+	if err := s.repo.CloseHoldingsByLeagueID(leagueID, league.EndDate); err != nil {
+		return false, fmt.Errorf("failed to close holdings of league %d: %w", leagueID, err)
+	}
+
+	// Step 2: Final scoring, a retry replaces the entries of an earlier attempt
+	if err := portfolioService.CalculateLeaguePortfolioTotalValues(leagueID, runKey); err != nil {
+		return false, fmt.Errorf("failed final scoring of league %d: %w", leagueID, err)
+	}
+
+	// Step 3: Store the final standings and hand out the awards
+	standings, awards, err := s.calculateResults(leagueID)
+	if err != nil {
+		return false, fmt.Errorf("failed to calculate results of league %d: %w", leagueID, err)
+	}
+	if err := s.repo.ReplaceResults(leagueID, standings, awards); err != nil {
+		return false, fmt.Errorf("failed to record results of league %d: %w", leagueID, err)
+	}
+
+	// Step 4: Mark the league completed, scoring runs leave it alone from now on
+	moved, err := s.Transition(leagueID, Event_Complete)
+	if err != nil || !moved {
+		return moved, err
+	}
+
+	// Step 5: Let the players know
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", leagueID, err)
+	}
+	return true, nil
+}
+
+// GetLeagueResults returns the final standings and awards of a completed league
+func (s *LeagueService) GetLeagueResults(leagueID uint) (*models.LeagueResults, error) {
+	league, err := s.repo.GetLeagueDetails(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch league details: %v", err)
+	}
+	if league.LeagueState != models.Completed {
+		return nil, fmt.Errorf("league %d has not completed yet", leagueID)
+	}
+
+	standings, err := s.repo.GetFinalStandings(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch final standings: %v", err)
+	}
+	portfolios, err := s.portfolioRepo.GetPortfoliosForLeague(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	rosters := make(map[uint][]models.Stock, len(portfolios))
+	for _, p := range portfolios {
+		rosters[p.ID] = p.Stocks
+	}
+	for index := range standings {
+		standings[index].Stocks = rosters[standings[index].PortfolioID]
+	}
+
+	return &models.LeagueResults{
+		LeagueID:   league.ID,
+		LeagueName: league.LeagueName,
+		StartDate:  league.StartDate,
+		EndDate:    league.EndDate,
+		Standings:  standings,
+		Awards:     league.Awards,
+	}, nil
+}
+
+// * Helper functions
+
+// startDraft moves a league into its draft and starts the draft loop. Players who have not queued up are put on
+// autopick when autopickAbsent is set. It reports false when the draft had already started, and an error when
+// the league is short of players.
+func (s *LeagueService) startDraft(leagueID uint, autopickAbsent bool) (bool, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get league %d: %w", leagueID, err)
+	}
+	if league.LeagueState != models.PreDraft {
+		return false, nil
+	}
+	if err := checkCanDraft(league, len(league.Users)); err != nil {
+		return false, err
+	}
+
+	moved, err := s.Transition(leagueID, Event_StartDraft)
+	if err != nil || !moved {
+		return moved, err
+	}
+	if autopickAbsent {
+		if err := s.repo.SetAbsentPlayersAutoPick(leagueID); err != nil {
+			log.Printf("Error putting absent players of league %d on autopick: %v", leagueID, err)
+		}
+	}
+
+	// Broadcast the updated league details with its new state
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", leagueID, err)
+	}
+
+	// Start the drafting loop in its own goroutine.
+	go s.startDraftLoop(leagueID)
+	return true, nil
+}
+
+// calculateResults works out a league's final standings and awards from its final points. The champion is
+// first in the standings, and the best pick is the stock that scored the most points in a portfolio's final
+// breakdown.
+func (s *LeagueService) calculateResults(leagueID uint) ([]models.LeagueStanding, []models.LeagueAward, error) {
+	portfolios, err := s.portfolioRepo.GetPortfoliosForLeague(leagueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(portfolios) == 0 {
+		return nil, nil, nil
+	}
+	now := utils.Now()
+
+	// Standings, portfolios with the same points share a rank
+	sort.Slice(portfolios, func(i, j int) bool {
+		if portfolios[i].Points != portfolios[j].Points {
+			return portfolios[i].Points > portfolios[j].Points
+		}
+		return portfolios[i].ID < portfolios[j].ID
+	})
+	standings := make([]models.LeagueStanding, len(portfolios))
+	owners := make(map[uint]uint, len(portfolios)) // portfolioID -> userID
+	for index, p := range portfolios {
+		owners[p.ID] = p.UserID
+		rank := index + 1
+		if index > 0 && p.Points == portfolios[index-1].Points {
+			rank = standings[index-1].Rank
+		}
+		standings[index] = models.LeagueStanding{
+			LeagueID:    leagueID,
+			UserID:      p.UserID,
+			PortfolioID: p.ID,
+			Rank:        rank,
+			Points:      p.Points,
+			FinalizedAt: now,
+		}
+	}
+
+	// Champion, shared on a tie
+	var awards []models.LeagueAward
+	for _, standing := range standings {
+		if standing.Rank == 1 {
+			awards = append(awards, models.LeagueAward{
+				LeagueID:    leagueID,
+				Award:       models.Award_Champion,
+				UserID:      standing.UserID,
+				PortfolioID: standing.PortfolioID,
+				Points:      float64(standing.Points),
+				AwardedAt:   now,
+			})
+		}
+	}
+
+	// Best pick, summing the windows a stock started in
+	breakdown, err := s.repo.GetLatestBreakdowns(leagueID)
+	if err != nil {
+		return nil, nil, err
+	}
+	type pick struct {
+		portfolioID uint
+		stockID     uint
+	}
+	points := make(map[pick]float64)
+	for _, entry := range breakdown {
+		points[pick{entry.PortfolioID, entry.StockID}] += entry.Points
+	}
+	picks := make([]pick, 0, len(points))
+	for p := range points {
+		picks = append(picks, p)
+	}
+	// Ties go to the earliest portfolio and stock so a rerun hands out the same award
+	sort.Slice(picks, func(i, j int) bool {
+		if points[picks[i]] != points[picks[j]] {
+			return points[picks[i]] > points[picks[j]]
+		}
+		if picks[i].portfolioID != picks[j].portfolioID {
+			return picks[i].portfolioID < picks[j].portfolioID
+		}
+		return picks[i].stockID < picks[j].stockID
+	})
+	if len(picks) > 0 {
+		top := picks[0]
+		stockID := top.stockID
+		awards = append(awards, models.LeagueAward{
+			LeagueID:    leagueID,
+			Award:       models.Award_BestPick,
+			UserID:      owners[top.portfolioID],
+			PortfolioID: top.portfolioID,
+			StockID:     &stockID,
+			Points:      points[top],
+			AwardedAt:   now,
+		})
+	}
+	return standings, awards, nil
+}
@@ -0,0 +1,86 @@
+package league
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// LeaveLeague takes a user out of a league. Any stocks they drafted go back to the draft pool, and after the
+// draft their portfolio stays on record but is no longer scored. Players cannot leave during the draft, and the owner cannot leave their own league.
+func (s *LeagueService) LeaveLeague(leagueID, userID uint) error {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to find league: %v", err)
+	}
+	if league.OwnerID == userID {
+		return fmt.Errorf("the league owner cannot leave, remove the league instead")
+	}
+	if err := checkCanRemoveMember(league); err != nil {
+		return err
+	}
+
+	if err := s.repo.RemoveMember(leagueID, userID, utils.Now()); err != nil {
+		return err
+	}
+	log.Printf("League %d: user %d left", leagueID, userID)
+
+	// Let the other players see who left
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Printf("Error broadcasting league %d: %v", leagueID, err)
+	}
+	return nil
+}
+
+// * Helper functions
+
+// validatePlayerLimits checks the player limits a league is created or edited with
+func validatePlayerLimits(minPlayers int, maxPlayers *int) error {
+	if minPlayers < 1 {
+		return fmt.Errorf("a league needs at least one player")
+	}
+	if maxPlayers != nil && *maxPlayers < minPlayers {
+		return fmt.Errorf("the player limit cannot be below the %d players needed to draft", minPlayers)
+	}
+	return nil
+}
+
+// checkJoinOpen returns an error once a league no longer takes new players
+func checkJoinOpen(league *models.League) error {
+	if league.LeagueState != models.PreDraft {
+		return fmt.Errorf("players can only join before the draft starts")
+	}
+	return nil
+}
+
+// checkCanJoin returns an error unless a league with the given number of members can take another player
+func checkCanJoin(league *models.League, members int) error {
+	if err := checkJoinOpen(league); err != nil {
+		return err
+	}
+	if league.MaxPlayers != nil && members >= *league.MaxPlayers {
+		return fmt.Errorf("the league is full")
+	}
+	return nil
+}
+
+// checkCanDraft returns an error unless a league with the given number of members has enough players to draft
+func checkCanDraft(league *models.League, members int) error {
+	if members < league.MinPlayers {
+		return fmt.Errorf("the league needs at least %d players to draft, it has %d", league.MinPlayers, members)
+	}
+	return nil
+}
+
+// checkCanRemoveMember returns an error when players cannot leave or be removed from the league in its current state
+func checkCanRemoveMember(league *models.League) error {
+	switch league.LeagueState {
+	case models.InDraft:
+		return fmt.Errorf("players cannot leave or be removed during the draft")
+	case models.Completed:
+		return fmt.Errorf("the league has completed")
+	}
+	return nil
+}
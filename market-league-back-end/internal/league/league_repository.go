@@ -3,10 +3,13 @@ package league
 import (
 	"fmt"
 	"log"
+	"strings"
+	"time"
 
 	"github.com/market-league/internal/models"
 	"github.com/market-league/internal/portfolio"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // LeagueRepository defines the interface for league-related database operations.
@@ -29,37 +32,74 @@ func (r *LeagueRepository) CreateLeaguePlayer(lp *models.LeaguePlayer) error {
 	return r.db.Create(lp).Error
 }
 
-// AddUserToLeague adds a user to a specific league by creating a record in the User_Leagues table.
-func (r *LeagueRepository) AddUserToLeague(userID, leagueID uint) error {
-	// Fetch the league to ensure it exists
+// AddUserToLeague makes a user a member of a league. The user_leagues row, the LeaguePlayer and the Portfolio are
+// created together, so a failure part way leaves the user out of the league rather than half in it.
+func (r *LeagueRepository) AddUserToLeague(userID, leagueID uint) (*models.Portfolio, error) {
+	var portfolio *models.Portfolio
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		portfolio, err = r.addMember(tx, userID, leagueID)
+		return err
+	})
+	return portfolio, err
+}
+
+// addMember adds a user to a league within a transaction, creating their LeaguePlayer and Portfolio
+func (r *LeagueRepository) addMember(tx *gorm.DB, userID, leagueID uint) (*models.Portfolio, error) {
+	// Fetch the league to ensure it exists, locking it so concurrent joins cannot overfill it
 	var league models.League
-	if err := r.db.First(&league, leagueID).Error; err != nil {
-		return fmt.Errorf("failed to find league: %w", err)
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&league, leagueID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find league: %w", err)
 	}
 
 	// Fetch the user to ensure it exists
 	var user models.User
-	if err := r.db.First(&user, userID).Error; err != nil {
-		return fmt.Errorf("failed to find user: %w", err)
+	if err := tx.First(&user, userID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find user: %w", err)
 	}
 
 	// Check if the user is already in the league by querying the join table
 	var count int64
-	if err := r.db.Table("user_leagues").Where("user_id = ? AND league_id = ?", userID, leagueID).Count(&count).Error; err != nil {
-		return fmt.Errorf("failed to check if user is already in league: %w", err)
+	if err := tx.Table("user_leagues").Where("user_id = ? AND league_id = ?", userID, leagueID).Count(&count).Error; err != nil {
+		return nil, fmt.Errorf("failed to check if user is already in league: %w", err)
 	}
 
 	if count > 0 {
 		log.Println("User already in league")
-		return fmt.Errorf("user already in league")
+		return nil, fmt.Errorf("user already in league")
+	}
+
+	// Apply the membership rules
+	var members int64
+	if err := tx.Table("user_leagues").Where("league_id = ?", leagueID).Count(&members).Error; err != nil {
+		return nil, fmt.Errorf("failed to count league members: %w", err)
+	}
+	if err := checkCanJoin(&league, int(members)); err != nil {
+		return nil, err
 	}
 
 	// Append the user to the league's Users association
-	if err := r.db.Model(&league).Association("Users").Append(&user); err != nil {
-		return fmt.Errorf("failed to add user to league: %w", err)
+	if err := tx.Model(&league).Association("Users").Append(&user); err != nil {
+		return nil, fmt.Errorf("failed to add user to league: %w", err)
 	}
 
-	return nil
+	// Create the LeaguePlayer record for the draft
+	lp := models.LeaguePlayer{
+		LeagueID:    leagueID,
+		PlayerID:    userID,
+		DraftStatus: models.DraftNotReady, // default draft status
+	}
+	if err := tx.Create(&lp).Error; err != nil {
+		return nil, fmt.Errorf("failed to create league player record: %w", err)
+	}
+
+	// Create the portfolio the user drafts into
+	portfolio := models.Portfolio{UserID: userID, LeagueID: leagueID}
+	if err := tx.Create(&portfolio).Error; err != nil {
+		return nil, fmt.Errorf("failed to create portfolio: %w", err)
+	}
+
+	return &portfolio, nil
 }
 
 // GetLeaderboard retrieves the leaderboard for a given league ID.
@@ -80,8 +120,9 @@ func (r *LeagueRepository) GetLeaderboard(leagueID uint, portfolioService *portf
 	// Map the result into the leaderboard slice
 	for _, portfolio := range portfolios {
 		leaderboard = append(leaderboard, models.LeaderboardEntry{
-			Username:   portfolio.User.Username,
-			TotalValue: portfolio.Points,
+			PortfolioID: portfolio.ID,
+			Username:    portfolio.User.Username,
+			TotalValue:  portfolio.Points,
 		})
 	}
 
@@ -94,6 +135,8 @@ func (r *LeagueRepository) GetLeagueDetails(leagueID uint) (*models.League, erro
 	err := r.db.
 		Preload("LeaguePlayers").
 		Preload("Users").
+		Preload("RosterPositions").
+		Preload("Awards").
 		Where("id = ?", leagueID).First(&league).Error
 	return &league, err
 }
@@ -116,6 +159,9 @@ func (r *LeagueRepository) RemoveTradesByLeagueID(tx *gorm.DB, leagueID uint) er
 }
 
 func (r *LeagueRepository) RemovePortfolioPointsHistoryByLeagueID(tx *gorm.DB, leagueID uint) error {
+	if err := tx.Exec("DELETE FROM points_breakdowns WHERE portfolio_id IN (SELECT id FROM portfolios WHERE league_id = ?)", leagueID).Error; err != nil {
+		return err
+	}
 	return tx.Exec("DELETE FROM portfolio_points_histories WHERE portfolio_id IN (SELECT id FROM portfolios WHERE league_id = ?)", leagueID).Error
 }
 
@@ -129,6 +175,11 @@ func (r *LeagueRepository) RemoveOwnershipHistoriesByLeagueID(tx *gorm.DB, leagu
 	return tx.Exec("DELETE FROM ownership_histories WHERE portfolio_id IN (SELECT id FROM portfolios WHERE league_id = ?)", leagueID).Error
 }
 
+// RemoveScoringWindowsByLeagueID removes lineup scoring windows for a specific league
+func (r *LeagueRepository) RemoveScoringWindowsByLeagueID(tx *gorm.DB, leagueID uint) error {
+	return tx.Exec("DELETE FROM scoring_windows WHERE portfolio_id IN (SELECT id FROM portfolios WHERE league_id = ?)", leagueID).Error
+}
+
 // RemovePortfolioStocksByLeagueID removes all portfolio stocks associated with a league
 func (r *LeagueRepository) RemoveLeaguePortfolioStocksByLeagueID(tx *gorm.DB, leagueID uint) error {
 	query := `
@@ -164,25 +215,60 @@ func (r *LeagueRepository) RemoveLeaguePlayerByLeagueID(tx *gorm.DB, leagueID ui
 	return tx.Exec("DELETE FROM league_players WHERE league_id = ?", leagueID).Error
 }
 
+// RemoveRosterPositionsByLeagueID removes the sector requirements of a league
+func (r *LeagueRepository) RemoveRosterPositionsByLeagueID(tx *gorm.DB, leagueID uint) error {
+	return tx.Where("league_id = ?", leagueID).Delete(&models.RosterPosition{}).Error
+}
+
+// RemoveAwardsByLeagueID removes the awards of a league
+func (r *LeagueRepository) RemoveAwardsByLeagueID(tx *gorm.DB, leagueID uint) error {
+	return tx.Where("league_id = ?", leagueID).Delete(&models.LeagueAward{}).Error
+}
+
+// RemoveStandingsByLeagueID removes the final standings of a league
+func (r *LeagueRepository) RemoveStandingsByLeagueID(tx *gorm.DB, leagueID uint) error {
+	return tx.Where("league_id = ?", leagueID).Delete(&models.LeagueStanding{}).Error
+}
+
+// RemoveInvitesByLeagueID removes the invites of a league
+func (r *LeagueRepository) RemoveInvitesByLeagueID(tx *gorm.DB, leagueID uint) error {
+	return tx.Where("league_id = ?", leagueID).Delete(&models.LeagueInvite{}).Error
+}
+
+// RemoveJoinRequestsByLeagueID removes the join requests of a league
+func (r *LeagueRepository) RemoveJoinRequestsByLeagueID(tx *gorm.DB, leagueID uint) error {
+	return tx.Where("league_id = ?", leagueID).Delete(&models.LeagueJoinRequest{}).Error
+}
+
 // RemoveLeague removes the league itself
 func (r *LeagueRepository) RemoveLeague(tx *gorm.DB, leagueID uint) error {
 	return tx.Where("id = ?", leagueID).Delete(&models.League{}).Error
 }
 
-func (r *LeagueRepository) GetAllLeagues() ([]models.League, error) {
-	var leagues []models.League
-
-	// Fetch all leagues from the database
-	// You might want to add pagination here in the future
-	err := r.db.Find(&leagues).Error
-	if err != nil {
-		return nil, err
+// GetDirectory returns the public leagues still waiting on their draft that have room for another player.
+// A search matches the league name or description, and leagues the excluded user is already in are left out.
+func (r *LeagueRepository) GetDirectory(search string, excludeUserID uint, limit, offset int) ([]models.DirectoryLeague, error) {
+	players := "(SELECT COUNT(*) FROM user_leagues WHERE user_leagues.league_id = leagues.id)"
+	query := r.db.Table("leagues").
+		Select("leagues.id, leagues.league_name, leagues.description, leagues.owner_id, users.username AS owner_username, "+
+			"leagues.start_date, leagues.end_date, leagues.draft_time, leagues.max_players, "+players+" AS players").
+		Joins("LEFT JOIN users ON users.id = leagues.owner_id").
+		Where("leagues.public = ? AND leagues.league_state = ?", true, models.PreDraft).
+		Where("leagues.max_players IS NULL OR " + players + " < leagues.max_players")
+	if search != "" {
+		pattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where("LOWER(leagues.league_name) LIKE ? OR LOWER(leagues.description) LIKE ?", pattern, pattern)
+	}
+	if excludeUserID != 0 {
+		query = query.Where("leagues.id NOT IN (SELECT league_id FROM user_leagues WHERE user_id = ?)", excludeUserID)
 	}
 
-	return leagues, nil
+	var leagues []models.DirectoryLeague
+	err := query.Order("leagues.id DESC").Limit(limit).Offset(offset).Scan(&leagues).Error
+	return leagues, err
 }
 
-// QueueUpPlayer updates the player's draft status to "ready"
+// QueueUpPlayer updates the player's draft status to "ready", which also takes them off autopick
 func (r *LeagueRepository) QueueUpPlayer(leagueID uint, playerID uint) error {
 	var leaguePlayer models.LeaguePlayer
 	if err := r.db.Where("league_id = ? AND player_id = ?", leagueID, playerID).First(&leaguePlayer).Error; err != nil {
@@ -205,7 +291,7 @@ func (r *LeagueRepository) AllPlayersReady(leagueID uint) (bool, error) {
 // GetLeague retrieves a league along with its players
 func (r *LeagueRepository) GetLeague(leagueID uint) (*models.League, error) {
 	var league models.League
-	err := r.db.Preload("Users").First(&league, leagueID).Error
+	err := r.db.Preload("Users").Preload("RosterPositions").First(&league, leagueID).Error
 	return &league, err
 }
 
@@ -213,3 +299,381 @@ func (r *LeagueRepository) GetLeague(leagueID uint) (*models.League, error) {
 func (r *LeagueRepository) UpdateLeague(league *models.League) error {
 	return r.db.Save(league).Error
 }
+
+// TransitionLeague moves a league to a new state only while it is in one of the from states, so a transition
+// made at the same time by a command and a job happens once. It reports whether the league moved.
+func (r *LeagueRepository) TransitionLeague(leagueID uint, from []models.LeagueState, to models.LeagueState) (bool, error) {
+	result := r.db.Model(&models.League{}).
+		Where("id = ? AND league_state IN ?", leagueID, from).
+		Update("league_state", to)
+	return result.RowsAffected > 0, result.Error
+}
+
+// LockRosters records when a league's rosters locked, unless they already are. It reports whether they locked now.
+func (r *LeagueRepository) LockRosters(leagueID uint, states []models.LeagueState, at time.Time) (bool, error) {
+	result := r.db.Model(&models.League{}).
+		Where("id = ? AND league_state IN ? AND rosters_locked_at IS NULL", leagueID, states).
+		Update("rosters_locked_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// GetLeaguesDueForDraft returns the leagues waiting on a draft whose draft time has come and that have enough
+// players to draft. A league short of players waits past its draft time until enough join.
+func (r *LeagueRepository) GetLeaguesDueForDraft(now time.Time) ([]models.League, error) {
+	var leagues []models.League
+	err := r.db.
+		Where("league_state = ? AND draft_time IS NOT NULL AND draft_time <= ?", models.PreDraft, now).
+		Where("(SELECT COUNT(*) FROM user_leagues WHERE user_leagues.league_id = leagues.id) >= leagues.min_players").
+		Find(&leagues).Error
+	return leagues, err
+}
+
+// GetLeaguesDueForRosterLock returns the drafted leagues that lock their rosters and have started
+func (r *LeagueRepository) GetLeaguesDueForRosterLock(now time.Time) ([]models.League, error) {
+	var leagues []models.League
+	err := r.db.
+		Where("league_state = ? AND lock_rosters = ? AND rosters_locked_at IS NULL AND start_date <= ?", models.PostDraft, true, now).
+		Find(&leagues).Error
+	return leagues, err
+}
+
+// GetLeaguesEndingBefore returns the leagues not completed yet whose end date is before a time
+func (r *LeagueRepository) GetLeaguesEndingBefore(before time.Time) ([]models.League, error) {
+	var leagues []models.League
+	err := r.db.
+		Where("league_state != ? AND end_date < ?", models.Completed, before).
+		Find(&leagues).Error
+	return leagues, err
+}
+
+// SetAbsentPlayersAutoPick puts the players of a league who have not queued up on autopick
+func (r *LeagueRepository) SetAbsentPlayersAutoPick(leagueID uint) error {
+	return r.db.Model(&models.LeaguePlayer{}).
+		Where("league_id = ? AND draft_status != ?", leagueID, models.DraftReady).
+		Update("draft_status", models.DraftAutoPick).Error
+}
+
+// GetDraftStatuses returns the draft status of every player in a league
+func (r *LeagueRepository) GetDraftStatuses(leagueID uint) (map[uint]models.DraftStatus, error) {
+	var players []models.LeaguePlayer
+	if err := r.db.Where("league_id = ?", leagueID).Find(&players).Error; err != nil {
+		return nil, err
+	}
+	statuses := make(map[uint]models.DraftStatus, len(players))
+	for _, player := range players {
+		statuses[player.PlayerID] = player.DraftStatus
+	}
+	return statuses, nil
+}
+
+// ReplaceResults records the final standings and awards of a league in place of any it was given before
+func (r *LeagueRepository) ReplaceResults(leagueID uint, standings []models.LeagueStanding, awards []models.LeagueAward) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := r.RemoveStandingsByLeagueID(tx, leagueID); err != nil {
+			return err
+		}
+		if err := r.RemoveAwardsByLeagueID(tx, leagueID); err != nil {
+			return err
+		}
+		if len(standings) > 0 {
+			if err := tx.Create(&standings).Error; err != nil {
+				return err
+			}
+		}
+		if len(awards) > 0 {
+			return tx.Create(&awards).Error
+		}
+		return nil
+	})
+}
+
+// GetFinalStandings returns the final standings of a league with the players' usernames, best first
+func (r *LeagueRepository) GetFinalStandings(leagueID uint) ([]models.FinalStanding, error) {
+	var standings []models.FinalStanding
+	err := r.db.Table("league_standings").
+		Select("league_standings.rank, league_standings.user_id, users.username, league_standings.portfolio_id, league_standings.points").
+		Joins("JOIN users ON users.id = league_standings.user_id").
+		Where("league_standings.league_id = ?", leagueID).
+		Order("league_standings.rank, users.username").
+		Scan(&standings).Error
+	return standings, err
+}
+
+// CloseHoldingsByLeagueID ends the open ownership history and scoring windows of every portfolio in a league
+func (r *LeagueRepository) CloseHoldingsByLeagueID(leagueID uint, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			UPDATE ownership_histories SET end_date = ?
+			WHERE end_date IS NULL AND portfolio_id IN (SELECT id FROM portfolios WHERE league_id = ?)`, at, leagueID).Error; err != nil {
+			return err
+		}
+		return tx.Exec(`
+			UPDATE scoring_windows SET end_date = ?
+			WHERE end_date IS NULL AND portfolio_id IN (SELECT id FROM portfolios WHERE league_id = ?)`, at, leagueID).Error
+	})
+}
+
+// GetLatestBreakdowns returns the points breakdown of the latest points history entry of each portfolio in a league
+func (r *LeagueRepository) GetLatestBreakdowns(leagueID uint) ([]models.PointsBreakdown, error) {
+	var breakdown []models.PointsBreakdown
+	err := r.db.Raw(`
+		SELECT points_breakdowns.* FROM points_breakdowns
+		WHERE points_history_id IN (
+			SELECT MAX(portfolio_points_histories.id) FROM portfolio_points_histories
+			JOIN portfolios ON portfolios.id = portfolio_points_histories.portfolio_id
+			WHERE portfolios.league_id = ?
+			GROUP BY portfolio_points_histories.portfolio_id
+		)`, leagueID).Scan(&breakdown).Error
+	return breakdown, err
+}
+
+// CreateInvite stores a new league invite
+func (r *LeagueRepository) CreateInvite(invite *models.LeagueInvite) error {
+	return r.db.Create(invite).Error
+}
+
+// GetInvite fetches an invite by ID
+func (r *LeagueRepository) GetInvite(inviteID uint) (*models.LeagueInvite, error) {
+	var invite models.LeagueInvite
+	if err := r.db.First(&invite, inviteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find invite %d: %w", inviteID, err)
+	}
+	return &invite, nil
+}
+
+// GetOutstandingInvites returns the invites of a league that can still be redeemed, newest first
+func (r *LeagueRepository) GetOutstandingInvites(leagueID uint, now time.Time) ([]models.LeagueInvite, error) {
+	var invites []models.LeagueInvite
+	err := r.db.
+		Where("league_id = ? AND revoked_at IS NULL", leagueID).
+		Where("expires_at IS NULL OR expires_at > ?", now).
+		Where("max_uses IS NULL OR uses < max_uses").
+		Order("id DESC").
+		Find(&invites).Error
+	return invites, err
+}
+
+// RevokeInvite marks an invite revoked, unless it already is. It reports whether it was revoked now.
+func (r *LeagueRepository) RevokeInvite(inviteID uint, at time.Time) (bool, error) {
+	result := r.db.Model(&models.LeagueInvite{}).
+		Where("id = ? AND revoked_at IS NULL", inviteID).
+		Update("revoked_at", at)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RedeemInvite uses up one redemption of an invite code and adds the user to its league in the same transaction,
+// so a code can never let in more users than its usage limit, and a failed join does not count as a use.
+func (r *LeagueRepository) RedeemInvite(code string, userID uint, now time.Time) (*models.Portfolio, error) {
+	var portfolio *models.Portfolio
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var invite models.LeagueInvite
+		if err := tx.Where("code = ?", code).First(&invite).Error; err != nil {
+			return fmt.Errorf("invite code %s not found", code)
+		}
+		if invite.RevokedAt != nil {
+			return fmt.Errorf("the invite has been revoked")
+		}
+		if invite.ExpiresAt != nil && !now.Before(*invite.ExpiresAt) {
+			return fmt.Errorf("the invite expired at %s", invite.ExpiresAt.Format(time.RFC3339))
+		}
+
+		// Claim a use, only one of two users racing for the last use gets it
+		result := tx.Model(&models.LeagueInvite{}).
+			Where("id = ? AND revoked_at IS NULL AND (max_uses IS NULL OR uses < max_uses)", invite.ID).
+			Update("uses", gorm.Expr("uses + 1"))
+		if result.Error != nil {
+			return fmt.Errorf("failed to redeem invite: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("the invite has been used up")
+		}
+
+		var err error
+		portfolio, err = r.addMember(tx, userID, invite.LeagueID)
+		return err
+	})
+	return portfolio, err
+}
+
+// IsMember reports whether a user is in a league
+func (r *LeagueRepository) IsMember(leagueID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Table("user_leagues").Where("league_id = ? AND user_id = ?", leagueID, userID).Count(&count).Error
+	return count > 0, err
+}
+
+// CreateJoinRequest stores a new request to join a league
+func (r *LeagueRepository) CreateJoinRequest(request *models.LeagueJoinRequest) error {
+	return r.db.Create(request).Error
+}
+
+// HasPendingJoinRequest reports whether a user is already waiting on an answer from a league
+func (r *LeagueRepository) HasPendingJoinRequest(leagueID, userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.LeagueJoinRequest{}).
+		Where("league_id = ? AND user_id = ? AND status = ?", leagueID, userID, models.JoinRequest_Pending).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// GetJoinRequest fetches a join request by ID
+func (r *LeagueRepository) GetJoinRequest(requestID uint) (*models.LeagueJoinRequest, error) {
+	var request models.LeagueJoinRequest
+	if err := r.db.First(&request, requestID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find join request %d: %w", requestID, err)
+	}
+	return &request, nil
+}
+
+// GetPendingJoinRequests returns the requests to join a league that are waiting on an answer, oldest first
+func (r *LeagueRepository) GetPendingJoinRequests(leagueID uint) ([]models.LeagueJoinRequest, error) {
+	var requests []models.LeagueJoinRequest
+	err := r.db.Table("league_join_requests").
+		Select("league_join_requests.*, users.username").
+		Joins("JOIN users ON users.id = league_join_requests.user_id").
+		Where("league_join_requests.league_id = ? AND league_join_requests.status = ?", leagueID, models.JoinRequest_Pending).
+		Order("league_join_requests.id ASC").
+		Scan(&requests).Error
+	return requests, err
+}
+
+// DecideJoinRequest answers a pending join request. An approved request adds the user to the league in the same
+// transaction, so the request is only marked approved when the user got in.
+func (r *LeagueRepository) DecideJoinRequest(requestID, deciderID uint, approve bool, at time.Time) error {
+	status := models.JoinRequest_Denied
+	if approve {
+		status = models.JoinRequest_Approved
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.LeagueJoinRequest{}).
+			Where("id = ? AND status = ?", requestID, models.JoinRequest_Pending).
+			Updates(map[string]interface{}{"status": status, "decided_by": deciderID, "decided_at": at})
+		if result.Error != nil {
+			return fmt.Errorf("failed to update join request: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("the join request has already been answered")
+		}
+		if !approve {
+			return nil
+		}
+
+		var request models.LeagueJoinRequest
+		if err := tx.First(&request, requestID).Error; err != nil {
+			return fmt.Errorf("failed to find join request %d: %w", requestID, err)
+		}
+		_, err := r.addMember(tx, request.UserID, request.LeagueID)
+		return err
+	})
+}
+
+// CountMembers returns how many users are in a league
+func (r *LeagueRepository) CountMembers(leagueID uint) (int, error) {
+	var count int64
+	err := r.db.Table("user_leagues").Where("league_id = ?", leagueID).Count(&count).Error
+	return int(count), err
+}
+
+// RemoveMember takes a user out of a league in one transaction. The stocks in their portfolio go back to the
+// draft pool, their pending trades are dropped, and their LeaguePlayer record and user_leagues row are removed.
+// Before the draft their portfolio is removed too. After it the portfolio is kept, emptied and marked as left
+// at the given time, so its history and the trades it took part in stay on record.
+func (r *LeagueRepository) RemoveMember(leagueID, userID uint, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return r.removeMember(tx, leagueID, userID, at)
+	})
+}
+
+// removeMember takes a user out of a league within a transaction
+func (r *LeagueRepository) removeMember(tx *gorm.DB, leagueID, userID uint, at time.Time) error {
+	var league models.League
+	if err := tx.First(&league, leagueID).Error; err != nil {
+		return fmt.Errorf("failed to find league %d: %w", leagueID, err)
+	}
+	var count int64
+	if err := tx.Table("user_leagues").Where("league_id = ? AND user_id = ?", leagueID, userID).Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check if user is in league: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("user %d is not in league %d", userID, leagueID)
+	}
+
+	var portfolios []models.Portfolio
+	if err := tx.Preload("Stocks").Where("league_id = ? AND user_id = ? AND left_at IS NULL", leagueID, userID).Find(&portfolios).Error; err != nil {
+		return fmt.Errorf("failed to find portfolio: %w", err)
+	}
+	for index := range portfolios {
+		portfolio := &portfolios[index]
+
+		// Return the drafted stocks to the pool
+		if len(portfolio.Stocks) > 0 {
+			var pool models.LeaguePortfolio
+			if err := tx.Where("league_id = ?", leagueID).First(&pool).Error; err != nil {
+				return fmt.Errorf("failed to find the draft pool: %w", err)
+			}
+			if err := tx.Model(&pool).Association("Stocks").Append(portfolio.Stocks); err != nil {
+				return fmt.Errorf("failed to return stocks to the draft pool: %w", err)
+			}
+		}
+
+		// Drop the trades still waiting on the departing player, finished trades stay on record
+		var pendingTrades []models.Trade
+		if err := tx.Where("status = ? AND (portfolio1_id = ? OR portfolio2_id = ?)", "pending", portfolio.ID, portfolio.ID).
+			Find(&pendingTrades).Error; err != nil {
+			return fmt.Errorf("failed to find pending trades: %w", err)
+		}
+		if len(pendingTrades) > 0 {
+			if err := tx.Select("Stocks1", "Stocks2").Delete(&pendingTrades).Error; err != nil {
+				return fmt.Errorf("failed to remove pending trades: %w", err)
+			}
+		}
+
+		if err := tx.Model(portfolio).Association("Stocks").Clear(); err != nil {
+			return fmt.Errorf("failed to empty portfolio %d: %w", portfolio.ID, err)
+		}
+		if league.LeagueState == models.PreDraft {
+			// Nothing has been recorded against the portfolio yet
+			if err := tx.Delete(&models.Portfolio{}, portfolio.ID).Error; err != nil {
+				return fmt.Errorf("failed to remove portfolio %d: %w", portfolio.ID, err)
+			}
+			continue
+		}
+
+		// Close what the portfolio still holds and keep it out of scoring from now on
+		if err := tx.Model(&models.OwnershipHistory{}).Where("portfolio_id = ? AND end_date IS NULL", portfolio.ID).
+			Update("end_date", at).Error; err != nil {
+			return fmt.Errorf("failed to close the holdings of portfolio %d: %w", portfolio.ID, err)
+		}
+		if err := tx.Model(&models.ScoringWindow{}).Where("portfolio_id = ? AND end_date IS NULL", portfolio.ID).
+			Update("end_date", at).Error; err != nil {
+			return fmt.Errorf("failed to close the scoring windows of portfolio %d: %w", portfolio.ID, err)
+		}
+		if err := tx.Model(portfolio).Update("left_at", at).Error; err != nil {
+			return fmt.Errorf("failed to mark portfolio %d as left: %w", portfolio.ID, err)
+		}
+	}
+
+	if err := tx.Where("league_id = ? AND player_id = ?", leagueID, userID).Delete(&models.LeaguePlayer{}).Error; err != nil {
+		return fmt.Errorf("failed to remove league player: %w", err)
+	}
+	if err := tx.Model(&league).Association("Users").Delete(&models.User{ID: userID}); err != nil {
+		return fmt.Errorf("failed to remove user from league: %w", err)
+	}
+	return nil
+}
+
+// SetOwner hands a league to a new owner
+func (r *LeagueRepository) SetOwner(leagueID, ownerID uint) error {
+	return r.db.Model(&models.League{}).Where("id = ?", leagueID).Update("owner_id", ownerID).Error
+}
+
+// UpdateLeagueSettings saves the editable settings of a league, provided it is still in the state it was read in.
+// It reports false when the league moved on in the meantime, such as a draft that started.
+func (r *LeagueRepository) UpdateLeagueSettings(league *models.League) (bool, error) {
+	result := r.db.Model(&models.League{ID: league.ID}).
+		Where("league_state = ?", league.LeagueState).
+		Select("league_name", "description", "public", "start_date", "end_date", "draft_time", "lock_rosters", "min_players", "max_players").
+		Updates(league)
+	return result.RowsAffected > 0, result.Error
+}
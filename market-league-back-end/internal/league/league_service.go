@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"math/rand"
+	"strings"
 	"sync"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	leagueportfolio "github.com/market-league/internal/league_portfolio"
 	"github.com/market-league/internal/models"
 	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/roster"
 	"github.com/market-league/internal/user"
 )
 
@@ -24,6 +26,7 @@ type LeagueService struct {
 	leaguePortfolioService *leagueportfolio.LeaguePortfolioService
 	activeDraftChannels    map[uint]chan uint   // activeDraftChannels maps leagueID to a channel that receives a drafted stockID.
 	activePlayerTimers     map[uint]playerTimer // Maps leagueID to current player's timer
+	inviteLinkBase         string               // Invite links are this URL followed by the code
 	mu                     sync.Mutex           // Protect concurrent access to maps.
 }
 
@@ -56,19 +59,61 @@ func (s *LeagueService) SetLeaguePortfolioService(lpService *leagueportfolio.Lea
 	s.leaguePortfolioService = lpService
 }
 
+// SetInviteLinkBase sets the URL invite links are built from, invites have no link without one.
+func (s *LeagueService) SetInviteLinkBase(base string) {
+	s.inviteLinkBase = strings.TrimRight(base, "/")
+}
+
+// LeagueSettings holds the roster configuration and schedule a league is created with.
+type LeagueSettings struct {
+	StartingSlots   int
+	BenchSlots      int
+	RosterPositions []models.RosterPosition
+	DraftTime       *time.Time // Optional: the draft starts on its own at this time
+	LockRosters     bool       // Trades close at the start date
+	Public          bool       // Listed in the league directory
+	Description     string     // Shown in the league directory
+	MinPlayers      int        // Players needed before the draft can start
+	MaxPlayers      *int       // Optional: nil for no limit
+}
+
 // LeagueResponse represents the response with sanitized users.
 type LeagueResponse struct {
-	ID         uint                   `json:"id"`
-	LeagueName string                 `json:"league_name"`
-	StartDate  time.Time              `json:"start_date"`
-	EndDate    time.Time              `json:"end_date"`
-	Users      []models.SanitizedUser `json:"users"`
+	ID              uint                    `json:"id"`
+	LeagueName      string                  `json:"league_name"`
+	OwnerID         uint                    `json:"owner_id"`
+	StartDate       time.Time               `json:"start_date"`
+	EndDate         time.Time               `json:"end_date"`
+	StartingSlots   int                     `json:"starting_slots"`
+	BenchSlots      int                     `json:"bench_slots"`
+	RosterPositions []models.RosterPosition `json:"roster_positions"`
+	DraftTime       *time.Time              `json:"draft_time"`
+	LockRosters     bool                    `json:"lock_rosters"`
+	Public          bool                    `json:"public"`
+	Description     string                  `json:"description"`
+	MinPlayers      int                     `json:"min_players"`
+	MaxPlayers      *int                    `json:"max_players"`
+	Users           []models.SanitizedUser  `json:"users"`
 }
 
 // CreateLeague creates a new league with the given details.
 // Since a league starts with only one user (the owner),
 // it adds the owner to the Users slice and creates a LeaguePlayer record for them.
-func (s *LeagueService) CreateLeague(leagueName string, ownerUser uint, startDate, endDate string) (*LeagueResponse, error) {
+func (s *LeagueService) CreateLeague(leagueName string, ownerUser uint, startDate, endDate string, settings LeagueSettings) (*LeagueResponse, error) {
+	// Validate the roster layout
+	if settings.StartingSlots < 1 {
+		return nil, fmt.Errorf("a league needs at least one starting slot")
+	}
+	if settings.BenchSlots < 0 {
+		return nil, fmt.Errorf("bench slots cannot be negative")
+	}
+	if err := roster.ValidatePositions(settings.RosterPositions, settings.StartingSlots+settings.BenchSlots); err != nil {
+		return nil, err
+	}
+	if err := validatePlayerLimits(settings.MinPlayers, settings.MaxPlayers); err != nil {
+		return nil, err
+	}
+
 	// Parse start and end dates into time.Time
 	start, err := time.Parse(time.RFC3339, startDate)
 	if err != nil {
@@ -78,6 +123,15 @@ func (s *LeagueService) CreateLeague(leagueName string, ownerUser uint, startDat
 	if err != nil {
 		return nil, fmt.Errorf("invalid end date format: %v", err)
 	}
+	if !end.After(start) {
+		return nil, fmt.Errorf("the end date must be after the start date")
+	}
+	if settings.DraftTime != nil && !settings.DraftTime.Before(end) {
+		return nil, fmt.Errorf("the draft time must be before the end date")
+	}
+	if len(settings.Description) > maxDescriptionLength {
+		return nil, fmt.Errorf("the description can be at most %d characters", maxDescriptionLength)
+	}
 
 	// Fetch the owner user by ID
 	owner, err := s.userRepo.GetUserByID(ownerUser)
@@ -87,10 +141,20 @@ func (s *LeagueService) CreateLeague(leagueName string, ownerUser uint, startDat
 
 	// Create a new league instance with the owner in the Users slice.
 	league := &models.League{
-		LeagueName: leagueName,
-		StartDate:  start,
-		EndDate:    end,
-		Users:      []models.User{*owner},
+		LeagueName:      leagueName,
+		OwnerID:         owner.ID,
+		StartDate:       start,
+		EndDate:         end,
+		StartingSlots:   settings.StartingSlots,
+		BenchSlots:      settings.BenchSlots,
+		RosterPositions: settings.RosterPositions,
+		DraftTime:       settings.DraftTime,
+		LockRosters:     settings.LockRosters,
+		Public:          settings.Public,
+		Description:     settings.Description,
+		MinPlayers:      settings.MinPlayers,
+		MaxPlayers:      settings.MaxPlayers,
+		Users:           []models.User{*owner},
 	}
 
 	// Save the league to the repository.
@@ -109,37 +173,47 @@ func (s *LeagueService) CreateLeague(leagueName string, ownerUser uint, startDat
 	}
 	league.LeaguePlayers = []models.LeaguePlayer{lp}
 
-	// Sanitize user data for the response.
-	sanitizedUsers := SanitizeUsers(league.Users)
-
 	// Return the league response with sanitized users.
-	return &LeagueResponse{
-		ID:         league.ID,
-		LeagueName: league.LeagueName,
-		StartDate:  league.StartDate,
-		EndDate:    league.EndDate,
-		Users:      sanitizedUsers,
-	}, nil
+	return newLeagueResponse(league), nil
 }
 
-// AddUserToLeague associates a user with a league and creates a LeaguePlayer record.
-func (s *LeagueService) AddUserToLeague(userID, leagueID uint) error {
-	// First, add the user to the league via the join table.
-	if err := s.repo.AddUserToLeague(userID, leagueID); err != nil {
-		return fmt.Errorf("failed to add user to league: %v", err)
+// newLeagueResponse builds the response for a league, sanitizing its users
+func newLeagueResponse(league *models.League) *LeagueResponse {
+	return &LeagueResponse{
+		ID:              league.ID,
+		LeagueName:      league.LeagueName,
+		OwnerID:         league.OwnerID,
+		StartDate:       league.StartDate,
+		EndDate:         league.EndDate,
+		StartingSlots:   league.StartingSlots,
+		BenchSlots:      league.BenchSlots,
+		RosterPositions: league.RosterPositions,
+		DraftTime:       league.DraftTime,
+		LockRosters:     league.LockRosters,
+		Public:          league.Public,
+		Description:     league.Description,
+		MinPlayers:      league.MinPlayers,
+		MaxPlayers:      league.MaxPlayers,
+		Users:           SanitizeUsers(league.Users),
 	}
+}
 
-	// Next, create a LeaguePlayer record for the new user.
-	lp := models.LeaguePlayer{
-		LeagueID:    leagueID,
-		PlayerID:    userID,
-		DraftStatus: models.DraftNotReady, // default draft status
+// AddUserToLeague lets the league owner or an admin add a user to a league directly, creating their
+// LeaguePlayer record and portfolio. Users joining on their own redeem an invite instead.
+func (s *LeagueService) AddUserToLeague(requesterID, userID, leagueID uint) (*models.Portfolio, error) {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find league: %v", err)
 	}
-	if err := s.repo.CreateLeaguePlayer(&lp); err != nil {
-		return fmt.Errorf("failed to create league player record: %v", err)
+	if err := s.checkOwner(league, requesterID, "add users to it"); err != nil {
+		return nil, err
 	}
 
-	return nil
+	portfolio, err := s.repo.AddUserToLeague(userID, leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add user to league: %v", err)
+	}
+	return portfolio, nil
 }
 
 // GetLeagueDetails retrieves details for a specific league by ID.
@@ -181,8 +255,16 @@ func (s *LeagueService) GetLeaderboard(leagueID uint, portfolioService *portfoli
 	return s.repo.GetLeaderboard(leagueID, portfolioService)
 }
 
-// RemoveLeague removes a league and all associated data in a transaction
-func (s *LeagueService) RemoveLeague(leagueID uint) error {
+// RemoveLeague lets the league owner or an admin remove a league and all associated data in a transaction
+func (s *LeagueService) RemoveLeague(leagueID, userID uint) error {
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return fmt.Errorf("failed to find league: %v", err)
+	}
+	if err := s.checkOwner(league, userID, "remove it"); err != nil {
+		return err
+	}
+
 	// Start a transaction
 	tx := s.repo.db.Begin()
 	if err := tx.Error; err != nil {
@@ -200,6 +282,11 @@ func (s *LeagueService) RemoveLeague(leagueID uint) error {
 		return err
 	}
 
+	if err := s.repo.RemoveScoringWindowsByLeagueID(tx, leagueID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := s.repo.RemovePortfolioStocksByLeagueID(tx, leagueID); err != nil {
 		tx.Rollback()
 		return err
@@ -247,6 +334,31 @@ func (s *LeagueService) RemoveLeague(leagueID uint) error {
 		return err
 	}
 
+	if err := s.repo.RemoveRosterPositionsByLeagueID(tx, leagueID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := s.repo.RemoveAwardsByLeagueID(tx, leagueID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := s.repo.RemoveStandingsByLeagueID(tx, leagueID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := s.repo.RemoveInvitesByLeagueID(tx, leagueID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := s.repo.RemoveJoinRequestsByLeagueID(tx, leagueID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
 	if err := s.repo.RemoveLeague(tx, leagueID); err != nil {
 		tx.Rollback()
 		return err
@@ -266,13 +378,9 @@ func (s *LeagueService) GetPlayerPortfoliosInLeague(leagueID uint) ([]models.Por
 	return portfolios, nil
 }
 
-func (s *LeagueService) GetAllLeagues() ([]models.League, error) {
-	// Delegate the league retrieval to the repository
-	return s.repo.GetAllLeagues()
-}
-
 // QueueUpPlayer marks a player as queued and checks if all players are ready.
-// If all are ready, it updates the league state and broadcasts the update.
+// If all are ready, it starts the draft and broadcasts the update. A player on autopick
+// queuing up during the draft picks for themselves again from their next turn.
 func (s *LeagueService) QueueUpPlayer(leagueID uint, playerID uint, conn *ws.Connection) error {
 	// 1. Update the player's queue status.
 	if err := s.repo.QueueUpPlayer(leagueID, playerID); err != nil {
@@ -289,33 +397,18 @@ func (s *LeagueService) QueueUpPlayer(leagueID uint, playerID uint, conn *ws.Con
 	}
 
 	if allReady {
-		// All players are ready. Retrieve the league.
-		league, err := s.repo.GetLeague(leagueID)
+		// All players are ready, start the draft unless it already started
+		started, err := s.startDraft(leagueID, false)
 		if err != nil {
 			return err
 		}
-
-		// Update league state to indicate the draft is live.
-		league.LeagueState = models.InDraft
-		if err := s.repo.UpdateLeague(league); err != nil {
-			return err
-		}
-
-		// Broadcast the updated league details with its new state
-		if err := s.BroadcastLeagueDetails(leagueID); err != nil {
-			return err
-		}
-
-		// Start the drafting loop in its own goroutine.
-		go s.startDraftLoop(leagueID)
-	} else {
-		// Not all players are ready, broadcast the current league state
-		if err := s.BroadcastLeagueDetails(leagueID); err != nil {
-			return err
+		if started {
+			return nil
 		}
 	}
 
-	return nil
+	// The draft has not started or is already running, broadcast the current league state
+	return s.BroadcastLeagueDetails(leagueID)
 }
 
 // BroadcastLeagueDetails broadcasts the league details to all subscribers
@@ -327,15 +420,7 @@ func (s *LeagueService) BroadcastLeagueDetails(leagueID uint) error {
 	}
 
 	// Prepare the data for broadcast
-	data := gin.H{
-		"id":             league.ID,
-		"league_name":    league.LeagueName,
-		"start_date":     league.StartDate,
-		"end_date":       league.EndDate,
-		"league_state":   league.LeagueState,
-		"max_players":    league.MaxPlayers,
-		"league_players": league.LeaguePlayers,
-	}
+	data := leagueDetails(league)
 
 	// Marshal the data into JSON
 	dataJSON, err := json.Marshal(data)
@@ -394,6 +479,7 @@ func (s *LeagueService) startDraftLoop(leagueID uint) {
 	}
 
 	currentPlayerIndex := 0
+	failedAutoPicks := 0 // Autopick turns in a row that drafted nothing
 
 	// Create a ticker to broadcast the current state regularly
 	stateBroadcastTicker := time.NewTicker(1 * time.Second)
@@ -403,113 +489,111 @@ func (s *LeagueService) startDraftLoop(leagueID uint) {
 		currentPlayer := players[currentPlayerIndex]
 		log.Printf("Draft turn for player %d in league %d", currentPlayer, leagueID)
 
-		// Set up the timer for this player's turn
-		timerStart := time.Now()
-		timerEnd := timerStart.Add(draftTurnDuration)
-
-		// Store the timer information
-		s.mu.Lock()
-		s.activePlayerTimers[leagueID] = playerTimer{
-			playerID:  currentPlayer,
-			startTime: timerStart,
-			endTime:   timerEnd,
-		}
-		s.mu.Unlock()
-
-		// Notify all clients that this player is now on the clock
-		s.notifyPlayerOnClock(currentPlayer, leagueID)
-
-		timer := time.NewTimer(draftTurnDuration)
-		defer timer.Stop()
+		// Players on autopick are not waited for
+		var stockID uint
+		autoPick := s.isOnAutoPick(leagueID, currentPlayer)
+		if autoPick {
+			autoStockID, err := s.autoSelectStock(leagueID, currentPlayer)
+			if err != nil {
+				log.Printf("Auto-select error for player %d: %v", currentPlayer, err)
+			} else {
+				stockID = autoStockID
+			}
+		} else {
+			// Set up the timer for this player's turn
+			timerStart := time.Now()
+			timerEnd := timerStart.Add(draftTurnDuration)
 
-		// Add logging before waiting for selection
-		log.Printf("Waiting for player %d selection in league %d", currentPlayer, leagueID)
+			// Store the timer information
+			s.mu.Lock()
+			s.activePlayerTimers[leagueID] = playerTimer{
+				playerID:  currentPlayer,
+				startTime: timerStart,
+				endTime:   timerEnd,
+			}
+			s.mu.Unlock()
 
-		// Create a loop to handle both the timer and periodic broadcasts
-		selectionReceived := false
-		var stockID uint
+			// Notify all clients that this player is now on the clock
+			s.notifyPlayerOnClock(currentPlayer, leagueID)
+
+			timer := time.NewTimer(draftTurnDuration)
+			defer timer.Stop()
+
+			// Add logging before waiting for selection
+			log.Printf("Waiting for player %d selection in league %d", currentPlayer, leagueID)
+
+			// Create a loop to handle both the timer and periodic broadcasts
+			selectionReceived := false
+
+			for !selectionReceived {
+				select {
+				case receivedStockID := <-selectionChannel:
+					// Player made a selection within the time limit
+					log.Printf("Player %d made selection (stock ID: %d) in league %d",
+						currentPlayer, receivedStockID, leagueID)
+					timer.Stop() // Stop the timer
+					stockID = receivedStockID
+					selectionReceived = true
+
+				case <-timer.C:
+					// Timer expired, player did not make a selection in time
+					log.Printf("Timer expired for player %d in league %d",
+						currentPlayer, leagueID)
+
+					// Auto-select a stock
+					autoStockID, err := s.autoSelectStock(leagueID, currentPlayer)
+					if err != nil {
+						log.Printf("Auto-select error for player %d: %v", currentPlayer, err)
+					} else {
+						stockID = autoStockID
+					}
+					selectionReceived = true
 
-		for !selectionReceived {
-			select {
-			case receivedStockID := <-selectionChannel:
-				// Player made a selection within the time limit
-				log.Printf("Player %d made selection (stock ID: %d) in league %d",
-					currentPlayer, receivedStockID, leagueID)
-				timer.Stop() // Stop the timer
-				stockID = receivedStockID
-				selectionReceived = true
-
-			case <-timer.C:
-				// Timer expired, player did not make a selection in time
-				log.Printf("Timer expired for player %d in league %d",
-					currentPlayer, leagueID)
-
-				// Auto-select a stock
-				autoStockID, err := s.autoSelectStock(leagueID)
-				if err != nil {
-					log.Printf("Auto-select error for player %d: %v", currentPlayer, err)
-				} else {
-					stockID = autoStockID
+				case <-stateBroadcastTicker.C:
+					// Broadcast current draft state with updated timer
+					s.notifyPlayerOnClock(currentPlayer, leagueID)
 				}
-				selectionReceived = true
-
-			case <-stateBroadcastTicker.C:
-				// Broadcast current draft state with updated timer
-				s.notifyPlayerOnClock(currentPlayer, leagueID)
 			}
 		}
 
 		// Process the stock selection
+		picked := false
 		if stockID > 0 {
 			err := s.leaguePortfolioService.DraftStock(leagueID, currentPlayer, stockID)
 			if err != nil {
 				log.Printf("Error processing selection for player %d: %v", currentPlayer, err)
 			} else {
 				s.broadcastDraftPick(leagueID, currentPlayer, stockID)
+				picked = true
 			}
 		}
 
+		// Autopick turns take no time, stop once a whole round of them could not draft anything
+		if autoPick && !picked {
+			failedAutoPicks++
+		} else {
+			failedAutoPicks = 0
+		}
+		if failedAutoPicks >= len(players) {
+			log.Printf("startDraftLoop: no stock left to autopick in league %d, ending the draft", leagueID)
+			break
+		}
+
 		currentPlayerIndex = (currentPlayerIndex + 1) % len(players)
 		if updatedLeague, err := s.repo.GetLeague(leagueID); err == nil {
 			league = updatedLeague
 		}
 	}
 
-	// Update league state to PostDraft
-	league.LeagueState = models.PostDraft
-	if err := s.repo.UpdateLeague(league); err != nil {
+	// Move the league on to PostDraft, unless it was completed meanwhile
+	if _, err := s.Transition(leagueID, Event_FinishDraft); err != nil {
 		log.Println("Error updating league to PostDraft:", err)
 	}
 
-	data := gin.H{
-		"id":             league.ID,
-		"league_name":    league.LeagueName,
-		"start_date":     league.StartDate,
-		"end_date":       league.EndDate,
-		"league_state":   league.LeagueState,
-		"max_players":    league.MaxPlayers,
-		"league_players": league.LeaguePlayers,
-	}
-
-	// Marshal the data into JSON
-	dataJSON, err := json.Marshal(data)
-	if err != nil {
-		log.Println("Failed to serialize league data:", err)
-	}
-
-	// Construct the WebSocket message
-	response := ws.WebsocketMessage{
-		Type: ws.MessageType_League_GetDetails,
-		Data: json.RawMessage(dataJSON),
-	}
-
 	// Broadcast the message to all users in the league
-	respBytes, err := json.Marshal(response)
-	if err != nil {
-		log.Println("Failed to serialize WebSocket message:", err)
+	if err := s.BroadcastLeagueDetails(leagueID); err != nil {
+		log.Println("Failed to broadcast league details:", err)
 	}
-
-	ws.Manager.BroadcastToLeague(leagueID, respBytes)
 }
 
 func (s *LeagueService) waitForPlayerSelection(playerID, leagueID uint, timer *time.Timer, selectionChannel chan uint) (uint, bool) {
@@ -696,7 +780,7 @@ func (s *LeagueService) GetDraftSelectionChannel(leagueID uint) chan uint {
 }
 
 // isDraftComplete checks whether the draft is complete by verifying
-// if each player has drafted 5 stocks.
+// if each player has filled every starting and bench slot.
 func (s *LeagueService) isDraftComplete(league *models.League) bool {
 	// Get all player portfolios for this league
 	playerPortfolios, err := s.portfolioRepo.GetPortfoliosForLeague(league.ID)
@@ -726,37 +810,66 @@ func (s *LeagueService) isDraftComplete(league *models.League) bool {
 		return false
 	}
 
-	// Check if each player has drafted 5 stocks
+	// Check if each player has filled their roster
+	rosterSize := league.RosterSize()
 	for _, portfolio := range playerPortfolios {
-		if len(portfolio.Stocks) < 5 {
-			// At least one player has not drafted 5 stocks yet
+		if len(portfolio.Stocks) < rosterSize {
+			// At least one player has not filled their roster yet
 			return false
 		}
 	}
 
-	// All players have drafted 5 stocks each, so draft is complete
+	// All players have filled their rosters, so draft is complete
 	return true
 }
 
 // autoSelectStock returns an auto-selected stock for the given player.
-// Implement your own logic here.
-func (s *LeagueService) autoSelectStock(leagueID uint) (uint, error) {
+// Only stocks that keep the player's roster able to fill its sector positions are considered.
+func (s *LeagueService) autoSelectStock(leagueID, playerID uint) (uint, error) {
 	// Get the league portfolio for the given league ID
 	leaguePortfolio, err := s.leaguePortfolioService.GetLeaguePortfolioInfo(leagueID)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get league portfolio: %w", err)
 	}
 
-	// Check if there are any stocks in the portfolio
-	if len(leaguePortfolio.Stocks) == 0 {
+	// Get the player's portfolio to check roster positions against
+	portfolioID, err := s.portfolioRepo.GetPortfolioIDByUserAndLeague(playerID, leagueID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get player portfolio: %w", err)
+	}
+	playerPortfolio, err := s.portfolioRepo.GetPortfolioWithID(portfolioID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get player portfolio: %w", err)
+	}
+
+	var candidates []models.Stock
+	for _, stock := range leaguePortfolio.Stocks {
+		updatedStocks := roster.ApplyMove(playerPortfolio.Stocks, nil, []models.Stock{stock})
+		if roster.ValidateRoster(&playerPortfolio.League, updatedStocks) == nil {
+			candidates = append(candidates, stock)
+		}
+	}
+
+	// Check if there are any stocks to pick from
+	if len(candidates) == 0 {
 		return 0, fmt.Errorf("no stocks available in the league portfolio")
 	}
 
 	// Use the newer random number generation approach
-	randomIndex := rand.Intn(len(leaguePortfolio.Stocks))
+	randomIndex := rand.Intn(len(candidates))
 
 	// Return the ID of the randomly selected stock
-	return leaguePortfolio.Stocks[randomIndex].ID, nil
+	return candidates[randomIndex].ID, nil
+}
+
+// isOnAutoPick reports whether picks are made for a player, because they were absent when the draft started
+func (s *LeagueService) isOnAutoPick(leagueID, playerID uint) bool {
+	statuses, err := s.repo.GetDraftStatuses(leagueID)
+	if err != nil {
+		log.Printf("Error getting draft statuses of league %d: %v", leagueID, err)
+		return false
+	}
+	return statuses[playerID] == models.DraftAutoPick
 }
 
 // getOrderedDraftPlayers returns a slice of player IDs for the league,
@@ -803,7 +916,7 @@ func (s *LeagueService) SendCurrentDraftState(leagueID uint, conn *ws.Connection
 		if len(players) > 0 {
 			// Find the player with the fewest stocks - that's whose turn it is
 			currentPlayerID := players[0]
-			minStocks := 5 // Max stocks per player
+			minStocks := league.RosterSize() // Max stocks per player
 
 			for _, portfolio := range portfolios {
 				for _, player := range players {
@@ -861,15 +974,7 @@ func (s *LeagueService) SendCurrentDraftState(leagueID uint, conn *ws.Connection
 	}
 
 	// Prepare the league details data
-	data := gin.H{
-		"id":             league.ID,
-		"league_name":    league.LeagueName,
-		"start_date":     league.StartDate,
-		"end_date":       league.EndDate,
-		"league_state":   league.LeagueState,
-		"max_players":    league.MaxPlayers,
-		"league_players": league.LeaguePlayers,
-	}
+	data := leagueDetails(league)
 
 	// Marshal the data into JSON
 	dataJSON, err := json.Marshal(data)
@@ -890,3 +995,29 @@ func (s *LeagueService) SendCurrentDraftState(leagueID uint, conn *ws.Connection
 
 	return nil
 }
+
+// * Helper functions
+
+// leagueDetails is the league as MessageType_League_GetDetails sends it
+func leagueDetails(league *models.League) gin.H {
+	return gin.H{
+		"id":                league.ID,
+		"league_name":       league.LeagueName,
+		"public":            league.Public,
+		"description":       league.Description,
+		"owner_id":          league.OwnerID,
+		"start_date":        league.StartDate,
+		"end_date":          league.EndDate,
+		"league_state":      league.LeagueState,
+		"max_players":       league.MaxPlayers,
+		"min_players":       league.MinPlayers,
+		"starting_slots":    league.StartingSlots,
+		"bench_slots":       league.BenchSlots,
+		"league_players":    league.LeaguePlayers,
+		"roster_positions":  league.RosterPositions,
+		"draft_time":        league.DraftTime,
+		"lock_rosters":      league.LockRosters,
+		"rosters_locked_at": league.RostersLockedAt,
+		"awards":            league.Awards,
+	}
+}
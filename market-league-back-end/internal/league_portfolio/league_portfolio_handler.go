@@ -6,12 +6,14 @@ import (
 	"log"
 
 	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/models"
 )
 
 // LeaguePortfolioHandler Interface
 type LeaguePortfolioHandlerInterface interface {
 	DraftStock(conn *ws.Connection, rawData json.RawMessage) error
 	GetLeaguePortfolioInfo(conn *ws.Connection, rawData json.RawMessage) error
+	SetDraftPool(conn *ws.Connection, rawData json.RawMessage) error
 }
 
 // Compile-time check
@@ -105,3 +107,49 @@ func (h *LeaguePortfolioHandler) GetLeaguePortfolioInfo(conn *ws.Connection, raw
 
 	return nil
 }
+
+// SetDraftPool handles the league owner changing which stocks can be drafted before the draft starts
+func (h *LeaguePortfolioHandler) SetDraftPool(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		LeagueID  uint                  `json:"league_id" binding:"required"`
+		UserID    uint                  `json:"user_id"`    // Optional: must be the signed-in user
+		DraftPool models.StockSelection `json:"draft_pool"` // Empty for every stock still trading
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_LeaguePortfolio_SetDraftPool, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_LeaguePortfolio_SetDraftPool, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	leaguePortfolio, err := h.leaguePortfolioService.SetDraftPool(request.LeagueID, userID, request.DraftPool)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_LeaguePortfolio_SetDraftPool, err.Error())
+		return fmt.Errorf("failed to set draft pool: %v", err)
+	}
+
+	// Step 4: Marshal the league portfolio into JSON
+	leaguePortfolioJSON, err := json.Marshal(leaguePortfolio)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_LeaguePortfolio_SetDraftPool, "Failed to serialize league portfolio")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_LeaguePortfolio_SetDraftPool,
+		Data: json.RawMessage(leaguePortfolioJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
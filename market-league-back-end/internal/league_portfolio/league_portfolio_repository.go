@@ -25,7 +25,7 @@ func (r *LeaguePortfolioRepository) CreateLeaguePortfolio(portfolio *models.Leag
 // GetLeagueDetails retrieves details for a specific league by ID.
 func (r *LeaguePortfolioRepository) GetLeagueDetails(leagueID uint) (*models.League, error) {
 	var league models.League
-	err := r.db.Where("id = ?", leagueID).First(&league).Error
+	err := r.db.Preload("RosterPositions").Where("id = ?", leagueID).First(&league).Error
 	return &league, err
 }
 
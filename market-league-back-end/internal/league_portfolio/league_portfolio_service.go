@@ -3,13 +3,15 @@ package leagueportfolio
 import (
 	"fmt"
 	"log"
-	"time"
 
 	"github.com/market-league/internal/draft"
+	"github.com/market-league/internal/lineup"
 	"github.com/market-league/internal/models"
 	ownership_history "github.com/market-league/internal/ownership_history"
 	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/roster"
 	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/user"
 	"github.com/market-league/internal/utils"
 )
 
@@ -18,7 +20,9 @@ type LeaguePortfolioService struct {
 	stockRepo               *stock.StockRepository
 	portfolioRepo           *portfolio.PortfolioRepository
 	ownershipHistoryService ownership_history.OwnershipHistoryServiceInterface
+	lineupService           lineup.LineupServiceInterface
 	draftProvider           draft.DraftChannelProvider
+	userRepo                *user.UserRepository
 }
 
 func NewLeaguePortfolioService(
@@ -26,29 +30,41 @@ func NewLeaguePortfolioService(
 	stockRepo *stock.StockRepository,
 	portfolioRepo *portfolio.PortfolioRepository,
 	ownershipHistoryService ownership_history.OwnershipHistoryServiceInterface,
+	lineupService lineup.LineupServiceInterface,
 	draftProvider draft.DraftChannelProvider,
+	userRepo *user.UserRepository,
 ) *LeaguePortfolioService {
 	return &LeaguePortfolioService{
 		repo:                    leaguePortfolioRepo,
 		stockRepo:               stockRepo,
 		portfolioRepo:           portfolioRepo,
 		ownershipHistoryService: ownershipHistoryService,
+		lineupService:           lineupService,
 		draftProvider:           draftProvider,
+		userRepo:                userRepo,
 	}
 }
 
-func (s *LeaguePortfolioService) CreateLeaguePortfolio(leagueID uint) (*models.LeaguePortfolio, error) {
+// CreateLeaguePortfolio creates the draft pool of a new league from a selection of stocks
+func (s *LeaguePortfolioService) CreateLeaguePortfolio(leagueID uint, selection models.StockSelection) (*models.LeaguePortfolio, error) {
 	// Fetch league details
 	league, err := s.repo.GetLeagueDetails(leagueID)
 	if err != nil {
 		return nil, err
 	}
 
+	// Pick the stocks of the pool
+	initialStocks, err := s.SelectDraftPool(league, selection)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialize League Portfolio
 	leaguePortfolio := &models.LeaguePortfolio{
-		LeagueID:  league.ID,
-		Name:      "Remaining League Stocks",
-		CreatedAt: time.Now(),
+		LeagueID:      league.ID,
+		Name:          "Remaining League Stocks",
+		CreatedAt:     utils.Now(),
+		PoolSelection: selection,
 	}
 
 	// Create the League Portfolio
@@ -57,18 +73,73 @@ func (s *LeaguePortfolioService) CreateLeaguePortfolio(leagueID uint) (*models.L
 		return nil, err
 	}
 
-	// Initialize stock pool (example: add initial stocks)
-	initialStocks, err := s.stockRepo.GetAllStocks()
+	// Assign stocks to the League Portfolio
+	if err := s.repo.AddStocksToLeaguePortfolio(createdLeaguePortfolio.ID, initialStocks); err != nil {
+		return nil, err
+	}
+
+	return createdLeaguePortfolio, nil
+}
+
+// SetDraftPool replaces the draft pool of a league with a new selection of stocks.
+// Only the league owner or an admin may change it, and only until the draft starts.
+func (s *LeaguePortfolioService) SetDraftPool(leagueID, userID uint, selection models.StockSelection) (*models.LeaguePortfolio, error) {
+	league, err := s.repo.GetLeagueDetails(leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch league %d: %w", leagueID, err)
+	}
+	if league.LeagueState != models.PreDraft {
+		return nil, fmt.Errorf("the draft pool is locked once the draft starts")
+	}
+	if league.OwnerID != userID {
+		isAdmin, err := s.userRepo.IsAdmin(userID)
+		if err != nil {
+			return nil, err
+		}
+		if !isAdmin {
+			return nil, fmt.Errorf("only the league owner can change the draft pool")
+		}
+	}
+
+	stocks, err := s.SelectDraftPool(league, selection)
 	if err != nil {
 		return nil, err
 	}
 
-	// Assign stocks to the League Portfolio
-	if err := s.repo.AddStocksToLeaguePortfolio(createdLeaguePortfolio.ID, initialStocks); err != nil {
+	leaguePortfolioID, err := s.repo.GetLeaguePortfolioIDByLeagueID(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	leaguePortfolio, err := s.repo.GetLeaguePortfolioWithID(leaguePortfolioID)
+	if err != nil {
+		return nil, err
+	}
+	leaguePortfolio.PoolSelection = selection
+	leaguePortfolio.Stocks = stocks
+	if err := s.repo.UpdateLeaguePortfolio(leaguePortfolio); err != nil {
 		return nil, err
 	}
 
-	return createdLeaguePortfolio, nil
+	return s.repo.GetLeaguePortfolioWithID(leaguePortfolioID)
+}
+
+// SelectDraftPool picks the stocks of a selection and checks they can fill at least one roster of the league
+func (s *LeaguePortfolioService) SelectDraftPool(league *models.League, selection models.StockSelection) ([]models.Stock, error) {
+	if selection.MinMarketCap != nil && selection.MaxMarketCap != nil && *selection.MinMarketCap > *selection.MaxMarketCap {
+		return nil, fmt.Errorf("the minimum market cap is above the maximum")
+	}
+
+	stocks, err := s.stockRepo.SelectStocks(selection)
+	if err != nil {
+		return nil, err
+	}
+	if len(stocks) < league.RosterSize() {
+		return nil, fmt.Errorf("the draft pool has %d stocks but a roster holds %d", len(stocks), league.RosterSize())
+	}
+	if missing := roster.MissingPositions(league.RosterPositions, stocks); len(missing) > 0 {
+		return nil, fmt.Errorf("the draft pool cannot fill the %s roster position", missing[0].Sector)
+	}
+	return stocks, nil
 }
 
 func (s *LeaguePortfolioService) DraftStock(leagueID, userID, stockID uint) error {
@@ -109,6 +180,11 @@ func (s *LeaguePortfolioService) DraftStock(leagueID, userID, stockID uint) erro
 		return fmt.Errorf("stock not found in league portfolio")
 	}
 
+	// Reject picks that would leave the roster unable to fill its sector positions
+	if err := roster.ValidateRoster(&userPortfolio.League, roster.ApplyMove(userPortfolio.Stocks, nil, []models.Stock{*stockToDraft})); err != nil {
+		return err
+	}
+
 	// Remove the stock from the league portfolio
 	var updatedStocks []models.Stock
 	for _, stock := range leaguePortfolio.Stocks {
@@ -145,11 +221,16 @@ func (s *LeaguePortfolioService) DraftStock(leagueID, userID, stockID uint) erro
 		return fmt.Errorf("unable to access first stock: %v", err)
 	}
 	startingValue := stock.CurrentPrice
-	startDate := time.Now()
+	startDate := utils.Now()
 	if err := s.ownershipHistoryService.CreateOwnershipHistory(portfolioID, stockID, startingValue, startDate); err != nil {
 		return fmt.Errorf("failed to update user portfolio: %v", err)
 	}
 
+	// Fill the starting lineup first, later picks go to the bench
+	if err := s.lineupService.AssignStock(portfolioID, stockID, startingValue, startDate); err != nil {
+		return fmt.Errorf("failed to assign stock to lineup: %v", err)
+	}
+
 	log.Printf("Draft selection successful: League=%d, User=%d, Stock=%d", leagueID, userID, stockID)
 	return nil
 }
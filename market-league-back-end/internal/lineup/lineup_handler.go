@@ -0,0 +1,119 @@
+package lineup
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ws "github.com/market-league/api/websocket"
+)
+
+// LineupHandler Interface
+type LineupHandlerInterface interface {
+	GetLineup(conn *ws.Connection, rawData json.RawMessage) error
+	SwapStocks(conn *ws.Connection, rawData json.RawMessage) error
+}
+
+// Compile-time check
+var _ LineupHandlerInterface = (*LineupHandler)(nil)
+
+// LineupHandler defines the handler for lineup-related operations.
+type LineupHandler struct {
+	service LineupServiceInterface
+}
+
+// NewLineupHandler creates a new instance of LineupHandler.
+func NewLineupHandler(service LineupServiceInterface) *LineupHandler {
+	return &LineupHandler{service: service}
+}
+
+// * Implementation of Interface
+
+// GetLineup handles fetching the starters and bench of a portfolio.
+func (h *LineupHandler) GetLineup(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		PortfolioID uint `json:"portfolio_id" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_GetLineup, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(0)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_GetLineup, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	lineup, err := h.service.GetLineup(request.PortfolioID, userID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_GetLineup, err.Error())
+		return fmt.Errorf("failed to retrieve lineup: %v", err)
+	}
+
+	// Step 4: Marshal the lineup into JSON
+	lineupJSON, err := json.Marshal(lineup)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_GetLineup, "Failed to serialize lineup")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Lineup_GetLineup,
+		Data: json.RawMessage(lineupJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// SwapStocks handles moving a starter to the bench and a bench stock into the starting lineup.
+func (h *LineupHandler) SwapStocks(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		PortfolioID    uint `json:"portfolio_id" binding:"required"`
+		StarterStockID uint `json:"starter_stock_id"` // Optional: starter to bench
+		BenchStockID   uint `json:"bench_stock_id"`   // Optional: bench stock to start
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_SwapStocks, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	userID, err := conn.User(0)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_SwapStocks, err.Error())
+		return err
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	lineup, err := h.service.SwapStocks(request.PortfolioID, userID, request.StarterStockID, request.BenchStockID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_SwapStocks, err.Error())
+		return fmt.Errorf("failed to swap lineup stocks: %v", err)
+	}
+
+	// Step 4: Marshal the updated lineup into JSON
+	lineupJSON, err := json.Marshal(lineup)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Lineup_SwapStocks, "Failed to serialize lineup")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Lineup_SwapStocks,
+		Data: json.RawMessage(lineupJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,123 @@
+package lineup
+
+import (
+	"fmt"
+
+	"github.com/market-league/internal/models"
+	"gorm.io/gorm"
+)
+
+// LineupRepositoryInterface defines the interface for scoring window database operations
+type LineupRepositoryInterface interface {
+	Create(window *models.ScoringWindow) error
+	Update(window *models.ScoringWindow) error
+	FindActiveByStockIDAndPortfolioID(stockID uint, portfolioID uint) (*models.ScoringWindow, error)
+	GetAllWindowsByStockIDAndPortfolioID(stockID uint, portfolioID uint) ([]models.ScoringWindow, error)
+	GetActiveWindowsForPortfolio(portfolioID uint) ([]models.ScoringWindow, error)
+	GetActiveWindows() ([]*models.ScoringWindow, error)
+	GetPortfolioWithStocks(portfolioID uint) (*models.Portfolio, error)
+	BackfillFromOwnershipHistory() (int64, error)
+}
+
+// lineupRepository implements LineupRepositoryInterface
+type lineupRepository struct {
+	db *gorm.DB
+}
+
+// NewLineupRepository creates a new repository
+func NewLineupRepository(db *gorm.DB) LineupRepositoryInterface {
+	return &lineupRepository{db: db}
+}
+
+// Create adds a new ScoringWindow record to the database
+func (r *lineupRepository) Create(window *models.ScoringWindow) error {
+	return r.db.Create(window).Error
+}
+
+// Update modifies an existing ScoringWindow record in the database
+func (r *lineupRepository) Update(window *models.ScoringWindow) error {
+	return r.db.Save(window).Error
+}
+
+// FindActiveByStockIDAndPortfolioID fetches the open scoring window of a starting stock
+func (r *lineupRepository) FindActiveByStockIDAndPortfolioID(stockID uint, portfolioID uint) (*models.ScoringWindow, error) {
+	var window models.ScoringWindow
+	err := r.db.
+		Where("stock_id = ? AND portfolio_id = ? AND end_date IS NULL", stockID, portfolioID).
+		First(&window).Error
+	if err != nil {
+		return nil, err
+	}
+	return &window, nil
+}
+
+// GetAllWindowsByStockIDAndPortfolioID gets every scoring window of a single stock so that we can calculate portfolio points
+func (r *lineupRepository) GetAllWindowsByStockIDAndPortfolioID(stockID uint, portfolioID uint) ([]models.ScoringWindow, error) {
+	var windows []models.ScoringWindow
+	err := r.db.Where("stock_id = ? AND portfolio_id = ?", stockID, portfolioID).Find(&windows).Error
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve scoring windows with stockID %d and portfolioID %d: %v", stockID, portfolioID, err)
+	}
+	return windows, nil
+}
+
+// GetActiveWindowsForPortfolio gets the open scoring windows of a portfolio, one per starter
+func (r *lineupRepository) GetActiveWindowsForPortfolio(portfolioID uint) ([]models.ScoringWindow, error) {
+	var windows []models.ScoringWindow
+	err := r.db.Where("portfolio_id = ? AND end_date IS NULL", portfolioID).Find(&windows).Error
+	if err != nil {
+		return nil, fmt.Errorf("unable to retrieve active scoring windows for portfolio %d: %v", portfolioID, err)
+	}
+	return windows, nil
+}
+
+// GetActiveWindows gets all the currently open scoring windows
+func (r *lineupRepository) GetActiveWindows() ([]*models.ScoringWindow, error) {
+	var windows []*models.ScoringWindow
+	err := r.db.Where("end_date IS NULL").Find(&windows).Error
+	if err != nil {
+		return nil, err
+	}
+	return windows, nil
+}
+
+// GetPortfolioWithStocks fetches a portfolio along with its league and stocks
+func (r *lineupRepository) GetPortfolioWithStocks(portfolioID uint) (*models.Portfolio, error) {
+	var portfolio models.Portfolio
+	err := r.db.
+		Preload("League").
+		Preload("Stocks").
+		First(&portfolio, portfolioID).Error
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("portfolio with ID %d not found", portfolioID)
+		}
+		return nil, fmt.Errorf("failed to fetch portfolio: %w", err)
+	}
+	return &portfolio, nil
+}
+
+// BackfillFromOwnershipHistory copies ownership history into scoring windows for portfolios
+// drafted before lineups existed, when every owned stock was a starter. Only the first stocks to enter a
+// portfolio, up to its league's starting slots, keep starting; the rest start on the bench.
+func (r *lineupRepository) BackfillFromOwnershipHistory() (int64, error) {
+	result := r.db.Exec(`
+		INSERT INTO scoring_windows (portfolio_id, stock_id, starting_value, current_value, start_date, end_date)
+		SELECT portfolio_id, stock_id, starting_value, current_value, start_date, end_date
+		FROM (
+			SELECT ownership_histories.*, leagues.starting_slots,
+				ROW_NUMBER() OVER (
+					PARTITION BY ownership_histories.portfolio_id, ownership_histories.end_date IS NULL
+					ORDER BY ownership_histories.start_date, ownership_histories.id
+				) AS slot
+			FROM ownership_histories
+			JOIN portfolios ON portfolios.id = ownership_histories.portfolio_id
+			JOIN leagues ON leagues.id = portfolios.league_id
+			WHERE ownership_histories.portfolio_id NOT IN (SELECT DISTINCT portfolio_id FROM scoring_windows)
+		) owned
+		WHERE end_date IS NOT NULL OR slot <= starting_slots`)
+	if result.Error != nil {
+		return 0, fmt.Errorf("unable to backfill scoring windows: %v", result.Error)
+	}
+	return result.RowsAffected, nil
+}
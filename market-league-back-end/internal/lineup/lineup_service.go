@@ -0,0 +1,302 @@
+package lineup
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"gorm.io/gorm"
+)
+
+// Lineups lock every weekday at the open and stay locked through the scheduler's price run after the close.
+const (
+	lineupLockHour   = utils.MarketOpenHour
+	lineupLockMinute = utils.MarketOpenMinute
+)
+
+// LineupServiceInterface defines the interface for lineup business logic
+type LineupServiceInterface interface {
+	AssignStock(portfolioID uint, stockID uint, price float64, at time.Time) error
+	ReleaseStock(portfolioID uint, stockID uint, price float64, at time.Time) error
+	SwapStocks(portfolioID uint, userID uint, starterStockID uint, benchStockID uint) (*Lineup, error)
+	GetLineup(portfolioID uint, userID uint) (*Lineup, error)
+	UpdateActiveScoringWindowPrices() error
+	BackfillScoringWindows() error
+}
+
+// Lineup is the starter/bench split of a portfolio's stocks
+type Lineup struct {
+	PortfolioID   uint           `json:"portfolio_id"`
+	StartingSlots int            `json:"starting_slots"`
+	BenchSlots    int            `json:"bench_slots"`
+	Starters      []models.Stock `json:"starters"`
+	Bench         []models.Stock `json:"bench"`
+	Locked        bool           `json:"locked"`
+	LocksAt       time.Time      `json:"locks_at"`
+	Final         bool           `json:"final"`          // The league has completed, the lineup no longer changes
+	RostersLocked bool           `json:"rosters_locked"` // The league locked its rosters, the lineup no longer changes
+}
+
+// lineupService implements LineupServiceInterface
+type lineupService struct {
+	repo      LineupRepositoryInterface
+	stockRepo *stock.StockRepository
+}
+
+// NewLineupService creates a new service
+func NewLineupService(
+	repo LineupRepositoryInterface,
+	stockRepo *stock.StockRepository,
+) LineupServiceInterface {
+	return &lineupService{
+		repo:      repo,
+		stockRepo: stockRepo,
+	}
+}
+
+// AssignStock places a stock that just entered a portfolio into the lineup.
+// It starts if there is an open starting slot and is benched otherwise.
+func (s *lineupService) AssignStock(portfolioID uint, stockID uint, price float64, at time.Time) error {
+	portfolio, err := s.repo.GetPortfolioWithStocks(portfolioID)
+	if err != nil {
+		return err
+	}
+
+	activeWindows, err := s.repo.GetActiveWindowsForPortfolio(portfolioID)
+	if err != nil {
+		return err
+	}
+
+	for _, window := range activeWindows {
+		if window.StockID == stockID {
+			return nil // Already starting
+		}
+	}
+
+	if len(activeWindows) >= portfolio.League.StartingSlots {
+		return nil // Lineup is full, the stock sits on the bench
+	}
+
+	return s.openWindow(portfolioID, stockID, price, at)
+}
+
+// ReleaseStock closes the scoring window of a stock that is leaving a portfolio, if it was starting.
+func (s *lineupService) ReleaseStock(portfolioID uint, stockID uint, price float64, at time.Time) error {
+	window, err := s.repo.FindActiveByStockIDAndPortfolioID(stockID, portfolioID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // Stock was on the bench
+		}
+		return fmt.Errorf("unable to retrieve scoring window: %v", err)
+	}
+	return s.closeWindow(window, price, at)
+}
+
+// SwapStocks moves a starter to the bench and a bench stock into the lineup of the user's portfolio.
+// Either ID may be zero to only bench a starter or only fill an open starting slot.
+func (s *lineupService) SwapStocks(portfolioID uint, userID uint, starterStockID uint, benchStockID uint) (*Lineup, error) {
+	if starterStockID == 0 && benchStockID == 0 {
+		return nil, errors.New("a starter or bench stock is required")
+	}
+
+	locked, _, err := LineupLockStatus(utils.Now())
+	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, errors.New("lineups are locked for today")
+	}
+
+	lineup, err := s.GetLineup(portfolioID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if lineup.Final {
+		return nil, errors.New("the league has completed")
+	}
+	if lineup.RostersLocked {
+		return nil, errors.New("rosters are locked")
+	}
+
+	if starterStockID != 0 && !containsStock(lineup.Starters, starterStockID) {
+		return nil, fmt.Errorf("stock with ID %d is not in the starting lineup", starterStockID)
+	}
+	if benchStockID != 0 && !containsStock(lineup.Bench, benchStockID) {
+		return nil, fmt.Errorf("stock with ID %d is not on the bench", benchStockID)
+	}
+	if starterStockID == 0 && len(lineup.Starters) >= lineup.StartingSlots {
+		return nil, errors.New("starting lineup is full")
+	}
+
+	now := utils.Now()
+	if starterStockID != 0 {
+		window, err := s.repo.FindActiveByStockIDAndPortfolioID(starterStockID, portfolioID)
+		if err != nil {
+			return nil, fmt.Errorf("unable to retrieve scoring window: %v", err)
+		}
+		price, err := s.currentPrice(starterStockID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.closeWindow(window, price, now); err != nil {
+			return nil, err
+		}
+	}
+	if benchStockID != 0 {
+		price, err := s.currentPrice(benchStockID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.openWindow(portfolioID, benchStockID, price, now); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.GetLineup(portfolioID, userID)
+}
+
+// GetLineup splits the stocks of the user's portfolio into starters and bench
+func (s *lineupService) GetLineup(portfolioID uint, userID uint) (*Lineup, error) {
+	portfolio, err := s.repo.GetPortfolioWithStocks(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	if portfolio.UserID != userID {
+		return nil, fmt.Errorf("portfolio %d does not belong to user %d", portfolioID, userID)
+	}
+
+	activeWindows, err := s.repo.GetActiveWindowsForPortfolio(portfolioID)
+	if err != nil {
+		return nil, err
+	}
+	starting := make(map[uint]bool, len(activeWindows))
+	for _, window := range activeWindows {
+		starting[window.StockID] = true
+	}
+
+	locked, locksAt, err := LineupLockStatus(utils.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	lineup := &Lineup{
+		PortfolioID:   portfolio.ID,
+		StartingSlots: portfolio.League.StartingSlots,
+		BenchSlots:    portfolio.League.BenchSlots,
+		Starters:      []models.Stock{},
+		Bench:         []models.Stock{},
+		Locked:        locked,
+		LocksAt:       locksAt,
+		Final:         portfolio.League.LeagueState == models.Completed,
+		RostersLocked: portfolio.League.RostersLockedAt != nil,
+	}
+	for _, stock := range portfolio.Stocks {
+		if starting[stock.ID] {
+			lineup.Starters = append(lineup.Starters, stock)
+		} else {
+			lineup.Bench = append(lineup.Bench, stock)
+		}
+	}
+
+	return lineup, nil
+}
+
+// UpdateActiveScoringWindowPrices refreshes the current value of every open scoring window
+func (s *lineupService) UpdateActiveScoringWindowPrices() error {
+	windows, err := s.repo.GetActiveWindows()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve active scoring windows: %v", err)
+	}
+
+	for i := range windows {
+		price, err := s.currentPrice(windows[i].StockID)
+		if err != nil {
+			return err
+		}
+
+		windows[i].CurrentValue = price
+		if err := s.repo.Update(windows[i]); err != nil {
+			return fmt.Errorf("unable to update scoring window ID %d: %v", windows[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// BackfillScoringWindows gives portfolios drafted before lineups existed a scoring window for every stock they owned
+func (s *lineupService) BackfillScoringWindows() error {
+	count, err := s.repo.BackfillFromOwnershipHistory()
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		log.Printf("Backfilled %d scoring windows from ownership history", count)
+	}
+	return nil
+}
+
+// LineupLockStatus reports whether lineups are locked at the given instant and when the current day's lock begins.
+// Lineups are locked from the lock time until the end of the trading day, and never on weekends or market holidays.
+func LineupLockStatus(now time.Time) (bool, time.Time, error) {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return false, time.Time{}, fmt.Errorf("error loading time location: %v", err)
+	}
+
+	now = now.In(location)
+	locksAt := time.Date(now.Year(), now.Month(), now.Day(), lineupLockHour, lineupLockMinute, 0, 0, location)
+
+	if !utils.IsTradingDay(now) {
+		return false, locksAt, nil
+	}
+	return !now.Before(locksAt), locksAt, nil
+}
+
+// * Helper Functions
+
+func (s *lineupService) openWindow(portfolioID uint, stockID uint, price float64, at time.Time) error {
+	window := &models.ScoringWindow{
+		PortfolioID:   portfolioID,
+		StockID:       stockID,
+		StartingValue: price,
+		CurrentValue:  price, // Initial current value is the same as starting value
+		StartDate:     at,
+	}
+	if err := s.repo.Create(window); err != nil {
+		return fmt.Errorf("unable to open scoring window: %v", err)
+	}
+	return nil
+}
+
+func (s *lineupService) closeWindow(window *models.ScoringWindow, price float64, at time.Time) error {
+	window.CurrentValue = price
+	window.EndDate = &at
+	if err := s.repo.Update(window); err != nil {
+		return fmt.Errorf("unable to close scoring window ID %d: %v", window.ID, err)
+	}
+	return nil
+}
+
+func (s *lineupService) currentPrice(stockID uint) (float64, error) {
+	stocks, err := s.stockRepo.GetStocksByIDs([]uint{stockID})
+	if err != nil {
+		return 0, fmt.Errorf("unable to fetch current price for stock ID %d: %v", stockID, err)
+	}
+	stock, err := utils.FirstStock(stocks)
+	if err != nil {
+		return 0, fmt.Errorf("unable to get first stock: %v", err)
+	}
+	return stock.CurrentPrice, nil
+}
+
+func containsStock(stocks []models.Stock, stockID uint) bool {
+	for _, stock := range stocks {
+		if stock.ID == stockID {
+			return true
+		}
+	}
+	return false
+}
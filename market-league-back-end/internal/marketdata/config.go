@@ -0,0 +1,99 @@
+package marketdata
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/utils"
+)
+
+// Provider names accepted by MARKET_DATA_PROVIDER
+const (
+	ProviderFinnhub = "finnhub"
+	ProviderReplay  = "replay"
+)
+
+// Config selects and configures a MarketDataProvider
+type Config struct {
+	Provider      string
+	FinnhubAPIKey string
+	ReplayPath    string  // File or directory of recorded bars
+	ReplayStart   string  // RFC 3339 or YYYY-MM-DD, defaults to the first bar
+	ReplaySpeed   float64 // Simulated seconds per real second, defaults to 1
+}
+
+// ConfigFromEnv reads the provider configuration from the environment, defaulting to Finnhub
+func ConfigFromEnv() Config {
+	provider := strings.ToLower(strings.TrimSpace(os.Getenv("MARKET_DATA_PROVIDER")))
+	if provider == "" {
+		provider = ProviderFinnhub
+	}
+	speed, err := strconv.ParseFloat(os.Getenv("MARKET_DATA_REPLAY_SPEED"), 64)
+	if err != nil {
+		speed = 1
+	}
+	return Config{
+		Provider:      provider,
+		FinnhubAPIKey: os.Getenv("FINNHUB_API_KEY"),
+		ReplayPath:    os.Getenv("MARKET_DATA_REPLAY_PATH"),
+		ReplayStart:   os.Getenv("MARKET_DATA_REPLAY_START"),
+		ReplaySpeed:   speed,
+	}
+}
+
+// NewProvider creates the provider named in the configuration
+func NewProvider(cfg Config) (MarketDataProvider, error) {
+	switch cfg.Provider {
+	case ProviderFinnhub:
+		if cfg.FinnhubAPIKey == "" {
+			return nil, fmt.Errorf("FINNHUB_API_KEY is required for the %s provider", ProviderFinnhub)
+		}
+		return NewFinnhubProvider(cfg.FinnhubAPIKey), nil
+	case ProviderReplay:
+		return newReplayProviderFromConfig(cfg)
+	default:
+		return nil, fmt.Errorf("unknown market data provider %q", cfg.Provider)
+	}
+}
+
+// ProviderClock is the clock a provider runs on, if it is not the wall clock
+func ProviderClock(provider MarketDataProvider) (utils.Clock, bool) {
+	replay, ok := provider.(*ReplayProvider)
+	if !ok {
+		return nil, false
+	}
+	return replay.Clock(), true
+}
+
+func newReplayProviderFromConfig(cfg Config) (*ReplayProvider, error) {
+	if cfg.ReplayPath == "" {
+		return nil, fmt.Errorf("MARKET_DATA_REPLAY_PATH is required for the %s provider", ProviderReplay)
+	}
+	bars, err := LoadReplayBars(cfg.ReplayPath)
+	if err != nil {
+		return nil, err
+	}
+	provider := NewReplayProvider(bars, nil)
+
+	var start time.Time
+	if cfg.ReplayStart == "" {
+		// Start at midnight before the first bar so that day's scheduler run sees it
+		location, err := utils.MarketLocation()
+		if err != nil {
+			return nil, err
+		}
+		first, _ := provider.FirstTimestamp()
+		first = first.In(location)
+		start = time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, location)
+	} else if start, err = time.Parse(time.RFC3339, cfg.ReplayStart); err != nil {
+		if start, err = parseMarketDate(cfg.ReplayStart); err != nil {
+			return nil, fmt.Errorf("invalid replay start %q, expected RFC 3339 or %s", cfg.ReplayStart, DateFormat)
+		}
+	}
+
+	provider.clock = utils.NewSimulatedClock(start, cfg.ReplaySpeed)
+	return provider, nil
+}
@@ -0,0 +1,225 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	finnhub "github.com/Finnhub-Stock-API/finnhub-go/v2"
+	"github.com/market-league/internal/utils"
+)
+
+// Compile-time check
+var _ MarketDataProvider = (*FinnhubProvider)(nil)
+
+// finnhubSectors maps Finnhub's industry classification onto the GICS sectors leagues draft by
+var finnhubSectors = map[string]string{
+	"Aerospace & Defense":              "Industrials",
+	"Airlines":                         "Industrials",
+	"Auto Components":                  "Consumer Discretionary",
+	"Automobiles":                      "Consumer Discretionary",
+	"Banking":                          "Financials",
+	"Beverages":                        "Consumer Staples",
+	"Biotechnology":                    "Health Care",
+	"Building":                         "Industrials",
+	"Chemicals":                        "Materials",
+	"Commercial Services & Supplies":   "Industrials",
+	"Communications":                   "Communication Services",
+	"Construction":                     "Industrials",
+	"Consumer products":                "Consumer Staples",
+	"Distributors":                     "Consumer Discretionary",
+	"Diversified Consumer Services":    "Consumer Discretionary",
+	"Electrical Equipment":             "Industrials",
+	"Energy":                           "Energy",
+	"Financial Services":               "Financials",
+	"Food Products":                    "Consumer Staples",
+	"Health Care":                      "Health Care",
+	"Hotels, Restaurants & Leisure":    "Consumer Discretionary",
+	"Industrial Conglomerates":         "Industrials",
+	"Insurance":                        "Financials",
+	"Leisure Products":                 "Consumer Discretionary",
+	"Life Sciences Tools & Services":   "Health Care",
+	"Logistics & Transportation":       "Industrials",
+	"Machinery":                        "Industrials",
+	"Marine":                           "Industrials",
+	"Media":                            "Communication Services",
+	"Metals & Mining":                  "Materials",
+	"Packaging":                        "Materials",
+	"Paper & Forest":                   "Materials",
+	"Pharmaceuticals":                  "Health Care",
+	"Professional Services":            "Industrials",
+	"Real Estate":                      "Real Estate",
+	"Retail":                           "Consumer Discretionary",
+	"Road & Rail":                      "Industrials",
+	"Semiconductors":                   "Information Technology",
+	"Technology":                       "Information Technology",
+	"Telecommunication":                "Communication Services",
+	"Textiles, Apparel & Luxury Goods": "Consumer Discretionary",
+	"Tobacco":                          "Consumer Staples",
+	"Trading Companies & Distributors": "Industrials",
+	"Transportation Infrastructure":    "Industrials",
+	"Utilities":                        "Utilities",
+}
+
+// FinnhubProvider fetches market data from the Finnhub API
+type FinnhubProvider struct {
+	client *finnhub.DefaultApiService
+}
+
+// NewFinnhubProvider creates a provider authenticated with the given API key
+func NewFinnhubProvider(apiKey string) *FinnhubProvider {
+	cfg := finnhub.NewConfiguration()
+	cfg.AddDefaultHeader("X-Finnhub-Token", apiKey)
+	return &FinnhubProvider{client: finnhub.NewAPIClient(cfg).DefaultApi}
+}
+
+// * Implementation of Interface
+
+func (p *FinnhubProvider) Name() string {
+	return "finnhub"
+}
+
+// GetQuote fetches the latest price of a symbol
+func (p *FinnhubProvider) GetQuote(ctx context.Context, symbol string) (*Quote, error) {
+	quote, _, err := p.client.Quote(ctx).Symbol(symbol).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching quote for %s: %w", symbol, err)
+	}
+
+	// Finnhub answers unknown symbols with an all-zero quote
+	if quote.C == nil || *quote.C == 0 {
+		return nil, fmt.Errorf("quote for %s: %w", symbol, ErrNoData)
+	}
+
+	return &Quote{
+		Symbol:        symbol,
+		Current:       float64(*quote.C),
+		Open:          float64(quote.GetO()),
+		High:          float64(quote.GetH()),
+		Low:           float64(quote.GetL()),
+		PreviousClose: float64(quote.GetPc()),
+		Timestamp:     time.Now(),
+	}, nil
+}
+
+// GetCandles fetches the candles of a symbol between from and to
+func (p *FinnhubProvider) GetCandles(ctx context.Context, symbol string, resolution Resolution, from time.Time, to time.Time) ([]Candle, error) {
+	candles, _, err := p.client.StockCandles(ctx).
+		Symbol(symbol).
+		Resolution(string(resolution)).
+		From(from.Unix()).
+		To(to.Unix()).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching candles for %s: %w", symbol, err)
+	}
+	if candles.GetS() != "ok" {
+		return nil, fmt.Errorf("candles for %s: %w", symbol, ErrNoData)
+	}
+
+	timestamps := candles.GetT()
+	opens, highs, lows, closes, volumes := candles.GetO(), candles.GetH(), candles.GetL(), candles.GetC(), candles.GetV()
+	result := make([]Candle, 0, len(timestamps))
+	for i := range timestamps {
+		if i >= len(opens) || i >= len(highs) || i >= len(lows) || i >= len(closes) {
+			return nil, fmt.Errorf("candles for %s are missing values", symbol)
+		}
+		candle := Candle{
+			Timestamp: time.Unix(timestamps[i], 0),
+			Open:      float64(opens[i]),
+			High:      float64(highs[i]),
+			Low:       float64(lows[i]),
+			Close:     float64(closes[i]),
+		}
+		if i < len(volumes) {
+			candle.Volume = float64(volumes[i])
+		}
+		result = append(result, candle)
+	}
+	return result, nil
+}
+
+// GetCompanyProfile fetches the profile of the company behind a symbol
+func (p *FinnhubProvider) GetCompanyProfile(ctx context.Context, symbol string) (*CompanyProfile, error) {
+	profile, _, err := p.client.CompanyProfile2(ctx).Symbol(symbol).Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching company profile for %s: %w", symbol, err)
+	}
+
+	// Finnhub answers unknown symbols with an empty profile
+	if profile.GetTicker() == "" && profile.GetName() == "" {
+		return nil, fmt.Errorf("company profile for %s: %w", symbol, ErrNoData)
+	}
+
+	return &CompanyProfile{
+		Symbol:    symbol,
+		Name:      profile.GetName(),
+		Exchange:  profile.GetExchange(),
+		Sector:    finnhubSectors[profile.GetFinnhubIndustry()],
+		Industry:  profile.GetFinnhubIndustry(),
+		Country:   profile.GetCountry(),
+		Currency:  profile.GetCurrency(),
+		MarketCap: float64(profile.GetMarketCapitalization()),
+		Logo:      profile.GetLogo(),
+		WebURL:    profile.GetWeburl(),
+	}, nil
+}
+
+// GetDividends fetches the dividends of a symbol with an ex-date between from and to
+func (p *FinnhubProvider) GetDividends(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Dividend, error) {
+	dividends, _, err := p.client.StockDividends(ctx).
+		Symbol(symbol).
+		From(from.Format(DateFormat)).
+		To(to.Format(DateFormat)).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching dividends for %s: %w", symbol, err)
+	}
+
+	result := make([]Dividend, 0, len(dividends))
+	for _, dividend := range dividends {
+		if dividend.Date == nil || dividend.Amount == nil {
+			continue
+		}
+		exDate, err := parseMarketDate(*dividend.Date)
+		if err != nil {
+			return nil, fmt.Errorf("dividend for %s has invalid date %q", symbol, *dividend.Date)
+		}
+		result = append(result, Dividend{ExDate: exDate, Amount: float64(*dividend.Amount)})
+	}
+	return result, nil
+}
+
+// GetSplits fetches the splits of a symbol between from and to
+func (p *FinnhubProvider) GetSplits(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Split, error) {
+	splits, _, err := p.client.StockSplits(ctx).
+		Symbol(symbol).
+		From(from.Format(DateFormat)).
+		To(to.Format(DateFormat)).
+		Execute()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching splits for %s: %w", symbol, err)
+	}
+
+	result := make([]Split, 0, len(splits))
+	for _, split := range splits {
+		if split.Date == nil || split.FromFactor == nil || split.ToFactor == nil || *split.FromFactor == 0 {
+			continue
+		}
+		exDate, err := parseMarketDate(*split.Date)
+		if err != nil {
+			return nil, fmt.Errorf("split for %s has invalid date %q", symbol, *split.Date)
+		}
+		result = append(result, Split{ExDate: exDate, Ratio: float64(*split.ToFactor) / float64(*split.FromFactor)})
+	}
+	return result, nil
+}
+
+// parseMarketDate reads a YYYY-MM-DD date as midnight in the market timezone
+func parseMarketDate(value string) (time.Time, error) {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.ParseInLocation(DateFormat, value, location)
+}
@@ -0,0 +1,83 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// DateFormat is the YYYY-MM-DD layout used for market dates
+const DateFormat = "2006-01-02"
+
+// ErrNoData is returned when a provider has nothing for a symbol, such as an unknown or delisted ticker
+var ErrNoData = errors.New("no market data")
+
+// Resolution is the width of a candle
+type Resolution string
+
+const (
+	ResolutionMinute Resolution = "1"
+	ResolutionHour   Resolution = "60"
+	ResolutionDay    Resolution = "D"
+	ResolutionWeek   Resolution = "W"
+	ResolutionMonth  Resolution = "M"
+)
+
+// Quote is the latest price of a symbol
+type Quote struct {
+	Symbol        string    `json:"symbol"`
+	Current       float64   `json:"current"`
+	Open          float64   `json:"open"`
+	High          float64   `json:"high"`
+	Low           float64   `json:"low"`
+	PreviousClose float64   `json:"previous_close"`
+	Timestamp     time.Time `json:"timestamp"`
+}
+
+// Candle is the open, high, low and close of a symbol over one resolution period
+type Candle struct {
+	Timestamp time.Time `json:"timestamp"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	Volume    float64   `json:"volume"`
+}
+
+// CompanyProfile describes the company behind a symbol
+type CompanyProfile struct {
+	Symbol    string  `json:"symbol"`
+	Name      string  `json:"name"`
+	Exchange  string  `json:"exchange"`
+	Sector    string  `json:"sector"` // GICS sector, empty when the provider cannot tell
+	Industry  string  `json:"industry"`
+	Country   string  `json:"country"`
+	Currency  string  `json:"currency"`
+	MarketCap float64 `json:"market_cap"` // In millions
+	Logo      string  `json:"logo"`
+	WebURL    string  `json:"web_url"`
+}
+
+// Dividend is a cash dividend per share
+type Dividend struct {
+	ExDate time.Time `json:"ex_date"`
+	Amount float64   `json:"amount"`
+}
+
+// Split is a stock split, a Ratio of 4 means each share became four
+type Split struct {
+	ExDate time.Time `json:"ex_date"`
+	Ratio  float64   `json:"ratio"`
+}
+
+// MarketDataProvider is a source of prices and company data. Implementations return errors rather
+// than exiting, and wrap ErrNoData when a symbol has nothing to return.
+type MarketDataProvider interface {
+	// Name identifies the provider in logs
+	Name() string
+	GetQuote(ctx context.Context, symbol string) (*Quote, error)
+	GetCandles(ctx context.Context, symbol string, resolution Resolution, from time.Time, to time.Time) ([]Candle, error)
+	GetCompanyProfile(ctx context.Context, symbol string) (*CompanyProfile, error)
+	GetDividends(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Dividend, error)
+	GetSplits(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Split, error)
+}
@@ -0,0 +1,222 @@
+package marketdata
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FetcherConfig tunes how hard a QuoteFetcher leans on its provider
+type FetcherConfig struct {
+	Concurrency    int           // Quotes in flight at once
+	RatePerMinute  int           // Requests allowed a minute, 0 for no limit
+	Burst          int           // Requests allowed back to back before the rate applies
+	MaxAttempts    int           // Attempts per symbol, including the first
+	BaseBackoff    time.Duration // Wait before the first retry, doubled for each one after
+	MaxBackoff     time.Duration // Longest wait between retries
+	RequestTimeout time.Duration // Longest a single quote request may take
+}
+
+// DefaultFetcherConfig fits Finnhub's free tier of 60 requests a minute
+func DefaultFetcherConfig() FetcherConfig {
+	return FetcherConfig{
+		Concurrency:    4,
+		RatePerMinute:  60,
+		Burst:          5,
+		MaxAttempts:    4,
+		BaseBackoff:    time.Second,
+		MaxBackoff:     30 * time.Second,
+		RequestTimeout: 10 * time.Second,
+	}
+}
+
+// FetcherConfigFromEnv overrides the defaults with MARKET_DATA_CONCURRENCY,
+// MARKET_DATA_RATE_PER_MINUTE and MARKET_DATA_MAX_ATTEMPTS
+func FetcherConfigFromEnv() FetcherConfig {
+	config := DefaultFetcherConfig()
+	if value, err := strconv.Atoi(os.Getenv("MARKET_DATA_CONCURRENCY")); err == nil && value > 0 {
+		config.Concurrency = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("MARKET_DATA_RATE_PER_MINUTE")); err == nil && value >= 0 {
+		config.RatePerMinute = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("MARKET_DATA_MAX_ATTEMPTS")); err == nil && value > 0 {
+		config.MaxAttempts = value
+	}
+	return config
+}
+
+// FetchFailure is a symbol that had no usable quote by the end of a run
+type FetchFailure struct {
+	Symbol   string `json:"symbol"`
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error"`
+}
+
+// FetchSummary reports how a run went
+type FetchSummary struct {
+	Requested int            `json:"requested"`
+	Succeeded int            `json:"succeeded"`
+	Retries   int            `json:"retries"`
+	Failures  []FetchFailure `json:"failures"`
+	Duration  time.Duration  `json:"duration"`
+}
+
+func (s FetchSummary) String() string {
+	return fmt.Sprintf("%d/%d quotes fetched in %s with %d retries, %d failed",
+		s.Succeeded, s.Requested, s.Duration.Round(time.Millisecond), s.Retries, len(s.Failures))
+}
+
+// QuoteHandler receives each quote as it arrives, an error marks the symbol failed without a retry
+type QuoteHandler func(symbol string, quote *Quote) error
+
+// CandleHandler receives the candles of each symbol as they arrive, an error marks the symbol failed without a retry
+type CandleHandler func(symbol string, candles []Candle) error
+
+// fetchRequest makes one provider request for a symbol and reports whether a failure is worth retrying
+type fetchRequest func(ctx context.Context, symbol string) (bool, error)
+
+// QuoteFetcher fetches many quotes concurrently within the provider's rate limit, retrying
+// transient errors with exponential backoff
+type QuoteFetcher struct {
+	provider MarketDataProvider
+	config   FetcherConfig
+	limiter  *TokenBucket
+}
+
+// NewQuoteFetcher creates a fetcher, the rate limit is shared by every run
+func NewQuoteFetcher(provider MarketDataProvider, config FetcherConfig) *QuoteFetcher {
+	if config.Concurrency < 1 {
+		config.Concurrency = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &QuoteFetcher{
+		provider: provider,
+		config:   config,
+		limiter:  NewTokenBucket(config.RatePerMinute, config.Burst),
+	}
+}
+
+// fetchJob is one attempt at one symbol
+type fetchJob struct {
+	symbol  string
+	attempt int
+}
+
+// FetchQuotes fetches a quote for every symbol and hands it to handle. A symbol that fails with a
+// transient error goes back on the queue after a backoff, so the rest keep flowing meanwhile.
+// It returns once every symbol has succeeded or run out of attempts.
+func (f *QuoteFetcher) FetchQuotes(ctx context.Context, symbols []string, handle QuoteHandler) FetchSummary {
+	return f.fetchAll(ctx, symbols, func(ctx context.Context, symbol string) (bool, error) {
+		quote, err := f.provider.GetQuote(ctx, symbol)
+		if err != nil {
+			// Unknown and delisted symbols will not come back on a retry
+			return !errors.Is(err, ErrNoData), err
+		}
+		return false, handle(symbol, quote)
+	})
+}
+
+// FetchCandles fetches the candles of every symbol between from and to the same way FetchQuotes fetches quotes
+func (f *QuoteFetcher) FetchCandles(ctx context.Context, symbols []string, resolution Resolution, from, to time.Time, handle CandleHandler) FetchSummary {
+	return f.fetchAll(ctx, symbols, func(ctx context.Context, symbol string) (bool, error) {
+		candles, err := f.provider.GetCandles(ctx, symbol, resolution, from, to)
+		if err != nil {
+			return !errors.Is(err, ErrNoData), err
+		}
+		return false, handle(symbol, candles)
+	})
+}
+
+// fetchAll makes request for every symbol on a pool of workers, retrying transient failures
+func (f *QuoteFetcher) fetchAll(ctx context.Context, symbols []string, request fetchRequest) FetchSummary {
+	start := time.Now()
+	summary := FetchSummary{Requested: len(symbols), Failures: []FetchFailure{}}
+	if len(symbols) == 0 {
+		return summary
+	}
+
+	// Each symbol is queued at most once at a time, so the buffer never fills
+	queue := make(chan fetchJob, len(symbols))
+	var pending sync.WaitGroup
+	var mutex sync.Mutex
+	finish := func(job fetchJob, err error) {
+		mutex.Lock()
+		if err == nil {
+			summary.Succeeded++
+		} else {
+			summary.Failures = append(summary.Failures, FetchFailure{Symbol: job.symbol, Attempts: job.attempt, Error: err.Error()})
+		}
+		mutex.Unlock()
+		pending.Done()
+	}
+
+	pending.Add(len(symbols))
+	for _, symbol := range symbols {
+		queue <- fetchJob{symbol: symbol, attempt: 1}
+	}
+
+	var workers sync.WaitGroup
+	for i := 0; i < f.config.Concurrency; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			for job := range queue {
+				retryable, err := f.fetchOne(ctx, job.symbol, request)
+				if err != nil && retryable && job.attempt < f.config.MaxAttempts && ctx.Err() == nil {
+					mutex.Lock()
+					summary.Retries++
+					mutex.Unlock()
+					retry := fetchJob{symbol: job.symbol, attempt: job.attempt + 1}
+					time.AfterFunc(f.backoff(job.attempt), func() { queue <- retry })
+					continue
+				}
+				finish(job, err)
+			}
+		}()
+	}
+
+	pending.Wait()
+	close(queue)
+	workers.Wait()
+
+	sort.Slice(summary.Failures, func(i, j int) bool { return summary.Failures[i].Symbol < summary.Failures[j].Symbol })
+	summary.Duration = time.Since(start)
+	return summary
+}
+
+// fetchOne makes one attempt at a symbol within the rate limit and reports whether a failure is worth retrying
+func (f *QuoteFetcher) fetchOne(ctx context.Context, symbol string, request fetchRequest) (bool, error) {
+	if err := f.limiter.Wait(ctx); err != nil {
+		return false, err
+	}
+
+	requestCtx := ctx
+	if f.config.RequestTimeout > 0 {
+		var cancel context.CancelFunc
+		requestCtx, cancel = context.WithTimeout(ctx, f.config.RequestTimeout)
+		defer cancel()
+	}
+	return request(requestCtx, symbol)
+}
+
+// backoff doubles the wait for every attempt, capped, with jitter so retries do not arrive together
+func (f *QuoteFetcher) backoff(attempt int) time.Duration {
+	if f.config.BaseBackoff <= 0 {
+		return 0
+	}
+	// A shift that overflows goes negative, which the cap also catches
+	wait := f.config.BaseBackoff << (attempt - 1)
+	if f.config.MaxBackoff > 0 && (wait <= 0 || wait > f.config.MaxBackoff) {
+		wait = f.config.MaxBackoff
+	}
+	return wait/2 + time.Duration(rand.Int63n(int64(wait/2)+1))
+}
@@ -0,0 +1,59 @@
+package marketdata
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// TokenBucket limits requests to a steady rate while allowing short bursts. Providers limit us in
+// wall-clock time, so it never follows a simulated clock.
+type TokenBucket struct {
+	mutex      sync.Mutex
+	tokens     float64
+	capacity   float64
+	perSecond  float64
+	lastRefill time.Time
+}
+
+// NewTokenBucket allows ratePerMinute requests a minute with bursts of up to burst requests.
+// A ratePerMinute of 0 or less means no limit.
+func NewTokenBucket(ratePerMinute int, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		tokens:     float64(burst),
+		capacity:   float64(burst),
+		perSecond:  float64(ratePerMinute) / 60,
+		lastRefill: time.Now(),
+	}
+}
+
+// Wait blocks until a request may be made or the context is done
+func (b *TokenBucket) Wait(ctx context.Context) error {
+	if b.perSecond <= 0 {
+		return ctx.Err()
+	}
+	for {
+		b.mutex.Lock()
+		now := time.Now()
+		b.tokens = min(b.capacity, b.tokens+now.Sub(b.lastRefill).Seconds()*b.perSecond)
+		b.lastRefill = now
+		if b.tokens >= 1 {
+			b.tokens--
+			b.mutex.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - b.tokens) / b.perSecond * float64(time.Second))
+		b.mutex.Unlock()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,367 @@
+package marketdata
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/utils"
+)
+
+// replayCloseHour is when a date-only bar is stamped, the market close in the market timezone
+const replayCloseHour = 15
+
+// Compile-time check
+var _ MarketDataProvider = (*ReplayProvider)(nil)
+
+// ReplayBar is one recorded price of a symbol. Price is shorthand for a bar where open, high, low and close are equal.
+type ReplayBar struct {
+	Symbol    string    `json:"symbol"`
+	Timestamp time.Time `json:"timestamp"`
+	Open      float64   `json:"open"`
+	High      float64   `json:"high"`
+	Low       float64   `json:"low"`
+	Close     float64   `json:"close"`
+	Volume    float64   `json:"volume"`
+}
+
+// ReplayProvider serves recorded prices as if they were live. A quote is the latest bar at or before
+// the clock, so running it on a simulated clock replays history through the scheduler unchanged.
+type ReplayProvider struct {
+	clock utils.Clock
+	bars  map[string][]ReplayBar // by upper-case symbol, oldest first
+}
+
+// NewReplayProvider creates a provider over the given bars, read on the given clock
+func NewReplayProvider(bars []ReplayBar, clock utils.Clock) *ReplayProvider {
+	p := &ReplayProvider{clock: clock, bars: make(map[string][]ReplayBar)}
+	for _, bar := range bars {
+		symbol := strings.ToUpper(bar.Symbol)
+		p.bars[symbol] = append(p.bars[symbol], bar)
+	}
+	for symbol := range p.bars {
+		sort.SliceStable(p.bars[symbol], func(i, j int) bool {
+			return p.bars[symbol][i].Timestamp.Before(p.bars[symbol][j].Timestamp)
+		})
+	}
+	return p
+}
+
+// FirstTimestamp is the time of the earliest bar, where a replay starts by default
+func (p *ReplayProvider) FirstTimestamp() (time.Time, bool) {
+	var first time.Time
+	for _, bars := range p.bars {
+		if len(bars) > 0 && (first.IsZero() || bars[0].Timestamp.Before(first)) {
+			first = bars[0].Timestamp
+		}
+	}
+	return first, !first.IsZero()
+}
+
+// Clock is the clock the replay runs on, install it with utils.SetClock so the rest of the app follows it
+func (p *ReplayProvider) Clock() utils.Clock {
+	return p.clock
+}
+
+// * Implementation of Interface
+
+func (p *ReplayProvider) Name() string {
+	return "replay"
+}
+
+// GetQuote returns the latest bar of a symbol at or before the clock
+func (p *ReplayProvider) GetQuote(ctx context.Context, symbol string) (*Quote, error) {
+	bars := p.bars[strings.ToUpper(symbol)]
+	now := p.clock.Now()
+	i := sort.Search(len(bars), func(i int) bool { return bars[i].Timestamp.After(now) })
+	if i == 0 {
+		return nil, fmt.Errorf("quote for %s at %s: %w", symbol, now.Format(time.RFC3339), ErrNoData)
+	}
+
+	bar := bars[i-1]
+	quote := &Quote{
+		Symbol:    symbol,
+		Current:   bar.Close,
+		Open:      bar.Open,
+		High:      bar.High,
+		Low:       bar.Low,
+		Timestamp: bar.Timestamp,
+	}
+	if i > 1 {
+		quote.PreviousClose = bars[i-2].Close
+	}
+	return quote, nil
+}
+
+// GetCandles aggregates the bars of a symbol between from and to, never past the clock, into candles
+func (p *ReplayProvider) GetCandles(ctx context.Context, symbol string, resolution Resolution, from time.Time, to time.Time) ([]Candle, error) {
+	bars := p.bars[strings.ToUpper(symbol)]
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("candles for %s: %w", symbol, ErrNoData)
+	}
+	if now := p.clock.Now(); to.After(now) {
+		to = now
+	}
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return nil, err
+	}
+
+	var candles []Candle
+	for _, bar := range bars {
+		if bar.Timestamp.Before(from) || bar.Timestamp.After(to) {
+			continue
+		}
+		bucket, err := candleStart(bar.Timestamp.In(location), resolution)
+		if err != nil {
+			return nil, err
+		}
+		last := len(candles) - 1
+		if last >= 0 && candles[last].Timestamp.Equal(bucket) {
+			candles[last].High = max(candles[last].High, bar.High)
+			candles[last].Low = min(candles[last].Low, bar.Low)
+			candles[last].Close = bar.Close
+			candles[last].Volume += bar.Volume
+			continue
+		}
+		candles = append(candles, Candle{
+			Timestamp: bucket,
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Close:     bar.Close,
+			Volume:    bar.Volume,
+		})
+	}
+	return candles, nil
+}
+
+// GetCompanyProfile knows only the symbols it has bars for
+func (p *ReplayProvider) GetCompanyProfile(ctx context.Context, symbol string) (*CompanyProfile, error) {
+	if len(p.bars[strings.ToUpper(symbol)]) == 0 {
+		return nil, fmt.Errorf("company profile for %s: %w", symbol, ErrNoData)
+	}
+	return &CompanyProfile{Symbol: symbol, Name: symbol}, nil
+}
+
+// GetDividends has no recorded dividends, import them from a corporate actions CSV instead
+func (p *ReplayProvider) GetDividends(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Dividend, error) {
+	return nil, nil
+}
+
+// GetSplits has no recorded splits, import them from a corporate actions CSV instead
+func (p *ReplayProvider) GetSplits(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Split, error) {
+	return nil, nil
+}
+
+// * Loading
+
+// LoadReplayBars reads bars from a .csv or .json file, or from every such file in a directory
+func LoadReplayBars(path string) ([]ReplayBar, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read replay data: %v", err)
+	}
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("unable to list replay data: %v", err)
+		}
+		files = nil
+		for _, entry := range entries {
+			extension := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (extension == ".csv" || extension == ".json") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	var bars []ReplayBar
+	for _, file := range files {
+		fileBars, err := loadReplayFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", file, err)
+		}
+		bars = append(bars, fileBars...)
+	}
+	if len(bars) == 0 {
+		return nil, fmt.Errorf("no replay bars found in %s", path)
+	}
+	return bars, nil
+}
+
+func loadReplayFile(path string) ([]ReplayBar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	if strings.ToLower(filepath.Ext(path)) == ".json" {
+		return ReadReplayJSON(file)
+	}
+	return ReadReplayCSV(file)
+}
+
+// ReadReplayCSV reads bars from CSV with a header row naming symbol, timestamp and either price or
+// open,high,low,close, plus an optional volume. Timestamps are RFC 3339, or YYYY-MM-DD for a daily
+// close in the market timezone.
+func ReadReplayCSV(reader io.Reader) ([]ReplayBar, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read header: %v", err)
+	}
+	columns := make(map[string]int, len(header))
+	for index, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	for _, required := range []string{"symbol", "timestamp"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %q column", required)
+		}
+	}
+	_, hasPrice := columns["price"]
+	_, hasClose := columns["close"]
+	if !hasPrice && !hasClose {
+		return nil, fmt.Errorf("missing %q or %q column", "price", "close")
+	}
+
+	var bars []ReplayBar
+	for line := 2; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		field := func(name string) string {
+			if index, ok := columns[name]; ok && index < len(record) {
+				return strings.TrimSpace(record[index])
+			}
+			return ""
+		}
+		number := func(name string) (float64, error) {
+			value := field(name)
+			if value == "" {
+				return 0, nil
+			}
+			return strconv.ParseFloat(value, 64)
+		}
+
+		timestamp, err := parseReplayTimestamp(field("timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		bar := ReplayBar{Symbol: field("symbol"), Timestamp: timestamp}
+		values := []struct {
+			name   string
+			target *float64
+		}{{"open", &bar.Open}, {"high", &bar.High}, {"low", &bar.Low}, {"close", &bar.Close}, {"volume", &bar.Volume}}
+		for _, value := range values {
+			if *value.target, err = number(value.name); err != nil {
+				return nil, fmt.Errorf("line %d: invalid %s: %v", line, value.name, err)
+			}
+		}
+		price, err := number("price")
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %v", line, err)
+		}
+		bars = append(bars, normalizeBar(bar, price))
+	}
+	return bars, nil
+}
+
+// ReadReplayJSON reads a JSON array of bars with the same fields as ReadReplayCSV
+func ReadReplayJSON(reader io.Reader) ([]ReplayBar, error) {
+	var records []struct {
+		Symbol    string  `json:"symbol"`
+		Timestamp string  `json:"timestamp"`
+		Price     float64 `json:"price"`
+		Open      float64 `json:"open"`
+		High      float64 `json:"high"`
+		Low       float64 `json:"low"`
+		Close     float64 `json:"close"`
+		Volume    float64 `json:"volume"`
+	}
+	if err := json.NewDecoder(reader).Decode(&records); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+
+	bars := make([]ReplayBar, 0, len(records))
+	for index, record := range records {
+		timestamp, err := parseReplayTimestamp(record.Timestamp)
+		if err != nil {
+			return nil, fmt.Errorf("record %d: %v", index, err)
+		}
+		bars = append(bars, normalizeBar(ReplayBar{
+			Symbol:    record.Symbol,
+			Timestamp: timestamp,
+			Open:      record.Open,
+			High:      record.High,
+			Low:       record.Low,
+			Close:     record.Close,
+			Volume:    record.Volume,
+		}, record.Price))
+	}
+	return bars, nil
+}
+
+// normalizeBar fills in whatever a price-only or close-only row left out
+func normalizeBar(bar ReplayBar, price float64) ReplayBar {
+	if bar.Close == 0 {
+		bar.Close = price
+	}
+	if bar.Open == 0 {
+		bar.Open = bar.Close
+	}
+	if bar.High == 0 {
+		bar.High = max(bar.Open, bar.Close)
+	}
+	if bar.Low == 0 {
+		bar.Low = min(bar.Open, bar.Close)
+	}
+	return bar
+}
+
+func parseReplayTimestamp(value string) (time.Time, error) {
+	if timestamp, err := time.Parse(time.RFC3339, value); err == nil {
+		return timestamp, nil
+	}
+	date, err := parseMarketDate(value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %q, expected RFC 3339 or %s", value, DateFormat)
+	}
+	return date.Add(replayCloseHour * time.Hour), nil
+}
+
+// candleStart is the start of the candle a moment falls in
+func candleStart(t time.Time, resolution Resolution) (time.Time, error) {
+	switch resolution {
+	case ResolutionMinute:
+		return t.Truncate(time.Minute), nil
+	case ResolutionHour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location()), nil
+	case ResolutionDay:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location()), nil
+	case ResolutionWeek:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location()), nil
+	case ResolutionMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location()), nil
+	default:
+		return time.Time{}, fmt.Errorf("unsupported resolution %q", resolution)
+	}
+}
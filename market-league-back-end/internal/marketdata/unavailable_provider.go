@@ -0,0 +1,53 @@
+package marketdata
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Compile-time check
+var _ MarketDataProvider = (*UnavailableProvider)(nil)
+
+// UnavailableProvider stands in for a provider that could not be set up, such as Finnhub without an API key.
+// Every call fails with the reason, so the rest of the app keeps running on the prices it already recorded.
+type UnavailableProvider struct {
+	reason error
+}
+
+// NewUnavailableProvider creates a provider whose every call fails with reason
+func NewUnavailableProvider(reason error) *UnavailableProvider {
+	return &UnavailableProvider{reason: reason}
+}
+
+// * Implementation of Interface
+
+func (p *UnavailableProvider) Name() string {
+	return "unavailable"
+}
+
+func (p *UnavailableProvider) GetQuote(ctx context.Context, symbol string) (*Quote, error) {
+	return nil, p.err()
+}
+
+func (p *UnavailableProvider) GetCandles(ctx context.Context, symbol string, resolution Resolution, from time.Time, to time.Time) ([]Candle, error) {
+	return nil, p.err()
+}
+
+func (p *UnavailableProvider) GetCompanyProfile(ctx context.Context, symbol string) (*CompanyProfile, error) {
+	return nil, p.err()
+}
+
+func (p *UnavailableProvider) GetDividends(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Dividend, error) {
+	return nil, p.err()
+}
+
+func (p *UnavailableProvider) GetSplits(ctx context.Context, symbol string, from time.Time, to time.Time) ([]Split, error) {
+	return nil, p.err()
+}
+
+// * Helper functions
+
+func (p *UnavailableProvider) err() error {
+	return fmt.Errorf("market data is unavailable: %v", p.reason)
+}
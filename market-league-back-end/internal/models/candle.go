@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// CandleInterval is how much time each candle of a price chart covers
+type CandleInterval string
+
+const (
+	CandleInterval_Hour CandleInterval = "1h"
+	CandleInterval_Day  CandleInterval = "1d"
+	CandleInterval_Week CandleInterval = "1w"
+)
+
+// Candle is the open, high, low and close of the prices recorded in one interval
+type Candle struct {
+	Time   time.Time `json:"time"` // Start of the interval
+	Open   float64   `json:"open"`
+	High   float64   `json:"high"`
+	Low    float64   `json:"low"`
+	Close  float64   `json:"close"`
+	Points int       `json:"points"` // Recorded prices the candle was built from
+}
+
+// CandleSeries is the price chart of a stock over a time range
+type CandleSeries struct {
+	StockID     uint           `json:"stock_id"`
+	Interval    CandleInterval `json:"interval"`    // Interval actually used, coarser than asked for when the range is long
+	From        time.Time      `json:"from"`        // Inclusive
+	To          time.Time      `json:"to"`          // Exclusive
+	Downsampled bool           `json:"downsampled"` // Whether candles were merged to fit the limit
+	Candles     []Candle       `json:"candles"`
+}
@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+type CorporateActionType string
+
+const (
+	DividendAction CorporateActionType = "dividend"
+	SplitAction    CorporateActionType = "split"
+)
+
+// CorporateAction is a dividend or split of a stock that scoring has to account for
+type CorporateAction struct {
+	ID        uint                `json:"id" gorm:"primaryKey;autoIncrement"`
+	StockID   uint                `json:"stock_id" gorm:"not null;uniqueIndex:idx_corporate_action"`
+	Stock     Stock               `json:"-" gorm:"foreignKey:StockID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Type      CorporateActionType `json:"type" gorm:"type:varchar(20);not null;uniqueIndex:idx_corporate_action"`
+	ExDate    time.Time           `json:"ex_date" gorm:"not null;uniqueIndex:idx_corporate_action"`
+	Amount    float64             `json:"amount"`     // Cash paid per share, dividends only
+	Ratio     float64             `json:"ratio"`      // New shares per old share, splits only (4 for a 4-for-1 split)
+	AppliedAt *time.Time          `json:"applied_at"` // Set once prices and holdings have been adjusted
+	CreatedAt time.Time           `json:"created_at" gorm:"autoCreateTime"`
+}
@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// JobRun is one run of a background job, kept so missed runs can be found and made up
+type JobRun struct {
+	ID           uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	Job          string    `json:"job" gorm:"type:varchar(50);not null;index:idx_job_run_job_scheduled"`
+	Key          string    `json:"key" gorm:"column:run_key;type:varchar(100);index"`            // Idempotency key, the job and its scheduled time
+	Trigger      string    `json:"trigger" gorm:"column:triggered_by;type:varchar(20);not null"` // schedule, catch_up or manual
+	ScheduledFor time.Time `json:"scheduled_for" gorm:"index:idx_job_run_job_scheduled"`
+	Status       string    `json:"status" gorm:"type:varchar(20);not null"` // succeeded, failed, timed_out or skipped
+	Error        string    `json:"error"`                                   // Summary of what went wrong
+	Items        int       `json:"items"`                                   // Items processed, such as prices recorded
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+}
@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+type AwardType string
+
+// Awards handed out when a league completes
+const (
+	Award_Champion AwardType = "champion"  // Most points, shared on a tie
+	Award_BestPick AwardType = "best_pick" // Stock that scored the most points for one portfolio
+)
+
+type LeagueAward struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	LeagueID    uint      `json:"league_id" gorm:"uniqueIndex:idx_league_award_user"`
+	Award       AwardType `json:"award" gorm:"type:varchar(20);uniqueIndex:idx_league_award_user"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_league_award_user"`
+	PortfolioID uint      `json:"portfolio_id"`
+	StockID     *uint     `json:"stock_id"` // Stock the award is for, nil for awards to a whole portfolio
+	Points      float64   `json:"points"`
+	AwardedAt   time.Time `json:"awarded_at"`
+}
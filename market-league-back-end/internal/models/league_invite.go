@@ -0,0 +1,17 @@
+package models
+
+import "time"
+
+// LeagueInvite is a shareable code the owner of a league hands out so users can join it themselves
+type LeagueInvite struct {
+	ID        uint       `json:"id" gorm:"primaryKey;autoIncrement"`
+	LeagueID  uint       `json:"league_id" gorm:"index"`
+	Code      string     `json:"code" gorm:"type:varchar(32);uniqueIndex"`
+	CreatedBy uint       `json:"created_by"`
+	ExpiresAt *time.Time `json:"expires_at"` // Nil never expires
+	MaxUses   *int       `json:"max_uses"`   // Nil can be redeemed any number of times
+	Uses      int        `json:"uses" gorm:"default:0"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at" gorm:"autoCreateTime"`
+	Link      string     `json:"link" gorm:"-"` // Built from INVITE_LINK_BASE_URL, empty when it is not set
+}
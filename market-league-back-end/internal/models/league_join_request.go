@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+type JoinRequestStatus string
+
+// The states of a request to join a public league
+const (
+	JoinRequest_Pending  JoinRequestStatus = "pending"
+	JoinRequest_Approved JoinRequestStatus = "approved"
+	JoinRequest_Denied   JoinRequestStatus = "denied"
+)
+
+// LeagueJoinRequest is a user asking the owner of a public league to let them in
+type LeagueJoinRequest struct {
+	ID        uint              `json:"id" gorm:"primaryKey;autoIncrement"`
+	LeagueID  uint              `json:"league_id" gorm:"index"`
+	UserID    uint              `json:"user_id" gorm:"index"`
+	Username  string            `json:"username" gorm:"->;-:migration"` // Filled in when listing requests
+	Message   string            `json:"message" gorm:"type:varchar(500)"`
+	Status    JoinRequestStatus `json:"status" gorm:"type:varchar(20);default:'pending'"`
+	DecidedBy *uint             `json:"decided_by"`
+	DecidedAt *time.Time        `json:"decided_at"`
+	CreatedAt time.Time         `json:"created_at" gorm:"autoCreateTime"`
+}
+
+// DirectoryLeague is a public league as listed in the league directory
+type DirectoryLeague struct {
+	ID            uint       `json:"id"`
+	LeagueName    string     `json:"league_name"`
+	Description   string     `json:"description"`
+	OwnerID       uint       `json:"owner_id"`
+	OwnerUsername string     `json:"owner_username"`
+	StartDate     time.Time  `json:"start_date"`
+	EndDate       time.Time  `json:"end_date"`
+	DraftTime     *time.Time `json:"draft_time"`
+	Players       int        `json:"players"`
+	MaxPlayers    *int       `json:"max_players"`
+}
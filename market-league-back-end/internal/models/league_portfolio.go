@@ -9,4 +9,7 @@ type LeaguePortfolio struct {
 	Name      string    `json:"name"`
 	Stocks    []Stock   `gorm:"many2many:league_portfolio_stocks;" json:"stocks"`
 	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// PoolSelection is how the draft pool was picked, the stocks still undrafted are in Stocks
+	PoolSelection StockSelection `gorm:"embedded;embeddedPrefix:pool_" json:"pool_selection"`
 }
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// LeagueStanding is a portfolio's place in the final results of a completed league
+type LeagueStanding struct {
+	ID          uint      `json:"id" gorm:"primaryKey;autoIncrement"`
+	LeagueID    uint      `json:"league_id" gorm:"uniqueIndex:idx_league_standing_user"`
+	UserID      uint      `json:"user_id" gorm:"uniqueIndex:idx_league_standing_user"`
+	PortfolioID uint      `json:"portfolio_id"`
+	Rank        int       `json:"rank"` // 1 for the champion, portfolios with the same points share a rank
+	Points      int       `json:"points"`
+	FinalizedAt time.Time `json:"finalized_at"`
+}
+
+// FinalStanding is a row of a league's archived results
+type FinalStanding struct {
+	Rank        int     `json:"rank"`
+	UserID      uint    `json:"user_id"`
+	Username    string  `json:"username"`
+	PortfolioID uint    `json:"portfolio_id"`
+	Points      int     `json:"points"`
+	Stocks      []Stock `json:"stocks" gorm:"-"` // Roster the portfolio finished with
+}
+
+// LeagueResults is the read-only record of a completed league
+type LeagueResults struct {
+	LeagueID   uint            `json:"league_id"`
+	LeagueName string          `json:"league_name"`
+	StartDate  time.Time       `json:"start_date"`
+	EndDate    time.Time       `json:"end_date"`
+	Standings  []FinalStanding `json:"standings"`
+	Awards     []LeagueAward   `json:"awards"`
+}
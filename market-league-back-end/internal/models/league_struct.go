@@ -2,13 +2,38 @@ package models
 
 import "time"
 
+// Default roster layout used when a league does not specify one
+const (
+	DefaultStartingSlots = 5
+	DefaultBenchSlots    = 0
+)
+
+// DefaultMinPlayers is how many players a league needs before its draft can start when it does not say
+const DefaultMinPlayers = 2
+
 type League struct {
-	ID            uint           `json:"id" gorm:"primaryKey;autoIncrement"`
-	LeagueName    string         `json:"league_name"`
-	StartDate     time.Time      `json:"start_date"`
-	EndDate       time.Time      `json:"end_date"`
-	LeagueState   LeagueState    `json:"league_state" gorm:"type:varchar(20);default:'pre_draft'"`
-	Users         []User         `json:"users" gorm:"many2many:user_leagues;"` // Many-to-many Users <-> Leagues
-	MaxPlayers    *int           `json:"max_players"`
-	LeaguePlayers []LeaguePlayer `json:"league_players" gorm:"foreignKey:LeagueID"`
+	ID              uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	LeagueName      string           `json:"league_name"`
+	Public          bool             `json:"public" gorm:"default:false;index"`    // Listed in the league directory, anyone can ask to join
+	Description     string           `json:"description" gorm:"type:varchar(500)"` // Shown in the league directory
+	OwnerID         uint             `json:"owner_id" gorm:"index"`                // User who created the league, 0 if it predates owners being recorded
+	StartDate       time.Time        `json:"start_date"`
+	EndDate         time.Time        `json:"end_date"`
+	LeagueState     LeagueState      `json:"league_state" gorm:"type:varchar(20);default:'pre_draft'"`
+	Users           []User           `json:"users" gorm:"many2many:user_leagues;"` // Many-to-many Users <-> Leagues
+	MaxPlayers      *int             `json:"max_players"`                          // Nil for no limit
+	MinPlayers      int              `json:"min_players" gorm:"default:1"`         // Players needed before the draft can start, leagues from before it was added keep drafting alone
+	StartingSlots   int              `json:"starting_slots" gorm:"default:5"`      // Number of stocks per portfolio that accrue points
+	BenchSlots      int              `json:"bench_slots" gorm:"default:0"`         // Number of drafted stocks held in reserve
+	LeaguePlayers   []LeaguePlayer   `json:"league_players" gorm:"foreignKey:LeagueID"`
+	RosterPositions []RosterPosition `json:"roster_positions" gorm:"foreignKey:LeagueID"` // Sector requirements, leftover slots are flex
+	DraftTime       *time.Time       `json:"draft_time"`                                  // When the draft starts without waiting for everyone to queue up, nil to wait
+	LockRosters     bool             `json:"lock_rosters" gorm:"default:false"`           // Trades close once the league starts
+	RostersLockedAt *time.Time       `json:"rosters_locked_at"`
+	Awards          []LeagueAward    `json:"awards" gorm:"foreignKey:LeagueID"` // Handed out when the league completes
+}
+
+// RosterSize returns the total number of stocks each player drafts in this league
+func (l *League) RosterSize() int {
+	return l.StartingSlots + l.BenchSlots
 }
@@ -13,6 +13,7 @@ type OwnershipHistory struct {
 	Stock         Stock      `json:"stock" gorm:"foreignKey:StockID"`         // Association with Stock
 	StartingValue float64    `json:"starting_value"`                          // Value of the stock when acquired
 	CurrentValue  float64    `json:"current_value"`                           // Current or ending value of the stock
+	Dividends     float64    `json:"dividends" gorm:"default:0"`              // Cash dividends per share paid while held
 	StartDate     time.Time  `json:"start_date"`                              // Timestamp when the stock was acquired
 	EndDate       *time.Time `json:"end_date"`                                // Nullable timestamp for when the stock was sold
 }
@@ -5,6 +5,7 @@ type DraftStatus string
 const (
 	DraftReady    DraftStatus = "ready"
 	DraftNotReady DraftStatus = "not_ready"
+	DraftAutoPick DraftStatus = "autopick" // Absent when the draft started on its own, picks are made for them
 )
 
 type PlayerDraftStatus struct {
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// PointsBreakdown is the share one scoring window contributed to a PortfolioPointsHistory entry
+type PointsBreakdown struct {
+	ID              uint       `json:"id" gorm:"primaryKey;autoIncrement"` // Auto-incrementing primary key
+	PointsHistoryID uint       `json:"points_history_id" gorm:"index"`     // Foreign key to PortfolioPointsHistory
+	PortfolioID     uint       `json:"portfolio_id" gorm:"index"`          // Foreign key to Portfolio
+	ScoringWindowID uint       `json:"scoring_window_id"`                  // Scoring window that was scored
+	StockID         uint       `json:"stock_id"`                           // Foreign key to Stock
+	Stock           Stock      `json:"-" gorm:"foreignKey:StockID"`        // Association with Stock
+	WindowStart     time.Time  `json:"window_start"`                       // When the stock entered the starting lineup
+	WindowEnd       *time.Time `json:"window_end"`                         // When it left, nil while still starting
+	StartPrice      float64    `json:"start_price"`                        // Price when the window opened
+	EndPrice        float64    `json:"end_price"`                          // Price when the window closed or was scored
+	Dividends       float64    `json:"dividends"`                          // Dividends per share paid during the window
+	PercentChange   float64    `json:"percent_change"`                     // Total return of the window in percent
+	Points          float64    `json:"points"`                             // Points contributed before the total is rounded
+	RecordedAt      time.Time  `json:"recorded_at" gorm:"autoCreateTime"`  // Timestamp of when the points were recorded
+}
+
+// PointsComponent is one stock's part of a PointsBreakdownReport, compared with the entry before it
+type PointsComponent struct {
+	PointsBreakdown
+	TickerSymbol   string  `json:"ticker_symbol"`
+	CompanyName    string  `json:"company_name"`
+	PreviousPoints float64 `json:"previous_points"` // Points the same window contributed to the previous entry
+	PointsChange   float64 `json:"points_change"`   // Points gained or lost since the previous entry
+	Dropped        bool    `json:"dropped"`         // Scored in the previous entry but not this one, e.g. traded away
+}
+
+// PointsBreakdownReport explains a PortfolioPointsHistory entry and how it moved from the one before it
+type PointsBreakdownReport struct {
+	PortfolioID     uint              `json:"portfolio_id"`
+	PointsHistoryID uint              `json:"points_history_id"`
+	RecordedAt      time.Time         `json:"recorded_at"`
+	Points          int               `json:"points"`
+	PreviousPoints  *int              `json:"previous_points"` // Nil for the first entry
+	PointsChange    int               `json:"points_change"`
+	Components      []PointsComponent `json:"components"`
+}
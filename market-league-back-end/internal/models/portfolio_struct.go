@@ -13,4 +13,5 @@ type Portfolio struct {
 	Points        int                      `json:"points" gorm:"default:0"`                   // Points calculated based on stock performances
 	PointsHistory []PortfolioPointsHistory `gorm:"foreignKey:PortfolioID"`                    // One-to-many relationship with PortfolioPointsHistory
 	CreatedAt     time.Time                `gorm:"autoCreateTime"`                            // Timestamp of portfolio creation
+	LeftAt        *time.Time               `json:"left_at" gorm:"index"`                      // When its player left the league after the draft, it is no longer scored
 }
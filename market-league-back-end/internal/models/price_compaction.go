@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// DailyPriceBar is the open, high, low and close of a stock's prices on one market day, kept once the
+// raw prices of that day have been compacted
+type DailyPriceBar struct {
+	ID      uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	StockID uint      `gorm:"not null;uniqueIndex:idx_daily_bar_stock_date" json:"stock_id"`
+	Stock   Stock     `gorm:"foreignKey:StockID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	Date    time.Time `gorm:"not null;uniqueIndex:idx_daily_bar_stock_date" json:"date"` // Midnight of the market day
+	Open    float64   `gorm:"not null" json:"open"`
+	High    float64   `gorm:"not null" json:"high"`
+	Low     float64   `gorm:"not null" json:"low"`
+	Close   float64   `gorm:"not null" json:"close"`
+	OpenAt  time.Time `gorm:"not null" json:"open_at"`  // When the opening price was recorded
+	CloseAt time.Time `gorm:"not null" json:"close_at"` // When the closing price was recorded
+	Points  int       `gorm:"not null" json:"points"`   // Raw prices the bar was built from
+}
+
+// AsPriceHistory stands in for the compacted prices of the day with its open and close, at the times they were recorded
+func (b DailyPriceBar) AsPriceHistory() []PriceHistory {
+	closing := PriceHistory{StockID: b.StockID, Price: b.Close, Timestamp: b.CloseAt}
+	if !b.OpenAt.Before(b.CloseAt) {
+		return []PriceHistory{closing}
+	}
+	return []PriceHistory{{StockID: b.StockID, Price: b.Open, Timestamp: b.OpenAt}, closing}
+}
+
+// CompactedRange records that the raw prices of a stock before a time were rolled into daily bars
+type CompactedRange struct {
+	ID          uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	StockID     uint      `gorm:"not null;index" json:"stock_id"`
+	From        time.Time `gorm:"not null" json:"from"` // Earliest raw price compacted
+	To          time.Time `gorm:"not null" json:"to"`   // Raw prices before this were compacted
+	Points      int       `gorm:"not null" json:"points"`
+	Bars        int       `gorm:"not null" json:"bars"`
+	CompactedAt time.Time `gorm:"not null" json:"compacted_at"`
+}
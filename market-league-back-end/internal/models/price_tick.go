@@ -0,0 +1,16 @@
+// models/price_tick.go
+
+package models
+
+import "time"
+
+// PriceTick is a live price update pushed to clients subscribed to a stock
+type PriceTick struct {
+	StockID       uint      `json:"stock_id"`
+	TickerSymbol  string    `json:"ticker_symbol"`
+	Price         float64   `json:"price"`
+	PreviousClose float64   `json:"previous_close"`
+	Change        float64   `json:"change"`         // Price minus the previous close, 0 when it is unknown
+	PercentChange float64   `json:"percent_change"` // Change as a percentage of the previous close
+	Timestamp     time.Time `json:"timestamp"`
+}
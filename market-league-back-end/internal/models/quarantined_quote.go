@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// QuoteRule names the data quality rule a quote broke
+type QuoteRule string
+
+const (
+	QuoteRule_InvalidPrice QuoteRule = "invalid_price" // Zero, negative, NaN or infinite
+	QuoteRule_MaxMove      QuoteRule = "max_move"      // Moved more than allowed from the last price
+	QuoteRule_Stale        QuoteRule = "stale"         // Has not changed for too many trading days
+)
+
+type QuarantineStatus string
+
+const (
+	QuarantinePending  QuarantineStatus = "pending"
+	QuarantineApproved QuarantineStatus = "approved"
+	QuarantineRejected QuarantineStatus = "rejected"
+)
+
+// QuarantinedQuote is a suspicious quote held back from prices and scoring until an admin reviews it.
+// A stock has at most one pending quote, later suspicious quotes replace it.
+type QuarantinedQuote struct {
+	ID            uint             `json:"id" gorm:"primaryKey;autoIncrement"`
+	StockID       uint             `json:"stock_id" gorm:"not null;index"`
+	Stock         Stock            `json:"stock" gorm:"foreignKey:StockID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
+	Price         float64          `json:"price"`          // Not NaN or infinite, those are stored as 0
+	PreviousPrice float64          `json:"previous_price"` // Price of the stock when the quote arrived
+	Timestamp     time.Time        `json:"timestamp"`      // When the price would have been recorded
+	Rule          QuoteRule        `json:"rule" gorm:"type:varchar(20);not null"`
+	Reason        string           `json:"reason"`
+	Occurrences   int              `json:"occurrences" gorm:"default:1"` // Suspicious quotes seen while pending
+	Status        QuarantineStatus `json:"status" gorm:"type:varchar(20);not null;default:'pending';index"`
+	ReviewedBy    *uint            `json:"reviewed_by"`
+	ReviewedAt    *time.Time       `json:"reviewed_at"`
+	CreatedAt     time.Time        `json:"created_at" gorm:"autoCreateTime"`
+}
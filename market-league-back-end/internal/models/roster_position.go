@@ -0,0 +1,12 @@
+package models
+
+// FlexPosition is the sector name of a roster position that any stock can fill
+const FlexPosition = "Flex"
+
+// RosterPosition is a sector requirement of a league's roster, e.g. "1 Technology" or "2 Flex"
+type RosterPosition struct {
+	ID       uint   `json:"id" gorm:"primaryKey;autoIncrement"`
+	LeagueID uint   `json:"league_id" gorm:"index"`
+	Sector   string `json:"sector"`
+	Count    int    `json:"count"`
+}
@@ -32,6 +32,7 @@ type SanitizedStock struct {
 	ID           uint    `json:"id"`
 	TickerSymbol string  `json:"ticker_symbol"`
 	CompanyName  string  `json:"company_name"`
+	Sector       string  `json:"sector"`
 	CurrentPrice float64 `json:"current_price"`
 }
 
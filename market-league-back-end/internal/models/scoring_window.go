@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+)
+
+// ScoringWindow tracks a span of time in which a stock sat in a portfolio's starting lineup.
+// Unlike OwnershipHistory, a window is closed whenever the stock is benched, so only starters accrue points.
+type ScoringWindow struct {
+	ID            uint       `json:"id" gorm:"primaryKey;autoIncrement"` // Auto-incrementing primary key
+	PortfolioID   uint       `json:"portfolio_id" gorm:"index"`          // Foreign key to Portfolio
+	Portfolio     Portfolio  `json:"-" gorm:"foreignKey:PortfolioID"`    // Association with Portfolio
+	StockID       uint       `json:"stock_id" gorm:"index"`              // Foreign key to Stock
+	Stock         Stock      `json:"stock" gorm:"foreignKey:StockID"`    // Association with Stock
+	StartingValue float64    `json:"starting_value"`                     // Price of the stock when it entered the lineup
+	CurrentValue  float64    `json:"current_value"`                      // Current price, or price when it left the lineup
+	Dividends     float64    `json:"dividends" gorm:"default:0"`         // Cash dividends per share paid while starting
+	StartDate     time.Time  `json:"start_date"`                         // Timestamp when the stock started scoring
+	EndDate       *time.Time `json:"end_date"`                           // Nullable timestamp for when the stock was benched
+}
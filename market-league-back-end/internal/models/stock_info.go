@@ -6,6 +6,8 @@ type StockInfo struct {
 	ID             uint              `json:"id"`
 	TickerSymbol   string            `json:"ticker_symbol"`
 	CompanyName    string            `json:"company_name"`
+	Sector         string            `json:"sector"`
+	Industry       string            `json:"industry"`
 	CurrentPrice   float64           `json:"current_price"`
 	PriceHistories []PriceHistoryDTO `json:"price_histories"`
 }
@@ -0,0 +1,11 @@
+package models
+
+// StockSelection picks stocks by criteria that all have to match. A blank criterion matches every
+// stock, so the zero value selects every stock still trading.
+type StockSelection struct {
+	Universe     string   `json:"universe,omitempty"`                         // Named universe such as "sp500"
+	Sectors      []string `json:"sectors,omitempty" gorm:"serializer:json"`   // Any of these sectors
+	MinMarketCap *float64 `json:"min_market_cap,omitempty"`                   // In millions of USD
+	MaxMarketCap *float64 `json:"max_market_cap,omitempty"`                   // In millions of USD
+	StockIDs     []uint   `json:"stock_ids,omitempty" gorm:"serializer:json"` // A hand-picked list
+}
@@ -2,10 +2,19 @@
 
 package models
 
+import "time"
+
 type Stock struct {
 	ID             uint           `gorm:"primaryKey;autoIncrement" json:"id"`
 	TickerSymbol   string         `json:"ticker_symbol" gorm:"unique;not null"`
 	CompanyName    string         `json:"company_name"`
+	Sector         string         `json:"sector"`
+	Industry       string         `json:"industry"`
+	Exchange       string         `json:"exchange"`
+	MarketCap      float64        `json:"market_cap"` // In millions of USD
+	LogoURL        string         `json:"logo_url"`
+	DelistedAt     *time.Time     `json:"delisted_at"` // Delisted stocks are kept so ownership history still points at them
+	DelistsAt      *time.Time     `json:"delists_at"`  // An announced delisting that has not taken effect yet
 	CurrentPrice   float64        `json:"current_price"`
 	PriceHistories []PriceHistory `json:"price_histories" gorm:"foreignKey:StockID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE"`
 }
@@ -0,0 +1,27 @@
+// models/stock_universe.go
+
+package models
+
+import "time"
+
+// UniverseMember puts a stock in a named universe of stocks, such as an index
+type UniverseMember struct {
+	ID        uint       `gorm:"primaryKey;autoIncrement" json:"id"`
+	Universe  string     `gorm:"not null;uniqueIndex:idx_universe_stock" json:"universe"`
+	StockID   uint       `gorm:"not null;uniqueIndex:idx_universe_stock" json:"stock_id"`
+	Stock     Stock      `gorm:"foreignKey:StockID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	AddedAt   time.Time  `gorm:"not null" json:"added_at"`
+	RemovedAt *time.Time `json:"removed_at"` // Set once the stock leaves the universe
+}
+
+// TickerChange records a stock starting to trade under a new symbol. The stock keeps its ID, so everything
+// that references it follows the change. A change announced ahead of its effective date stays pending until then.
+type TickerChange struct {
+	ID            uint      `gorm:"primaryKey;autoIncrement" json:"id"`
+	StockID       uint      `gorm:"not null;index" json:"stock_id"`
+	Stock         Stock     `gorm:"foreignKey:StockID;constraint:OnUpdate:CASCADE,OnDelete:CASCADE" json:"-"`
+	OldSymbol     string    `gorm:"not null" json:"old_symbol"`
+	NewSymbol     string    `gorm:"not null" json:"new_symbol"`
+	EffectiveDate time.Time `gorm:"not null" json:"effective_date"`
+	Pending       bool      `gorm:"default:false;index" json:"pending"` // The stock still trades under OldSymbol
+}
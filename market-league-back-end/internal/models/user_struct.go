@@ -11,5 +11,6 @@ type User struct {
 	Email     string    `json:"email"`                                  // User email
 	Password  string    `gorm:"not null"`                               // Store hashed password (not plaintext)
 	Leagues   []League  `json:"leagues" gorm:"many2many:user_leagues;"` // Many-to-many relation with Leagues
+	IsAdmin   bool      `json:"is_admin" gorm:"default:false"`          // Allowed to run admin operations
 	CreatedAt time.Time `gorm:"autoCreateTime"`                         // Auto-create timestamp
 }
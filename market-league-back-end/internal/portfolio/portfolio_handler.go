@@ -13,6 +13,7 @@ type PortfolioHandlerInterface interface {
 	GetLeaguePortfolio(conn *ws.Connection, rawData json.RawMessage) error
 	GetStocksValueChange(conn *ws.Connection, rawData json.RawMessage) error
 	GetPortfolioPointsHistory(conn *ws.Connection, rawData json.RawMessage) error
+	GetPointsBreakdown(conn *ws.Connection, rawData json.RawMessage) error
 	CreatePortfolio(conn *ws.Connection, rawData json.RawMessage) error
 	AddStockToPortfolio(conn *ws.Connection, rawData json.RawMessage) error
 	RemoveStockFromPortfolio(conn *ws.Connection, rawData json.RawMessage) error
@@ -116,7 +117,7 @@ func (h *PortfolioHandler) GetLeaguePortfolio(conn *ws.Connection, rawData json.
 func (h *PortfolioHandler) CreatePortfolio(conn *ws.Connection, rawData json.RawMessage) error {
 	// Step 1: Parse the WebSocket message
 	var request struct {
-		UserID   uint `json:"user_id"`
+		UserID   uint `json:"user_id"` // Optional: must be the signed-in user
 		LeagueID uint `json:"league_id"`
 	}
 
@@ -125,9 +126,14 @@ func (h *PortfolioHandler) CreatePortfolio(conn *ws.Connection, rawData json.Raw
 		ws.SendError(conn, ws.MessageType_Portfolio_CreatePortfolio, "Invalid input: "+err.Error())
 		return fmt.Errorf("invalid input: %v", err)
 	}
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Portfolio_CreatePortfolio, err.Error())
+		return err
+	}
 
 	// Step 3: Process business logic (reuse the service layer)
-	portfolio, err := h.service.CreatePortfolio(request.UserID, request.LeagueID)
+	portfolio, err := h.service.CreatePortfolio(userID, request.LeagueID)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_Portfolio_CreatePortfolio, err.Error())
 		return fmt.Errorf("failed to create portfolio: %v", err)
@@ -255,6 +261,46 @@ func (h *PortfolioHandler) GetPortfolioPointsHistory(conn *ws.Connection, rawDat
 	return nil
 }
 
+// GetPointsBreakdown explains a points history entry stock by stock so a total can be drilled into
+func (h *PortfolioHandler) GetPointsBreakdown(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		PortfolioID     uint `json:"portfolio_id" binding:"required"`
+		PointsHistoryID uint `json:"points_history_id"` // Optional: defaults to the latest entry
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Portfolio_GetPointsBreakdown, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	report, err := h.service.GetPointsBreakdown(request.PortfolioID, request.PointsHistoryID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Portfolio_GetPointsBreakdown, err.Error())
+		return fmt.Errorf("failed to retrieve points breakdown: %v", err)
+	}
+
+	// Step 4: Marshal the report into JSON
+	reportJSON, err := json.Marshal(report)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Portfolio_GetPointsBreakdown, "Failed to serialize points breakdown")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Portfolio_GetPointsBreakdown,
+		Data: json.RawMessage(reportJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
 // GetStockValueChange implements PortfolioHandlerInterface.
 func (h *PortfolioHandler) GetStocksValueChange(conn *ws.Connection, rawData json.RawMessage) error {
 	// Step 1: Parse the WebSocket message
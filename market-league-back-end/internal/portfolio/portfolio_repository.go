@@ -2,9 +2,9 @@ package portfolio
 
 import (
 	"fmt"
-	"time"
 
 	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -25,6 +25,7 @@ func (r *PortfolioRepository) GetPortfolioWithID(portfolioID uint) (*models.Port
 	err := r.db.
 		Preload("User").
 		Preload("League").
+		Preload("League.RosterPositions").
 		Preload("Stocks").
 		First(&portfolio, portfolioID).Error
 
@@ -52,12 +53,29 @@ func (r *PortfolioRepository) GetAllPortfolios() ([]models.Portfolio, error) {
 	return portfolios, nil
 }
 
-// GetPortfoliosForLeague gets all the portfolios and filters by a league
+// GetScoredPortfolios gets the portfolios of every league that has not completed, the ones scoring runs update.
+// Portfolios whose player left the league are not scored.
+func (r *PortfolioRepository) GetScoredPortfolios() ([]models.Portfolio, error) {
+	var portfolios []models.Portfolio
+	err := r.db.
+		Preload("User").
+		Preload("League").
+		Preload("Stocks").
+		Joins("JOIN leagues ON leagues.id = portfolios.league_id").
+		Where("leagues.league_state != ? AND portfolios.left_at IS NULL", models.Completed).
+		Find(&portfolios).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve portfolios: %v", err)
+	}
+	return portfolios, nil
+}
+
+// GetPortfoliosForLeague gets the portfolios of the players still in a league
 func (r *PortfolioRepository) GetPortfoliosForLeague(leagueID uint) ([]models.Portfolio, error) {
 	var portfolios []models.Portfolio
 	err := r.db.
 		Preload("Stocks").
-		Where("league_id = ?", leagueID).
+		Where("league_id = ? AND left_at IS NULL", leagueID).
 		Find(&portfolios).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve portfolios: %v", err)
@@ -68,7 +86,7 @@ func (r *PortfolioRepository) GetPortfoliosForLeague(leagueID uint) ([]models.Po
 // GetPortfolioIDByUserAndLeague retrieves the portfolio ID for a given user and league.
 func (r *PortfolioRepository) GetPortfolioIDByUserAndLeague(userID, leagueID uint) (uint, error) {
 	var portfolio models.Portfolio
-	err := r.db.Select("id").Where("user_id = ? AND league_id = ?", userID, leagueID).First(&portfolio).Error
+	err := r.db.Select("id").Where("user_id = ? AND league_id = ? AND left_at IS NULL", userID, leagueID).First(&portfolio).Error
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			return 0, fmt.Errorf("portfolio not found for user ID %d in league ID %d", userID, leagueID)
@@ -85,23 +103,19 @@ func (r *PortfolioRepository) CreatePortfolio(portfolio *models.Portfolio) error
 
 // UpdatePortfolio updates an existing portfolio in the database.
 func (r *PortfolioRepository) UpdatePortfolio(portfolio *models.Portfolio) error {
-	// Start a transaction
-	tx := r.db.Begin()
-
-	// Update the basic portfolio fields
-	if err := tx.Save(portfolio).Error; err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update portfolio: %w", err)
-	}
-
-	// Update the Stocks association explicitly
-	if err := tx.Model(portfolio).Association("Stocks").Replace(portfolio.Stocks); err != nil {
-		tx.Rollback()
-		return fmt.Errorf("failed to update stocks for portfolio: %w", err)
-	}
+	// Run in a transaction, nested as a savepoint when the repository is already bound to one
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Update the basic portfolio fields
+		if err := tx.Save(portfolio).Error; err != nil {
+			return fmt.Errorf("failed to update portfolio: %w", err)
+		}
 
-	// Commit the transaction
-	return tx.Commit().Error
+		// Update the Stocks association explicitly
+		if err := tx.Model(portfolio).Association("Stocks").Replace(portfolio.Stocks); err != nil {
+			return fmt.Errorf("failed to update stocks for portfolio: %w", err)
+		}
+		return nil
+	})
 }
 
 // UpdatePortfolioPoints update points of a portfolio specifically
@@ -137,6 +151,15 @@ func (r *PortfolioRepository) LeagueExists(leagueID uint) (bool, error) {
 	return count > 0, nil
 }
 
+// IsMember reports whether a user is in a league
+func (r *PortfolioRepository) IsMember(userID, leagueID uint) (bool, error) {
+	var count int64
+	if err := r.db.Table("user_leagues").Where("league_id = ? AND user_id = ?", leagueID, userID).Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
 func (r *PortfolioRepository) GetPortfolioPointsHistoryEntry(portfolioID uint) ([]models.PortfolioPointsHistory, error) {
 	var history []models.PortfolioPointsHistory
 	err := r.db.
@@ -149,16 +172,72 @@ func (r *PortfolioRepository) GetPortfolioPointsHistoryEntry(portfolioID uint) (
 	return history, nil
 }
 
-func (r *PortfolioRepository) LogPortfolioPointsChange(portfolioID uint, newPoints int) error {
+// LogPortfolioPointsChange records the new points of a portfolio along with the per-stock breakdown behind them.
+// An entry logged earlier with the same run key is replaced, an empty run key always adds an entry.
+func (r *PortfolioRepository) LogPortfolioPointsChange(portfolioID uint, newPoints int, breakdown []models.PointsBreakdown, runKey string) error {
+	recordedAt := utils.Now()
+	for index := range breakdown {
+		breakdown[index].RecordedAt = recordedAt
+	}
 	historyEntry := models.PortfolioPointsHistory{
 		PortfolioID: portfolioID,
 		Points:      newPoints,
-		RecordedAt:  time.Now(),
+		RecordedAt:  recordedAt,
+		Breakdown:   breakdown,
+	}
+	if runKey != "" {
+		key := fmt.Sprintf("%s:%d", runKey, portfolioID)
+		historyEntry.RunKey = &key
 	}
 
-	err := r.db.Create(&historyEntry).Error
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if historyEntry.RunKey != nil {
+			var existing []models.PortfolioPointsHistory
+			if err := tx.Where("run_key = ?", *historyEntry.RunKey).Limit(1).Find(&existing).Error; err != nil {
+				return err
+			}
+			if len(existing) > 0 {
+				if err := tx.Where("points_history_id = ?", existing[0].ID).Delete(&models.PointsBreakdown{}).Error; err != nil {
+					return err
+				}
+				historyEntry.ID = existing[0].ID
+				return tx.Save(&historyEntry).Error
+			}
+		}
+		return tx.Create(&historyEntry).Error
+	})
 	if err != nil {
 		return fmt.Errorf("failed to log portfolio points change: %v", err)
 	}
 	return nil
 }
+
+// GetPointsHistoryWithBreakdown gets a points history entry of a portfolio and its breakdown, or the latest entry when pointsHistoryID is 0
+func (r *PortfolioRepository) GetPointsHistoryWithBreakdown(portfolioID uint, pointsHistoryID uint) (*models.PortfolioPointsHistory, error) {
+	var entry models.PortfolioPointsHistory
+	query := r.db.Preload("Breakdown").Preload("Breakdown.Stock").Where("portfolio_id = ?", portfolioID)
+	if pointsHistoryID != 0 {
+		query = query.Where("id = ?", pointsHistoryID)
+	}
+	if err := query.Order("recorded_at DESC, id DESC").First(&entry).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve portfolio points history entry: %w", err)
+	}
+	return &entry, nil
+}
+
+// GetPreviousPointsHistoryWithBreakdown gets the points history entry recorded just before the given one, or nil if it is the first
+func (r *PortfolioRepository) GetPreviousPointsHistoryWithBreakdown(entry *models.PortfolioPointsHistory) (*models.PortfolioPointsHistory, error) {
+	var previous []models.PortfolioPointsHistory
+	err := r.db.Preload("Breakdown").Preload("Breakdown.Stock").
+		Where("portfolio_id = ? AND (recorded_at < ? OR (recorded_at = ? AND id < ?))", entry.PortfolioID, entry.RecordedAt, entry.RecordedAt, entry.ID).
+		Order("recorded_at DESC, id DESC").
+		Limit(1).
+		Find(&previous).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve previous portfolio points history entry: %w", err)
+	}
+	if len(previous) == 0 {
+		return nil, nil
+	}
+	return &previous[0], nil
+}
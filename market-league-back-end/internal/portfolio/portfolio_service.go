@@ -4,24 +4,32 @@ import (
 	"fmt"
 	"math"
 
+	"github.com/market-league/internal/lineup"
 	"github.com/market-league/internal/models"
 	ownership_history "github.com/market-league/internal/ownership_history"
+	"github.com/market-league/internal/roster"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"gorm.io/gorm"
 )
 
 // PortfolioService handles business logic related to portfolios.
 type PortfolioService struct {
 	repo                 *PortfolioRepository
 	ownershipHistoryRepo ownership_history.OwnershipHistoryRepositoryInterface
+	lineupRepo           lineup.LineupRepositoryInterface
 }
 
 // NewPortfolioService creates a new instance of PortfolioService.
 func NewPortfolioService(
 	repo *PortfolioRepository,
 	ownershipHistoryRepo ownership_history.OwnershipHistoryRepositoryInterface,
+	lineupRepo lineup.LineupRepositoryInterface,
 ) *PortfolioService {
 	return &PortfolioService{
 		repo:                 repo,
 		ownershipHistoryRepo: ownershipHistoryRepo,
+		lineupRepo:           lineupRepo,
 	}
 }
 
@@ -49,6 +57,7 @@ func (s *PortfolioService) GetPortfolioWithID(portfolioID uint) (*models.Sanitiz
 			ID:           stock.ID,
 			TickerSymbol: stock.TickerSymbol,
 			CompanyName:  stock.CompanyName,
+			Sector:       stock.Sector,
 			CurrentPrice: stock.CurrentPrice,
 		})
 	}
@@ -88,6 +97,15 @@ func (s *PortfolioService) CreatePortfolio(userID, leagueID uint) (*models.Portf
 		return nil, fmt.Errorf("league with ID %d not found", leagueID)
 	}
 
+	// Step 2a: Only members get a portfolio, joining goes through an invite, a join request or the owner
+	member, err := s.repo.IsMember(userID, leagueID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check league membership: %v", err)
+	}
+	if !member {
+		return nil, fmt.Errorf("user %d is not a member of league %d", userID, leagueID)
+	}
+
 	// Step 3: Check if the user already has a portfolio in the league
 	existingPortfolio, err := s.repo.GetPortfolioIDByUserAndLeague(userID, leagueID)
 	if err == nil && existingPortfolio != 0 {
@@ -117,6 +135,9 @@ func (s *PortfolioService) AddStockToPortfolio(portfolioID, stockID uint) error
 	if err != nil {
 		return fmt.Errorf("failed to fetch portfolio: %v", err)
 	}
+	if err := roster.CheckUnlocked(&portfolio.League); err != nil {
+		return err
+	}
 
 	// Check if the stock is already in the portfolio
 	for _, s := range portfolio.Stocks {
@@ -132,15 +153,24 @@ func (s *PortfolioService) AddStockToPortfolio(portfolioID, stockID uint) error
 		return fmt.Errorf("failed to fetch stock: %v", err)
 	}
 
+	// Make sure the roster can still fill its sector positions
+	if err := roster.ValidateRoster(&portfolio.League, roster.ApplyMove(portfolio.Stocks, nil, []models.Stock{*stock})); err != nil {
+		return err
+	}
+
 	// Add the stock to the portfolio
 	portfolio.Stocks = append(portfolio.Stocks, *stock)
 
-	// Update the portfolio in the repository
-	if err := s.repo.UpdatePortfolio(portfolio); err != nil {
-		return fmt.Errorf("failed to update portfolio: %v", err)
-	}
-
-	return nil
+	// Update the portfolio and start the stock if the lineup has an open slot, together
+	return s.updateRoster(func(repo *PortfolioRepository, lineupService lineup.LineupServiceInterface) error {
+		if err := repo.UpdatePortfolio(portfolio); err != nil {
+			return fmt.Errorf("failed to update portfolio: %v", err)
+		}
+		if err := lineupService.AssignStock(portfolioID, stockID, stock.CurrentPrice, utils.Now()); err != nil {
+			return fmt.Errorf("failed to assign stock to the lineup: %v", err)
+		}
+		return nil
+	})
 }
 
 // RemoveStockFromPortfolio removes a stock from the user's portfolio.
@@ -150,44 +180,58 @@ func (s *PortfolioService) RemoveStockFromPortfolio(portfolioID, stockID uint) e
 	if err != nil {
 		return fmt.Errorf("failed to fetch portfolio: %v", err)
 	}
+	if err := roster.CheckUnlocked(&portfolio.League); err != nil {
+		return err
+	}
 
 	// Check if the stock exists in the portfolio
-	var stockFound bool
+	var removedStock *models.Stock
 	var updatedStocks []models.Stock
-	for _, s := range portfolio.Stocks {
+	for i, s := range portfolio.Stocks {
 		if s.ID == stockID {
-			stockFound = true
+			removedStock = &portfolio.Stocks[i]
 			continue // Skip adding this stock to the updated list
 		}
 		updatedStocks = append(updatedStocks, s)
 	}
 
-	if !stockFound {
+	if removedStock == nil {
 		return fmt.Errorf("stock with ID %d is not in the portfolio", stockID)
 	}
 
+	// Make sure the roster can still fill its sector positions
+	if err := roster.ValidateRoster(&portfolio.League, updatedStocks); err != nil {
+		return err
+	}
+
 	// Update the portfolio's stocks
 	portfolio.Stocks = updatedStocks
 
-	// Update the portfolio in the repository
-	if err := s.repo.UpdatePortfolio(portfolio); err != nil {
-		return fmt.Errorf("failed to update portfolio: %v", err)
-	}
-
-	return nil
+	// Update the portfolio and close the stock's scoring window if it was starting, together
+	return s.updateRoster(func(repo *PortfolioRepository, lineupService lineup.LineupServiceInterface) error {
+		if err := lineupService.ReleaseStock(portfolioID, stockID, removedStock.CurrentPrice, utils.Now()); err != nil {
+			return fmt.Errorf("failed to release stock from the lineup: %v", err)
+		}
+		if err := repo.UpdatePortfolio(portfolio); err != nil {
+			return fmt.Errorf("failed to update portfolio: %v", err)
+		}
+		return nil
+	})
 }
 
-// CalculateAllPortfolioTotalValues calculates the value of every portfolio
-func (s *PortfolioService) CalculateAllPortfolioTotalValues() error {
+// CalculateAllPortfolioTotalValues calculates the value of every portfolio in a league that has not completed,
+// completed leagues keep their final points. Points history entries are keyed by runKey, so calculating again
+// with the same key replaces them instead of adding more.
+func (s *PortfolioService) CalculateAllPortfolioTotalValues(runKey string) error {
 	// Get all portfolios to update
-	allPortfolios, err := s.repo.GetAllPortfolios()
+	allPortfolios, err := s.repo.GetScoredPortfolios()
 	if err != nil {
 		return fmt.Errorf("unable to load all portfolios: %v", err)
 	}
 	for index := range allPortfolios {
 		// update each portfolio's value
 		portfolio := allPortfolios[index]
-		err := s.CalculatePortfolioTotalValue(&portfolio)
+		err := s.calculatePortfolioTotalValue(&portfolio, runKey)
 		if err != nil {
 			return fmt.Errorf("unable to calculate portfolio total value %v", err)
 		}
@@ -196,28 +240,60 @@ func (s *PortfolioService) CalculateAllPortfolioTotalValues() error {
 	return nil
 }
 
+// CalculateLeaguePortfolioTotalValues calculates the value of every portfolio in one league, keyed by runKey
+// like CalculateAllPortfolioTotalValues
+func (s *PortfolioService) CalculateLeaguePortfolioTotalValues(leagueID uint, runKey string) error {
+	portfolios, err := s.repo.GetPortfoliosForLeague(leagueID)
+	if err != nil {
+		return fmt.Errorf("unable to load portfolios of league %d: %v", leagueID, err)
+	}
+	for index := range portfolios {
+		if err := s.calculatePortfolioTotalValue(&portfolios[index], runKey); err != nil {
+			return fmt.Errorf("unable to calculate portfolio total value %v", err)
+		}
+	}
+	return nil
+}
+
 // CalculateTotalValue calculates the total value of the portfolio based on its stocks.
+// Only the time a stock spent in the starting lineup counts, so points come from scoring windows
+// rather than from plain ownership history.
 func (s *PortfolioService) CalculatePortfolioTotalValue(portfolio *models.Portfolio) error {
+	return s.calculatePortfolioTotalValue(portfolio, "")
+}
+
+// calculatePortfolioTotalValue calculates the value of a portfolio, replacing the points history entry of an earlier
+// calculation with the same run key
+func (s *PortfolioService) calculatePortfolioTotalValue(portfolio *models.Portfolio, runKey string) error {
 	totalPercentChangeForPortfolio := 0.0
-	// Get percent change of each ownership_history
+	var breakdown []models.PointsBreakdown
+	// Get percent change of each scoring window
 	for index := range portfolio.Stocks {
 		stock := portfolio.Stocks[index]
-		ownershipHistoryList, err := s.ownershipHistoryRepo.GetAllStockHistoryByStockIDAndPortfolioID(stock.ID, portfolio.ID)
+		scoringWindowList, err := s.lineupRepo.GetAllWindowsByStockIDAndPortfolioID(stock.ID, portfolio.ID)
 		if err != nil {
-			return fmt.Errorf("unable to retrieve ownership history with stockID and portfolioID: %v", err)
+			return fmt.Errorf("unable to retrieve scoring windows with stockID and portfolioID: %v", err)
 		}
 		totalPercentageChangeForStock := 0.0
-		for index := range ownershipHistoryList {
-			ownershipHistoryItem := ownershipHistoryList[index]
-			currentVal := ownershipHistoryItem.CurrentValue
-
-			previousVal := ownershipHistoryItem.StartingValue
-			// Check for 0 and replace with 1 to avoid infinity
-			if previousVal == 0 {
-				previousVal = 1
-			}
+		for index := range scoringWindowList {
+			scoringWindowItem := scoringWindowList[index]
+			// Dividends paid while starting count towards the return
+			currentVal := scoringWindowItem.CurrentValue + scoringWindowItem.Dividends
+
 			// Calculate the percentage change and use that in the point scoring system
-			percentChangeForItem := ((currentVal - previousVal) / math.Abs(previousVal)) * 100
+			percentChangeForItem := utils.PercentChange(scoringWindowItem.StartingValue, currentVal)
+			breakdown = append(breakdown, models.PointsBreakdown{
+				PortfolioID:     portfolio.ID,
+				ScoringWindowID: scoringWindowItem.ID,
+				StockID:         stock.ID,
+				WindowStart:     scoringWindowItem.StartDate,
+				WindowEnd:       scoringWindowItem.EndDate,
+				StartPrice:      scoringWindowItem.StartingValue,
+				EndPrice:        scoringWindowItem.CurrentValue,
+				Dividends:       scoringWindowItem.Dividends,
+				PercentChange:   percentChangeForItem,
+				Points:          percentChangeForItem,
+			})
 			totalPercentageChangeForStock = totalPercentageChangeForStock + percentChangeForItem
 		}
 		totalPercentChangeForPortfolio = totalPercentChangeForPortfolio + totalPercentageChangeForStock
@@ -228,7 +304,7 @@ func (s *PortfolioService) CalculatePortfolioTotalValue(portfolio *models.Portfo
 	if err != nil {
 		return fmt.Errorf("unable to update portfolio points: %v", err)
 	}
-	err = s.repo.LogPortfolioPointsChange(portfolio.ID, portfolioValue)
+	err = s.repo.LogPortfolioPointsChange(portfolio.ID, portfolioValue, breakdown, runKey)
 	if err != nil {
 		return fmt.Errorf("unable to log portfolio points change %v", err)
 	}
@@ -246,6 +322,74 @@ func (s *PortfolioService) GetPortfolioPointsHistory(portfolioID uint) ([]models
 	return portfolioPointsHistoryEntries, nil
 }
 
+// GetPointsBreakdown explains a points history entry stock by stock, and what changed since the entry before it.
+// A pointsHistoryID of 0 explains the latest entry.
+func (s *PortfolioService) GetPointsBreakdown(portfolioID uint, pointsHistoryID uint) (*models.PointsBreakdownReport, error) {
+	entry, err := s.repo.GetPointsHistoryWithBreakdown(portfolioID, pointsHistoryID)
+	if err != nil {
+		return nil, err
+	}
+	previous, err := s.repo.GetPreviousPointsHistoryWithBreakdown(entry)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &models.PointsBreakdownReport{
+		PortfolioID:     portfolioID,
+		PointsHistoryID: entry.ID,
+		RecordedAt:      entry.RecordedAt,
+		Points:          entry.Points,
+		PointsChange:    entry.Points,
+		Components:      []models.PointsComponent{},
+	}
+
+	// Match components to the previous entry by the scoring window they came from
+	previousByWindow := make(map[uint]models.PointsBreakdown)
+	if previous != nil {
+		report.PreviousPoints = &previous.Points
+		report.PointsChange = entry.Points - previous.Points
+		for _, item := range previous.Breakdown {
+			previousByWindow[item.ScoringWindowID] = item
+		}
+	}
+
+	for _, item := range entry.Breakdown {
+		component := models.PointsComponent{
+			PointsBreakdown: item,
+			TickerSymbol:    item.Stock.TickerSymbol,
+			CompanyName:     item.Stock.CompanyName,
+			PointsChange:    item.Points,
+		}
+		if previousItem, ok := previousByWindow[item.ScoringWindowID]; ok {
+			component.PreviousPoints = previousItem.Points
+			component.PointsChange = item.Points - previousItem.Points
+			delete(previousByWindow, item.ScoringWindowID)
+		}
+		report.Components = append(report.Components, component)
+	}
+
+	// Windows that no longer score take their points with them
+	if previous != nil {
+		for _, item := range previous.Breakdown {
+			if _, ok := previousByWindow[item.ScoringWindowID]; !ok {
+				continue
+			}
+			dropped := item
+			dropped.Points = 0
+			report.Components = append(report.Components, models.PointsComponent{
+				PointsBreakdown: dropped,
+				TickerSymbol:    item.Stock.TickerSymbol,
+				CompanyName:     item.Stock.CompanyName,
+				PreviousPoints:  item.Points,
+				PointsChange:    -item.Points,
+				Dropped:         true,
+			})
+		}
+	}
+
+	return report, nil
+}
+
 // GetStocksValueChange
 func (s *PortfolioService) GetStocksValueChange(portfolioID uint) ([]*models.OwnershipHistory, error) {
 	var stocksAndHistory []*models.OwnershipHistory
@@ -265,3 +409,11 @@ func (s *PortfolioService) GetStocksValueChange(portfolioID uint) ([]*models.Own
 
 	return stocksAndHistory, nil
 }
+
+// updateRoster runs a roster change with a repository and lineup service bound to one transaction,
+// so a portfolio's stocks and its scoring windows never disagree
+func (s *PortfolioService) updateRoster(fn func(repo *PortfolioRepository, lineupService lineup.LineupServiceInterface) error) error {
+	return s.repo.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewPortfolioRepository(tx), lineup.NewLineupService(lineup.NewLineupRepository(tx), stock.NewStockRepository(tx)))
+	})
+}
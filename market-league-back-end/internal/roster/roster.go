@@ -0,0 +1,104 @@
+package roster
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/market-league/internal/models"
+)
+
+// CheckUnlocked returns an error once a league's rosters are locked and no stock can move in or out of a roster
+func CheckUnlocked(league *models.League) error {
+	if league.RostersLockedAt != nil {
+		return fmt.Errorf("rosters locked at %s", league.RostersLockedAt.Format("2006-01-02 15:04 MST"))
+	}
+	return nil
+}
+
+// ValidatePositions checks that a league's sector requirements fit in its roster.
+func ValidatePositions(positions []models.RosterPosition, rosterSize int) error {
+	total := 0
+	for _, position := range positions {
+		if strings.TrimSpace(position.Sector) == "" {
+			return fmt.Errorf("roster positions need a sector")
+		}
+		if position.Count < 1 {
+			return fmt.Errorf("roster position %s needs a count of at least 1", position.Sector)
+		}
+		total += position.Count
+	}
+	if total > rosterSize {
+		return fmt.Errorf("roster positions need %d stocks but rosters only hold %d", total, rosterSize)
+	}
+	return nil
+}
+
+// ValidateRoster checks that a roster of stocks can still fill every required sector position of its league.
+// The roster may be partial, as during a draft, in which case the open slots must be enough to cover what is missing.
+func ValidateRoster(league *models.League, stocks []models.Stock) error {
+	missing := MissingPositions(league.RosterPositions, stocks)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	missingCount := 0
+	for _, position := range missing {
+		missingCount += position.Count
+	}
+
+	openSlots := league.RosterSize() - len(stocks)
+	if missingCount <= openSlots {
+		return nil
+	}
+
+	var names []string
+	for _, position := range missing {
+		names = append(names, fmt.Sprintf("%d %s", position.Count, position.Sector))
+	}
+	return fmt.Errorf("roster would be unable to fill its required positions: missing %s", strings.Join(names, ", "))
+}
+
+// MissingPositions returns the sector positions a roster has not filled yet.
+// Flex positions are never missing since any stock can fill them.
+func MissingPositions(positions []models.RosterPosition, stocks []models.Stock) []models.RosterPosition {
+	sectorCounts := make(map[string]int)
+	for _, stock := range stocks {
+		sectorCounts[strings.ToLower(stock.Sector)]++
+	}
+
+	var missing []models.RosterPosition
+	for _, position := range positions {
+		if strings.EqualFold(position.Sector, models.FlexPosition) {
+			continue
+		}
+		sector := strings.ToLower(position.Sector)
+		have := sectorCounts[sector]
+		if have >= position.Count {
+			sectorCounts[sector] -= position.Count
+			continue
+		}
+		sectorCounts[sector] = 0
+		missing = append(missing, models.RosterPosition{
+			LeagueID: position.LeagueID,
+			Sector:   position.Sector,
+			Count:    position.Count - have,
+		})
+	}
+	return missing
+}
+
+// ApplyMove returns the stocks of a roster after removing and adding the given stocks.
+func ApplyMove(stocks []models.Stock, removed []models.Stock, added []models.Stock) []models.Stock {
+	removedIDs := make(map[uint]bool, len(removed))
+	for _, stock := range removed {
+		removedIDs[stock.ID] = true
+	}
+
+	result := make([]models.Stock, 0, len(stocks)+len(added))
+	for _, stock := range stocks {
+		if !removedIDs[stock.ID] {
+			result = append(result, stock)
+		}
+	}
+	return append(result, added...)
+}
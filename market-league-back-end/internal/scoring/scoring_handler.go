@@ -0,0 +1,89 @@
+package scoring
+
+import (
+	"encoding/json"
+	"fmt"
+
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/user"
+)
+
+// ScoringHandler Interface
+type ScoringHandlerInterface interface {
+	RecomputeScores(conn *ws.Connection, rawData json.RawMessage) error
+}
+
+// Compile-time check
+var _ ScoringHandlerInterface = (*ScoringHandler)(nil)
+
+// ScoringHandler defines the handler for admin scoring operations.
+type ScoringHandler struct {
+	service  ScoringServiceInterface
+	userRepo *user.UserRepository
+}
+
+// NewScoringHandler creates a new instance of ScoringHandler.
+func NewScoringHandler(service ScoringServiceInterface, userRepo *user.UserRepository) *ScoringHandler {
+	return &ScoringHandler{service: service, userRepo: userRepo}
+}
+
+// * Implementation of Interface
+
+// RecomputeScores handles an admin rebuilding the points history of a league over a date range.
+func (h *ScoringHandler) RecomputeScores(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint   `json:"user_id"` // Optional: must be the signed-in user
+		LeagueID uint   `json:"league_id" binding:"required"`
+		From     string `json:"from" binding:"required"` // YYYY-MM-DD
+		To       string `json:"to" binding:"required"`   // YYYY-MM-DD
+		DryRun   bool   `json:"dry_run"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_RecomputeScores, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Only a signed-in admin may rewrite scores
+	userID, err := conn.User(request.UserID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_RecomputeScores, err.Error())
+		return err
+	}
+	isAdmin, err := h.userRepo.IsAdmin(userID)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_RecomputeScores, err.Error())
+		return fmt.Errorf("failed to check admin status: %v", err)
+	}
+	if !isAdmin {
+		ws.SendError(conn, ws.MessageType_Admin_RecomputeScores, "Admin access required")
+		return fmt.Errorf("user %d is not an admin", userID)
+	}
+
+	// Step 4: Process business logic (reuse the service layer)
+	result, err := h.service.Recompute(request.LeagueID, request.From, request.To, request.DryRun)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_RecomputeScores, err.Error())
+		return fmt.Errorf("failed to recompute scores: %v", err)
+	}
+
+	// Step 5: Marshal the result into JSON
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_RecomputeScores, "Failed to serialize recompute result")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 6: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Admin_RecomputeScores,
+		Data: json.RawMessage(resultJSON), // Use marshaled JSON bytes
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,166 @@
+package scoring
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"gorm.io/gorm"
+)
+
+// ScoringRepositoryInterface defines the interface for the database reads and writes behind score recomputation
+type ScoringRepositoryInterface interface {
+	GetLeague(leagueID uint) (*models.League, error)
+	GetPortfoliosByLeagueID(leagueID uint) ([]models.Portfolio, error)
+	GetOwnershipHistory(portfolioIDs []uint) ([]models.OwnershipHistory, error)
+	GetScoringWindows(portfolioIDs []uint) ([]models.ScoringWindow, error)
+	GetPriceHistory(stockIDs []uint, until time.Time) ([]models.PriceHistory, error)
+	GetCorporateActions(stockIDs []uint) ([]models.CorporateAction, error)
+	GetPointsHistory(portfolioIDs []uint, from time.Time, to time.Time) ([]models.PortfolioPointsHistory, error)
+	ReplacePointsHistory(portfolioIDs []uint, from time.Time, to time.Time, entries []models.PortfolioPointsHistory) error
+}
+
+// scoringRepository implements ScoringRepositoryInterface
+type scoringRepository struct {
+	db *gorm.DB
+}
+
+// NewScoringRepository creates a new repository
+func NewScoringRepository(db *gorm.DB) ScoringRepositoryInterface {
+	return &scoringRepository{db: db}
+}
+
+// GetLeague fetches a league by ID
+func (r *scoringRepository) GetLeague(leagueID uint) (*models.League, error) {
+	var league models.League
+	if err := r.db.First(&league, leagueID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find league with ID %d: %w", leagueID, err)
+	}
+	return &league, nil
+}
+
+// GetPortfoliosByLeagueID gets every portfolio in a league
+func (r *scoringRepository) GetPortfoliosByLeagueID(leagueID uint) ([]models.Portfolio, error) {
+	var portfolios []models.Portfolio
+	if err := r.db.Where("league_id = ?", leagueID).Order("id ASC").Find(&portfolios).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve portfolios for league %d: %w", leagueID, err)
+	}
+	return portfolios, nil
+}
+
+// GetOwnershipHistory gets every ownership window, open or closed, of the given portfolios
+func (r *scoringRepository) GetOwnershipHistory(portfolioIDs []uint) ([]models.OwnershipHistory, error) {
+	var history []models.OwnershipHistory
+	err := r.db.Where("portfolio_id IN ?", portfolioIDs).Order("id ASC").Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ownership history: %w", err)
+	}
+	return history, nil
+}
+
+// GetScoringWindows gets every scoring window, open or closed, of the given portfolios
+func (r *scoringRepository) GetScoringWindows(portfolioIDs []uint) ([]models.ScoringWindow, error) {
+	var windows []models.ScoringWindow
+	err := r.db.Where("portfolio_id IN ?", portfolioIDs).Order("id ASC").Find(&windows).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve scoring windows: %w", err)
+	}
+	return windows, nil
+}
+
+// GetPriceHistory gets the recorded prices of the given stocks up to a time, oldest first. Days whose
+// prices were compacted contribute their open and close.
+func (r *scoringRepository) GetPriceHistory(stockIDs []uint, until time.Time) ([]models.PriceHistory, error) {
+	var history []models.PriceHistory
+	err := r.db.
+		Where("stock_id IN ? AND timestamp <= ?", stockIDs, until).
+		Order("stock_id ASC, timestamp ASC, id ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve price history: %w", err)
+	}
+
+	var bars []models.DailyPriceBar
+	if err := r.db.Where("stock_id IN ? AND open_at <= ?", stockIDs, until).Find(&bars).Error; err != nil {
+		return nil, fmt.Errorf("failed to retrieve daily price bars: %w", err)
+	}
+	if len(bars) == 0 {
+		return history, nil
+	}
+	for _, bar := range bars {
+		for _, point := range bar.AsPriceHistory() {
+			if !point.Timestamp.After(until) {
+				history = append(history, point)
+			}
+		}
+	}
+	sort.SliceStable(history, func(i, j int) bool {
+		if history[i].StockID != history[j].StockID {
+			return history[i].StockID < history[j].StockID
+		}
+		return history[i].Timestamp.Before(history[j].Timestamp)
+	})
+	return history, nil
+}
+
+// GetCorporateActions gets the dividends and splits of the given stocks, oldest first
+func (r *scoringRepository) GetCorporateActions(stockIDs []uint) ([]models.CorporateAction, error) {
+	var actions []models.CorporateAction
+	err := r.db.Where("stock_id IN ?", stockIDs).Order("ex_date ASC").Find(&actions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve corporate actions: %w", err)
+	}
+	return actions, nil
+}
+
+// GetPointsHistory gets the points history of the given portfolios recorded in [from, to), oldest first
+func (r *scoringRepository) GetPointsHistory(portfolioIDs []uint, from time.Time, to time.Time) ([]models.PortfolioPointsHistory, error) {
+	var history []models.PortfolioPointsHistory
+	err := r.db.
+		Where("portfolio_id IN ? AND recorded_at >= ? AND recorded_at < ?", portfolioIDs, from, to).
+		Order("recorded_at ASC, id ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve portfolio points history: %w", err)
+	}
+	return history, nil
+}
+
+// ReplacePointsHistory swaps the points history recorded in [from, to) and its breakdowns for the given
+// entries and resets each portfolio's points to its latest entry, in a transaction
+func (r *scoringRepository) ReplacePointsHistory(portfolioIDs []uint, from time.Time, to time.Time, entries []models.PortfolioPointsHistory) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.
+			Where("points_history_id IN (?)", tx.Model(&models.PortfolioPointsHistory{}).
+				Select("id").
+				Where("portfolio_id IN ? AND recorded_at >= ? AND recorded_at < ?", portfolioIDs, from, to)).
+			Delete(&models.PointsBreakdown{}).Error; err != nil {
+			return fmt.Errorf("failed to clear points breakdowns: %w", err)
+		}
+
+		if err := tx.
+			Where("portfolio_id IN ? AND recorded_at >= ? AND recorded_at < ?", portfolioIDs, from, to).
+			Delete(&models.PortfolioPointsHistory{}).Error; err != nil {
+			return fmt.Errorf("failed to clear portfolio points history: %w", err)
+		}
+
+		if len(entries) > 0 {
+			if err := tx.Create(&entries).Error; err != nil {
+				return fmt.Errorf("failed to write portfolio points history: %w", err)
+			}
+		}
+
+		for _, portfolioID := range portfolioIDs {
+			var latest models.PortfolioPointsHistory
+			err := tx.Where("portfolio_id = ?", portfolioID).Order("recorded_at DESC, id DESC").Limit(1).Find(&latest).Error
+			if err != nil {
+				return fmt.Errorf("failed to read latest points of portfolio %d: %w", portfolioID, err)
+			}
+			if err := tx.Model(&models.Portfolio{}).Where("id = ?", portfolioID).Update("points", latest.Points).Error; err != nil {
+				return fmt.Errorf("failed to update points of portfolio %d: %w", portfolioID, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,389 @@
+package scoring
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// RecomputeDateFormat is the format of the dates bounding a recompute
+const RecomputeDateFormat = "2006-01-02"
+
+// PointsChange is one day of a portfolio's points history that a recompute adds, changes or removes.
+// A nil OldPoints means no entry was recorded that day, a nil NewPoints means the entry is removed.
+type PointsChange struct {
+	PortfolioID uint   `json:"portfolio_id"`
+	Date        string `json:"date"`
+	OldPoints   *int   `json:"old_points"`
+	NewPoints   *int   `json:"new_points"`
+}
+
+// RecomputeResult summarizes a recompute of a league over a date range
+type RecomputeResult struct {
+	LeagueID uint           `json:"league_id"`
+	From     string         `json:"from"`
+	To       string         `json:"to"`
+	DryRun   bool           `json:"dry_run"`
+	Days     int            `json:"days"`
+	Changes  []PointsChange `json:"changes"`
+}
+
+// ScoringServiceInterface defines the methods for rebuilding portfolio points
+type ScoringServiceInterface interface {
+	Recompute(leagueID uint, from string, to string, dryRun bool) (*RecomputeResult, error)
+}
+
+// Compile-time check
+var _ ScoringServiceInterface = (*ScoringService)(nil)
+
+// ScoringService implements ScoringServiceInterface
+type ScoringService struct {
+	repo ScoringRepositoryInterface
+}
+
+// NewScoringService creates a new service
+func NewScoringService(repo ScoringRepositoryInterface) *ScoringService {
+	return &ScoringService{repo: repo}
+}
+
+// * Implementation of Interface
+
+// Recompute replays the ownership windows of a league against the recorded prices and rebuilds the
+// points history one snapshot per trading day in [from, to]. On each day a stock the portfolio owns
+// scores every starting-lineup slice of its ownership, its scoring windows. The result only depends
+// on the stored windows, prices and corporate actions, so running it twice changes nothing.
+// Days after the league's end date are left alone, and a completed league keeps its final points.
+// With dryRun set, the changes are reported but not written.
+func (s *ScoringService) Recompute(leagueID uint, from string, to string, dryRun bool) (*RecomputeResult, error) {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load market timezone: %v", err)
+	}
+
+	fromDate, err := time.ParseInLocation(RecomputeDateFormat, from, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid from date %q, expected %s", from, RecomputeDateFormat)
+	}
+	toDate, err := time.ParseInLocation(RecomputeDateFormat, to, location)
+	if err != nil {
+		return nil, fmt.Errorf("invalid to date %q, expected %s", to, RecomputeDateFormat)
+	}
+	if toDate.Before(fromDate) {
+		return nil, errors.New("from date must not be after to date")
+	}
+
+	league, err := s.repo.GetLeague(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	// A completed league keeps the final points it was given
+	if league.LeagueState == models.Completed {
+		return nil, fmt.Errorf("league %d has completed, its final points are not recomputed", leagueID)
+	}
+
+	// Nothing is scored before the league starts, after it ends or after today's run
+	leagueStart := startOfDay(league.StartDate.In(location))
+	if fromDate.Before(leagueStart) {
+		fromDate = leagueStart
+	}
+	now := utils.Now().In(location)
+	lastRun := startOfDay(now)
+	if now.Before(snapshotTime(lastRun)) {
+		lastRun = lastRun.AddDate(0, 0, -1)
+	}
+	if toDate.After(lastRun) {
+		toDate = lastRun
+	}
+	if !league.EndDate.IsZero() {
+		// Holdings close at the end date, so the last day scored is the last snapshot taken before it
+		endDate := league.EndDate.In(location)
+		lastScored := startOfDay(endDate)
+		if endDate.Before(snapshotTime(lastScored)) {
+			lastScored = lastScored.AddDate(0, 0, -1)
+		}
+		if toDate.After(lastScored) {
+			toDate = lastScored
+		}
+	}
+	if toDate.Before(fromDate) {
+		return nil, fmt.Errorf("league %d has no scored days between %s and %s", leagueID, from, to)
+	}
+
+	result := &RecomputeResult{
+		LeagueID: leagueID,
+		From:     fromDate.Format(RecomputeDateFormat),
+		To:       toDate.Format(RecomputeDateFormat),
+		DryRun:   dryRun,
+		Changes:  []PointsChange{},
+	}
+
+	portfolios, err := s.repo.GetPortfoliosByLeagueID(leagueID)
+	if err != nil {
+		return nil, err
+	}
+	if len(portfolios) == 0 {
+		return result, nil
+	}
+	portfolioIDs := make([]uint, len(portfolios))
+	for i := range portfolios {
+		portfolioIDs[i] = portfolios[i].ID
+	}
+
+	replay, err := s.loadReplay(portfolioIDs, snapshotTime(toDate))
+	if err != nil {
+		return nil, err
+	}
+
+	// Rebuild one snapshot per portfolio per trading day
+	var entries []models.PortfolioPointsHistory
+	for day := fromDate; !day.After(toDate); day = day.AddDate(0, 0, 1) {
+		if !utils.IsTradingDay(day) {
+			continue
+		}
+		result.Days++
+		at := snapshotTime(day)
+		for _, portfolioID := range portfolioIDs {
+			points, breakdown := replay.scoreAt(portfolioID, at)
+			entries = append(entries, models.PortfolioPointsHistory{
+				PortfolioID: portfolioID,
+				Points:      points,
+				RecordedAt:  at,
+				Breakdown:   breakdown,
+			})
+		}
+	}
+
+	// Diff against what is recorded now, keyed by portfolio and calendar day
+	rangeEnd := toDate.AddDate(0, 0, 1)
+	existing, err := s.repo.GetPointsHistory(portfolioIDs, fromDate, rangeEnd)
+	if err != nil {
+		return nil, err
+	}
+	result.Changes = diffPointsHistory(existing, entries, location)
+
+	if dryRun {
+		return result, nil
+	}
+	if err := s.repo.ReplacePointsHistory(portfolioIDs, fromDate, rangeEnd, entries); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// * Helpers
+
+// replay holds everything needed to score a league at any moment without touching the database
+type replay struct {
+	owned   map[uint][]models.OwnershipHistory // by portfolio ID
+	windows map[uint][]models.ScoringWindow    // by portfolio ID
+	prices  map[uint][]models.PriceHistory     // by stock ID, oldest first
+	actions map[uint][]models.CorporateAction
+}
+
+func (s *ScoringService) loadReplay(portfolioIDs []uint, until time.Time) (*replay, error) {
+	owned, err := s.repo.GetOwnershipHistory(portfolioIDs)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.repo.GetScoringWindows(portfolioIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replay{
+		owned:   make(map[uint][]models.OwnershipHistory),
+		windows: make(map[uint][]models.ScoringWindow),
+		prices:  make(map[uint][]models.PriceHistory),
+		actions: make(map[uint][]models.CorporateAction),
+	}
+	for _, ownership := range owned {
+		r.owned[ownership.PortfolioID] = append(r.owned[ownership.PortfolioID], ownership)
+	}
+	stockIDs := []uint{}
+	seen := make(map[uint]bool)
+	for _, window := range windows {
+		r.windows[window.PortfolioID] = append(r.windows[window.PortfolioID], window)
+		if !seen[window.StockID] {
+			seen[window.StockID] = true
+			stockIDs = append(stockIDs, window.StockID)
+		}
+	}
+	if len(stockIDs) == 0 {
+		return r, nil
+	}
+
+	prices, err := s.repo.GetPriceHistory(stockIDs, until)
+	if err != nil {
+		return nil, err
+	}
+	for _, price := range prices {
+		r.prices[price.StockID] = append(r.prices[price.StockID], price)
+	}
+
+	actions, err := s.repo.GetCorporateActions(stockIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, action := range actions {
+		r.actions[action.StockID] = append(r.actions[action.StockID], action)
+	}
+	return r, nil
+}
+
+// scoreAt scores a portfolio as of a moment, the same way CalculatePortfolioTotalValue does
+// for the stocks the portfolio held then, and returns the breakdown behind the points
+func (r *replay) scoreAt(portfolioID uint, at time.Time) (int, []models.PointsBreakdown) {
+	total := 0.0
+	var breakdown []models.PointsBreakdown
+	for _, window := range r.windows[portfolioID] {
+		if window.StartDate.After(at) || !r.ownedAt(portfolioID, window.StockID, at) {
+			continue
+		}
+		end := at
+		closed := window.EndDate != nil && !window.EndDate.After(at)
+		if closed {
+			end = *window.EndDate
+		}
+
+		startingValue, ok := r.priceAt(window.StockID, window.StartDate)
+		if !ok {
+			startingValue = window.StartingValue
+		}
+		endingValue, ok := r.priceAt(window.StockID, end)
+		if !ok {
+			if closed {
+				endingValue = window.CurrentValue
+			} else {
+				endingValue = startingValue
+			}
+		}
+		dividends := r.dividendsBetween(window.StockID, window.StartDate, end)
+
+		percentChange := utils.PercentChange(startingValue, endingValue+dividends)
+		total += percentChange
+
+		var windowEnd *time.Time
+		if closed {
+			windowEnd = window.EndDate
+		}
+		breakdown = append(breakdown, models.PointsBreakdown{
+			PortfolioID:     portfolioID,
+			ScoringWindowID: window.ID,
+			StockID:         window.StockID,
+			WindowStart:     window.StartDate,
+			WindowEnd:       windowEnd,
+			StartPrice:      startingValue,
+			EndPrice:        endingValue,
+			Dividends:       dividends,
+			PercentChange:   percentChange,
+			Points:          percentChange,
+			RecordedAt:      at,
+		})
+	}
+	return int(math.Round(total)), breakdown
+}
+
+// ownedAt reports whether a portfolio held a stock at a moment
+func (r *replay) ownedAt(portfolioID uint, stockID uint, at time.Time) bool {
+	for _, ownership := range r.owned[portfolioID] {
+		if ownership.StockID == stockID && !ownership.StartDate.After(at) && (ownership.EndDate == nil || ownership.EndDate.After(at)) {
+			return true
+		}
+	}
+	return false
+}
+
+// priceAt finds the last recorded price of a stock at or before a moment
+func (r *replay) priceAt(stockID uint, at time.Time) (float64, bool) {
+	prices := r.prices[stockID]
+	i := sort.Search(len(prices), func(i int) bool { return prices[i].Timestamp.After(at) })
+	if i == 0 {
+		return 0, false
+	}
+	return prices[i-1].Price, true
+}
+
+// dividendsBetween sums the dividends that went ex in (start, end], restated through any later applied splits
+// the same way the corporate action repository restates credited dividends
+func (r *replay) dividendsBetween(stockID uint, start time.Time, end time.Time) float64 {
+	total := 0.0
+	actions := r.actions[stockID]
+	for i, action := range actions {
+		if action.Type != models.DividendAction || !action.ExDate.After(start) || action.ExDate.After(end) {
+			continue
+		}
+		amount := action.Amount
+		for _, later := range actions[i+1:] {
+			if later.Type == models.SplitAction && later.AppliedAt != nil && later.ExDate.After(action.ExDate) && later.Ratio > 0 {
+				amount /= later.Ratio
+			}
+		}
+		total += amount
+	}
+	return total
+}
+
+// diffPointsHistory compares the recorded points history with the rebuilt one, day by day.
+// When a day has several recorded entries, the latest one is what the recompute replaces.
+func diffPointsHistory(existing []models.PortfolioPointsHistory, rebuilt []models.PortfolioPointsHistory, location *time.Location) []PointsChange {
+	type dayKey struct {
+		portfolioID uint
+		date        string
+	}
+	oldPoints := make(map[dayKey]int)
+	var keys []dayKey
+	for _, entry := range existing {
+		key := dayKey{entry.PortfolioID, entry.RecordedAt.In(location).Format(RecomputeDateFormat)}
+		if _, ok := oldPoints[key]; !ok {
+			keys = append(keys, key)
+		}
+		oldPoints[key] = entry.Points
+	}
+	newPoints := make(map[dayKey]int)
+	for _, entry := range rebuilt {
+		key := dayKey{entry.PortfolioID, entry.RecordedAt.In(location).Format(RecomputeDateFormat)}
+		if _, ok := oldPoints[key]; !ok {
+			if _, ok := newPoints[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		newPoints[key] = entry.Points
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].date != keys[j].date {
+			return keys[i].date < keys[j].date
+		}
+		return keys[i].portfolioID < keys[j].portfolioID
+	})
+
+	changes := []PointsChange{}
+	for _, key := range keys {
+		change := PointsChange{PortfolioID: key.portfolioID, Date: key.date}
+		if points, ok := oldPoints[key]; ok {
+			change.OldPoints = &points
+		}
+		if points, ok := newPoints[key]; ok {
+			change.NewPoints = &points
+		}
+		if change.OldPoints != nil && change.NewPoints != nil && *change.OldPoints == *change.NewPoints {
+			continue
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+func startOfDay(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+// snapshotTime is when the scheduler records a day's points
+func snapshotTime(day time.Time) time.Time {
+	return utils.DailyRunTime(day)
+}
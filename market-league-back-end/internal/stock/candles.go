@@ -0,0 +1,161 @@
+package stock
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// StockInfoHistoryDays is how many days of raw prices GetStockInfo returns
+const StockInfoHistoryDays = 30
+
+// MaxCandles is the most candles a chart returns. Longer ranges switch to a coarser interval, and
+// past weekly candles neighbouring candles are merged.
+const MaxCandles = 500
+
+// candleIntervals are the supported intervals, finest first
+var candleIntervals = []models.CandleInterval{
+	models.CandleInterval_Hour,
+	models.CandleInterval_Day,
+	models.CandleInterval_Week,
+}
+
+// defaultCandleRange is how far back a chart reaches when no start is given
+var defaultCandleRange = map[models.CandleInterval]time.Duration{
+	models.CandleInterval_Hour: 7 * 24 * time.Hour,
+	models.CandleInterval_Day:  365 * 24 * time.Hour,
+	models.CandleInterval_Week: 5 * 365 * 24 * time.Hour,
+}
+
+// GetCandles builds the OHLC candles of a stock from its recorded prices. A nil to is now and a nil
+// from reaches back a default range for the interval. Candles start on market-time boundaries.
+func (s *StockService) GetCandles(stockID uint, interval models.CandleInterval, from, to *time.Time) (*models.CandleSeries, error) {
+	if stockID == 0 {
+		return nil, errors.New("invalid stock ID")
+	}
+	if interval == "" {
+		interval = models.CandleInterval_Day
+	}
+	if _, ok := defaultCandleRange[interval]; !ok {
+		return nil, fmt.Errorf("unknown interval %q, use 1h, 1d or 1w", interval)
+	}
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load market timezone: %w", err)
+	}
+
+	end := utils.Now()
+	if to != nil {
+		end = *to
+	}
+	start := end.Add(-defaultCandleRange[interval])
+	if from != nil {
+		start = *from
+	}
+	if !start.Before(end) {
+		return nil, errors.New("the start of the range must be before its end")
+	}
+
+	// Older days only survive as daily bars once compacted
+	history, err := s.StockRepo.GetPriceHistoryRange(stockID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	bars, err := s.StockRepo.GetDailyBars(stockID, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	series := &models.CandleSeries{StockID: stockID, Interval: interval, From: start, To: end}
+	series.Candles = BuildCandles(history, bars, interval, location)
+	for i := indexOfInterval(interval) + 1; len(series.Candles) > MaxCandles && i < len(candleIntervals); i++ {
+		series.Interval = candleIntervals[i]
+		series.Candles = BuildCandles(history, bars, series.Interval, location)
+	}
+	if len(series.Candles) > MaxCandles {
+		series.Candles = MergeCandles(series.Candles, (len(series.Candles)+MaxCandles-1)/MaxCandles)
+		series.Downsampled = true
+	}
+	return series, nil
+}
+
+// BuildCandles groups raw prices and compacted daily bars into candles of an interval in a timezone.
+// Intervals without prices have no candle. A daily bar is never split, so on compacted days an hourly
+// chart has one candle starting at midnight.
+func BuildCandles(history []models.PriceHistory, bars []models.DailyPriceBar, interval models.CandleInterval, location *time.Location) []models.Candle {
+	parts := make([]models.Candle, 0, len(history)+len(bars))
+	for _, bar := range bars {
+		parts = append(parts, models.Candle{
+			Time: bar.Date.In(location), Open: bar.Open, High: bar.High, Low: bar.Low, Close: bar.Close, Points: bar.Points,
+		})
+	}
+	for _, point := range history {
+		parts = append(parts, models.Candle{
+			Time: point.Timestamp.In(location), Open: point.Price, High: point.Price, Low: point.Price, Close: point.Price, Points: 1,
+		})
+	}
+	sort.SliceStable(parts, func(i, j int) bool { return parts[i].Time.Before(parts[j].Time) })
+
+	candles := []models.Candle{}
+	for _, part := range parts {
+		bucket := candleStart(part.Time, interval)
+		last := len(candles) - 1
+		if last < 0 || !candles[last].Time.Equal(bucket) {
+			part.Time = bucket
+			candles = append(candles, part)
+			continue
+		}
+		candle := &candles[last]
+		candle.High = max(candle.High, part.High)
+		candle.Low = min(candle.Low, part.Low)
+		candle.Close = part.Close
+		candle.Points += part.Points
+	}
+	return candles
+}
+
+// MergeCandles combines every size neighbouring candles into one
+func MergeCandles(candles []models.Candle, size int) []models.Candle {
+	if size <= 1 {
+		return candles
+	}
+	merged := make([]models.Candle, 0, (len(candles)+size-1)/size)
+	for i := 0; i < len(candles); i += size {
+		group := candles[i:min(i+size, len(candles))]
+		candle := group[0]
+		for _, next := range group[1:] {
+			candle.High = max(candle.High, next.High)
+			candle.Low = min(candle.Low, next.Low)
+			candle.Close = next.Close
+			candle.Points += next.Points
+		}
+		merged = append(merged, candle)
+	}
+	return merged
+}
+
+// candleStart returns the start of the interval t falls in, weeks start on Monday
+func candleStart(t time.Time, interval models.CandleInterval) time.Time {
+	switch interval {
+	case models.CandleInterval_Hour:
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, t.Location())
+	case models.CandleInterval_Week:
+		daysSinceMonday := (int(t.Weekday()) + 6) % 7
+		return time.Date(t.Year(), t.Month(), t.Day()-daysSinceMonday, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	}
+}
+
+func indexOfInterval(interval models.CandleInterval) int {
+	for i, candidate := range candleIntervals {
+		if candidate == interval {
+			return i
+		}
+	}
+	return len(candleIntervals)
+}
@@ -0,0 +1,68 @@
+package stock
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/market-league/internal/utils"
+)
+
+// DefaultPriceHistoryRetentionDays is how many days of raw prices are kept unless
+// PRICE_HISTORY_RETENTION_DAYS says otherwise
+const DefaultPriceHistoryRetentionDays = 90
+
+// PriceHistoryRetentionDays reads PRICE_HISTORY_RETENTION_DAYS, falling back to the default. It is never
+// shorter than StockInfoHistoryDays, so stock info always shows raw prices.
+func PriceHistoryRetentionDays() int {
+	days, err := strconv.Atoi(os.Getenv("PRICE_HISTORY_RETENTION_DAYS"))
+	if err != nil || days <= 0 {
+		return DefaultPriceHistoryRetentionDays
+	}
+	return max(days, StockInfoHistoryDays)
+}
+
+// CompactionResult reports what a compaction rolled up
+type CompactionResult struct {
+	Before time.Time `json:"before"` // Raw prices before this were compacted
+	Stocks int       `json:"stocks"`
+	Points int       `json:"points"` // Raw prices removed
+	Bars   int       `json:"bars"`   // Daily bars written
+}
+
+func (r CompactionResult) String() string {
+	return fmt.Sprintf("%d prices of %d stocks before %s compacted into %d daily bars",
+		r.Points, r.Stocks, r.Before.Format(time.RFC3339), r.Bars)
+}
+
+// CompactPriceHistory rolls raw prices older than retentionDays, counted from the start of today in
+// market time, into daily bars. Each stock is compacted in its own transaction, so a failure leaves
+// the stocks before it compacted and can simply be run again.
+func (s *StockService) CompactPriceHistory(retentionDays int) (*CompactionResult, error) {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load market timezone: %w", err)
+	}
+	now := utils.Now().In(location)
+	before := time.Date(now.Year(), now.Month(), now.Day()-retentionDays, 0, 0, 0, 0, location)
+	result := &CompactionResult{Before: before}
+
+	stockIDs, err := s.StockRepo.GetStockIDsWithPriceHistoryBefore(before)
+	if err != nil {
+		return result, err
+	}
+	for _, stockID := range stockIDs {
+		compacted, err := s.StockRepo.CompactPriceHistory(stockID, before, location)
+		if err != nil {
+			return result, err
+		}
+		if compacted == nil {
+			continue
+		}
+		result.Stocks++
+		result.Points += compacted.Points
+		result.Bars += compacted.Bars
+	}
+	return result, nil
+}
@@ -0,0 +1,164 @@
+package stock
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+)
+
+// PriceRules are the data quality checks a quote has to pass before it is recorded
+type PriceRules struct {
+	MaxMovePercent   float64 // Largest move from the last price, 0 for no limit
+	StaleTradingDays int     // Trading days a price may stay unchanged, 0 for no limit
+}
+
+// DefaultPriceRules flags moves of more than half and prices stuck for three trading days
+func DefaultPriceRules() PriceRules {
+	return PriceRules{MaxMovePercent: 50, StaleTradingDays: 3}
+}
+
+// PriceRulesFromEnv overrides the defaults with PRICE_MAX_MOVE_PERCENT and PRICE_STALE_DAYS
+func PriceRulesFromEnv() PriceRules {
+	rules := DefaultPriceRules()
+	if value, err := strconv.ParseFloat(os.Getenv("PRICE_MAX_MOVE_PERCENT"), 64); err == nil && value >= 0 {
+		rules.MaxMovePercent = value
+	}
+	if value, err := strconv.Atoi(os.Getenv("PRICE_STALE_DAYS")); err == nil && value >= 0 {
+		rules.StaleTradingDays = value
+	}
+	return rules
+}
+
+// ErrQuoteQuarantined is returned for a quote held back for review instead of being recorded
+var ErrQuoteQuarantined = errors.New("quote quarantined for review")
+
+// RecordQuote checks a quote against the price rules and records it, or quarantines it with
+// ErrQuoteQuarantined. A nil timestamp is now.
+func (s *StockService) RecordQuote(stockID uint, price float64, timestamp *time.Time) (*models.QuarantinedQuote, error) {
+	at := utils.Now()
+	if timestamp != nil {
+		at = *timestamp
+	}
+	stock, err := s.StockRepo.GetStockByID(stockID)
+	if err != nil {
+		return nil, err
+	}
+
+	rule, reason, err := s.checkQuote(stock, price, at)
+	if err != nil {
+		return nil, err
+	}
+	if rule == "" {
+		return nil, s.UpdateStockPrice(stockID, price, &at)
+	}
+
+	// Keep one pending quote per stock, so a feed stuck on bad data does not flood the review list
+	quote, err := s.StockRepo.GetPendingQuarantinedQuote(stockID)
+	if err != nil {
+		return nil, err
+	}
+	if quote == nil {
+		quote = &models.QuarantinedQuote{StockID: stockID, Status: models.QuarantinePending}
+	} else {
+		quote.Occurrences++
+	}
+	if math.IsNaN(price) || math.IsInf(price, 0) {
+		price = 0
+	}
+	quote.Price = price
+	quote.PreviousPrice = stock.CurrentPrice
+	quote.Timestamp = at
+	quote.Rule = rule
+	quote.Reason = reason
+	if err := s.StockRepo.SaveQuarantinedQuote(quote); err != nil {
+		return nil, err
+	}
+	return quote, fmt.Errorf("%w: %s %s", ErrQuoteQuarantined, stock.TickerSymbol, reason)
+}
+
+// checkQuote returns the rule a quote breaks and why, or an empty rule for a quote that looks fine
+func (s *StockService) checkQuote(stock *models.Stock, price float64, at time.Time) (models.QuoteRule, string, error) {
+	if math.IsNaN(price) || math.IsInf(price, 0) || price <= 0 {
+		return models.QuoteRule_InvalidPrice, fmt.Sprintf("price %v is not a positive number", price), nil
+	}
+
+	previous := stock.CurrentPrice
+	if s.Rules.MaxMovePercent > 0 && previous > 0 {
+		move := utils.PercentChange(previous, price)
+		if math.Abs(move) > s.Rules.MaxMovePercent {
+			return models.QuoteRule_MaxMove, fmt.Sprintf("moved %.1f%% from %.2f to %.2f, more than %.1f%%",
+				move, previous, price, s.Rules.MaxMovePercent), nil
+		}
+	}
+
+	if s.Rules.StaleTradingDays > 0 && price == previous {
+		since, err := s.StockRepo.GetUnchangedSince(stock.ID, price)
+		if err != nil {
+			return "", "", err
+		}
+		if since != nil {
+			if days := tradingDaysBetween(*since, at); days > s.Rules.StaleTradingDays {
+				return models.QuoteRule_Stale, fmt.Sprintf("unchanged at %.2f for %d trading days", price, days), nil
+			}
+		}
+	}
+	return "", "", nil
+}
+
+// tradingDaysBetween counts the trading days after the day of from up to and including the day of to, in market time
+func tradingDaysBetween(from, to time.Time) int {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		location = time.UTC
+	}
+	from, to = from.In(location), to.In(location)
+	days := 0
+	for day := time.Date(from.Year(), from.Month(), from.Day()+1, 0, 0, 0, 0, location); !day.After(to); day = day.AddDate(0, 0, 1) {
+		if utils.IsTradingDay(day) {
+			days++
+		}
+	}
+	return days
+}
+
+// GetQuarantinedQuotes lists quarantined quotes with a status, all of them for an empty status
+func (s *StockService) GetQuarantinedQuotes(status models.QuarantineStatus) ([]models.QuarantinedQuote, error) {
+	switch status {
+	case "", models.QuarantinePending, models.QuarantineApproved, models.QuarantineRejected:
+		return s.StockRepo.GetQuarantinedQuotes(status)
+	default:
+		return nil, fmt.Errorf("unknown status %q", status)
+	}
+}
+
+// ReviewQuarantinedQuote approves a quarantined quote, recording its price, or rejects it
+func (s *StockService) ReviewQuarantinedQuote(quoteID, reviewerID uint, approve bool) (*models.QuarantinedQuote, error) {
+	quote, err := s.StockRepo.GetQuarantinedQuote(quoteID)
+	if err != nil {
+		return nil, err
+	}
+	if quote.Status != models.QuarantinePending {
+		return nil, fmt.Errorf("quote %d has already been %s", quoteID, quote.Status)
+	}
+	if approve && quote.Rule == models.QuoteRule_InvalidPrice {
+		return nil, errors.New("an invalid price cannot be approved")
+	}
+
+	now := utils.Now()
+	quote.Status = models.QuarantineRejected
+	if approve {
+		quote.Status = models.QuarantineApproved
+	}
+	quote.ReviewedBy = &reviewerID
+	quote.ReviewedAt = &now
+	if err := s.StockRepo.ResolveQuarantinedQuote(quote); err != nil {
+		return nil, err
+	}
+	return s.StockRepo.GetQuarantinedQuote(quoteID)
+}
@@ -3,10 +3,13 @@ package stock
 import (
 	"encoding/json"
 	"fmt"
+	"strings"
 	"time"
 
+	"github.com/gin-gonic/gin"
 	ws "github.com/market-league/api/websocket"
 	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/user"
 )
 
 // StockHandler Interface
@@ -16,6 +19,12 @@ type StockHandlerInterface interface {
 	UpdatePrice(conn *ws.Connection, rawData json.RawMessage) error
 	GetAllStocks(conn *ws.Connection, rawData json.RawMessage) error
 	GetStockInfo(conn *ws.Connection, rawData json.RawMessage) error
+	GetPriceCandles(conn *ws.Connection, rawData json.RawMessage) error
+	SubscribeToPrices(conn *ws.Connection, rawData json.RawMessage) error
+	UnsubscribeToPrices(conn *ws.Connection, rawData json.RawMessage) error
+	ImportUniverse(conn *ws.Connection, rawData json.RawMessage) error
+	GetQuarantinedQuotes(conn *ws.Connection, rawData json.RawMessage) error
+	ReviewQuarantinedQuote(conn *ws.Connection, rawData json.RawMessage) error
 }
 
 // Compile-time check
@@ -24,11 +33,12 @@ var _ StockHandlerInterface = (*StockHandler)(nil)
 // StockHandler defines the HTTP handler for stock-related operations.
 type StockHandler struct {
 	StockService *StockService
+	userRepo     *user.UserRepository
 }
 
 // NewStockHandler creates a new instance of StockHandler.
-func NewStockHandler(service *StockService) *StockHandler {
-	return &StockHandler{StockService: service}
+func NewStockHandler(service *StockService, userRepo *user.UserRepository) *StockHandler {
+	return &StockHandler{StockService: service, userRepo: userRepo}
 }
 
 // * Implementation of Interface
@@ -39,6 +49,8 @@ func (h *StockHandler) CreateStock(conn *ws.Connection, rawData json.RawMessage)
 	var request struct {
 		TickerSymbol string `json:"ticker_symbol" binding:"required"`
 		CompanyName  string `json:"company_name" binding:"required"`
+		Sector       string `json:"sector"`
+		Industry     string `json:"industry"`
 	}
 
 	// Step 2: Parse data from WebSocket JSON payload
@@ -48,7 +60,7 @@ func (h *StockHandler) CreateStock(conn *ws.Connection, rawData json.RawMessage)
 	}
 
 	// Step 3: Process business logic (reuse the service layer)
-	stock, err := h.StockService.CreateStock(request.TickerSymbol, request.CompanyName)
+	stock, err := h.StockService.CreateStock(request.TickerSymbol, request.CompanyName, request.Sector, request.Industry)
 	if err != nil {
 		ws.SendError(conn, ws.MessageType_Stock_CreateStock, err.Error())
 		return fmt.Errorf("failed to create portfolio: %v", err)
@@ -79,6 +91,8 @@ func (h *StockHandler) CreateMultipleStocks(conn *ws.Connection, rawData json.Ra
 	var request []struct {
 		TickerSymbol string `json:"ticker_symbol" binding:"required"`
 		CompanyName  string `json:"company_name" binding:"required"`
+		Sector       string `json:"sector"`
+		Industry     string `json:"industry"`
 	}
 
 	// Step 2: Parse data from WebSocket JSON payload
@@ -93,6 +107,8 @@ func (h *StockHandler) CreateMultipleStocks(conn *ws.Connection, rawData json.Ra
 		stock := &models.Stock{
 			TickerSymbol: stockReq.TickerSymbol,
 			CompanyName:  stockReq.CompanyName,
+			Sector:       stockReq.Sector,
+			Industry:     stockReq.Industry,
 		}
 		stocksPointer = append(stocksPointer, stock)
 	}
@@ -141,8 +157,8 @@ func (h *StockHandler) UpdatePrice(conn *ws.Connection, rawData json.RawMessage)
 		return fmt.Errorf("invalid input: %v", err)
 	}
 
-	// Step 3: Process business logic (reuse the service layer)
-	if err := h.StockService.UpdateStockPrice(request.StockID, request.NewPrice, request.Timestamp); err != nil {
+	// Step 3: Process business logic (reuse the service layer), suspicious prices wait for an admin
+	if _, err := h.StockService.RecordQuote(request.StockID, request.NewPrice, request.Timestamp); err != nil {
 		ws.SendError(conn, ws.MessageType_Stock_UpdateCurrentStockPrice, err.Error())
 		return fmt.Errorf("failed to create portfolio: %v", err)
 	}
@@ -212,6 +228,8 @@ func (h *StockHandler) GetStockInfo(conn *ws.Connection, rawData json.RawMessage
 		ID:             stock.ID,
 		TickerSymbol:   stock.TickerSymbol,
 		CompanyName:    stock.CompanyName,
+		Sector:         stock.Sector,
+		Industry:       stock.Industry,
 		CurrentPrice:   stock.CurrentPrice,
 		PriceHistories: convertPriceHistories(stock.PriceHistories),
 	}
@@ -235,6 +253,178 @@ func (h *StockHandler) GetStockInfo(conn *ws.Connection, rawData json.RawMessage
 	return nil
 }
 
+// GetPriceCandles handles retrieving the OHLC candles of a stock for a price chart
+func (h *StockHandler) GetPriceCandles(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		StockID  uint                  `json:"stock_id" binding:"required"`
+		Interval models.CandleInterval `json:"interval"` // 1h, 1d or 1w, defaults to 1d
+		From     *time.Time            `json:"from"`     // Optional: defaults to a range that suits the interval
+		To       *time.Time            `json:"to"`       // Optional: defaults to now
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Stock_GetPriceCandles, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Process business logic (reuse the service layer)
+	series, err := h.StockService.GetCandles(request.StockID, request.Interval, request.From, request.To)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Stock_GetPriceCandles, err.Error())
+		return fmt.Errorf("failed to get price candles: %v", err)
+	}
+
+	// Step 4: Marshal the candles into JSON
+	seriesJSON, err := json.Marshal(series)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Stock_GetPriceCandles, "Failed to serialize price candles")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 5: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Stock_GetPriceCandles,
+		Data: json.RawMessage(seriesJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// SubscribeToPrices starts pushing live price ticks of the given stocks to the connection
+func (h *StockHandler) SubscribeToPrices(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		StockIDs []uint `json:"stock_ids" binding:"required"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Stock_SubscribeToPrices, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+	if len(request.StockIDs) == 0 {
+		ws.SendError(conn, ws.MessageType_Stock_SubscribeToPrices, "stock_ids is required")
+		return fmt.Errorf("no stock IDs to subscribe to")
+	}
+
+	// Step 3: Look the stocks up so the client starts from their current prices
+	stocks, err := h.StockService.StockRepo.GetStocksByIDs(request.StockIDs)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Stock_SubscribeToPrices, "Failed to retrieve stocks: "+err.Error())
+		return fmt.Errorf("failed to retrieve stocks: %v", err)
+	}
+
+	// Step 4: Subscribe to the stocks that exist
+	stockIDs := make([]uint, len(stocks))
+	for i := range stocks {
+		stockIDs[i] = stocks[i].ID
+	}
+	ws.Manager.SubscribeToPrices(conn, stockIDs)
+
+	// Step 5: Marshal the current prices into JSON
+	responseJSON, err := json.Marshal(gin.H{
+		"message":   "Subscribed to prices successfully",
+		"stock_ids": stockIDs,
+		"stocks":    stocks,
+	})
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Stock_SubscribeToPrices, "Failed to serialize response")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 6: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Stock_SubscribeToPrices,
+		Data: json.RawMessage(responseJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// UnsubscribeToPrices stops pushing price ticks of the given stocks, or of every stock when none are given
+func (h *StockHandler) UnsubscribeToPrices(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		StockIDs []uint `json:"stock_ids"`
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if len(rawData) > 0 {
+		if err := json.Unmarshal(rawData, &request); err != nil {
+			ws.SendError(conn, ws.MessageType_Stock_UnsubscribeToPrices, "Invalid input: "+err.Error())
+			return fmt.Errorf("invalid input: %v", err)
+		}
+	}
+
+	// Step 3: Drop the subscriptions
+	ws.Manager.UnsubscribeToPrices(conn, request.StockIDs)
+
+	// Step 4: Send success response (no data, just confirmation)
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Stock_UnsubscribeToPrices,
+		Data: json.RawMessage(`{"message": "Unsubscribed from prices successfully"}`),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// ImportUniverse handles an admin importing a universe of stocks from CSV text
+func (h *StockHandler) ImportUniverse(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID   uint   `json:"user_id"`                     // Optional: must be the signed-in user
+		Universe string `json:"universe" binding:"required"` // Such as "sp500"
+		CSV      string `json:"csv" binding:"required"`      // Contents of the universe file
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_ImportUniverse, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Only admins may change the stock universe
+	if _, err := h.requireAdmin(conn, ws.MessageType_Admin_ImportUniverse, request.UserID); err != nil {
+		return err
+	}
+
+	// Step 4: Process business logic (reuse the service layer)
+	result, err := h.StockService.ImportUniverse(request.Universe, strings.NewReader(request.CSV))
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_ImportUniverse, err.Error())
+		return fmt.Errorf("failed to import universe: %v", err)
+	}
+
+	// Step 5: Marshal the result into JSON
+	resultJSON, err := json.Marshal(result)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_ImportUniverse, "Failed to serialize import result")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 6: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Admin_ImportUniverse,
+		Data: json.RawMessage(resultJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
 // * Helper functions
 
 // Helper function to detect unique constraint errors
@@ -245,6 +435,118 @@ func isUniqueConstraintError(err error, field string) bool {
 	return false
 }
 
+// GetQuarantinedQuotes handles an admin listing the quotes held back by the price rules
+func (h *StockHandler) GetQuarantinedQuotes(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID uint                    `json:"user_id"` // Optional: must be the signed-in user
+		Status models.QuarantineStatus `json:"status"`  // Optional: pending, approved or rejected, all when empty
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_GetQuarantinedQuotes, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Only admins may review quotes
+	if _, err := h.requireAdmin(conn, ws.MessageType_Admin_GetQuarantinedQuotes, request.UserID); err != nil {
+		return err
+	}
+
+	// Step 4: Process business logic (reuse the service layer)
+	quotes, err := h.StockService.GetQuarantinedQuotes(request.Status)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_GetQuarantinedQuotes, err.Error())
+		return fmt.Errorf("failed to get quarantined quotes: %v", err)
+	}
+
+	// Step 5: Marshal the quotes into JSON
+	quotesJSON, err := json.Marshal(quotes)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_GetQuarantinedQuotes, "Failed to serialize quarantined quotes")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 6: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Admin_GetQuarantinedQuotes,
+		Data: json.RawMessage(quotesJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// ReviewQuarantinedQuote handles an admin approving or rejecting a quarantined quote
+func (h *StockHandler) ReviewQuarantinedQuote(conn *ws.Connection, rawData json.RawMessage) error {
+	// Step 1: Parse the WebSocket message
+	var request struct {
+		UserID  uint `json:"user_id"` // Optional: must be the signed-in user
+		QuoteID uint `json:"quote_id" binding:"required"`
+		Approve bool `json:"approve"` // True records the price, false discards it
+	}
+
+	// Step 2: Parse data from WebSocket JSON payload
+	if err := json.Unmarshal(rawData, &request); err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_ReviewQuarantinedQuote, "Invalid input: "+err.Error())
+		return fmt.Errorf("invalid input: %v", err)
+	}
+
+	// Step 3: Only admins may review quotes
+	adminID, err := h.requireAdmin(conn, ws.MessageType_Admin_ReviewQuarantinedQuote, request.UserID)
+	if err != nil {
+		return err
+	}
+
+	// Step 4: Process business logic (reuse the service layer)
+	quote, err := h.StockService.ReviewQuarantinedQuote(request.QuoteID, adminID, request.Approve)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_ReviewQuarantinedQuote, err.Error())
+		return fmt.Errorf("failed to review quarantined quote: %v", err)
+	}
+
+	// Step 5: Marshal the quote into JSON
+	quoteJSON, err := json.Marshal(quote)
+	if err != nil {
+		ws.SendError(conn, ws.MessageType_Admin_ReviewQuarantinedQuote, "Failed to serialize quarantined quote")
+		return fmt.Errorf("serialization error: %v", err)
+	}
+
+	// Step 6: Send success response back via WebSocket
+	response := ws.WebsocketMessage{
+		Type: ws.MessageType_Admin_ReviewQuarantinedQuote,
+		Data: json.RawMessage(quoteJSON),
+	}
+	if err := conn.Ws.WriteJSON(response); err != nil {
+		return fmt.Errorf("failed to send response: %v", err)
+	}
+
+	return nil
+}
+
+// requireAdmin sends an error of messageType unless the connection signed in as an admin, and returns that
+// admin's user ID. A user ID in the request must name that same user.
+func (h *StockHandler) requireAdmin(conn *ws.Connection, messageType string, userID uint) (uint, error) {
+	userID, err := conn.User(userID)
+	if err != nil {
+		ws.SendError(conn, messageType, err.Error())
+		return 0, err
+	}
+	isAdmin, err := h.userRepo.IsAdmin(userID)
+	if err != nil {
+		ws.SendError(conn, messageType, err.Error())
+		return 0, fmt.Errorf("failed to check admin status: %v", err)
+	}
+	if !isAdmin {
+		ws.SendError(conn, messageType, "Admin access required")
+		return 0, fmt.Errorf("user %d is not an admin", userID)
+	}
+	return userID, nil
+}
+
 // Helper function to extract necessary data from stocks
 func extractStocksData(stocks []*models.Stock) []models.Stock {
 	var result []models.Stock
@@ -2,9 +2,13 @@ package stock
 
 import (
 	"fmt"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/market-league/internal/marketdata"
 	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -27,6 +31,24 @@ func (r *StockRepository) GetStocksByIDs(stockIDs []uint) ([]models.Stock, error
 	return stocks, nil
 }
 
+// GetStockByTicker fetches a stock by its ticker symbol.
+func (r *StockRepository) GetStockByTicker(tickerSymbol string) (*models.Stock, error) {
+	var stock models.Stock
+	if err := r.db.Where("ticker_symbol = ?", tickerSymbol).First(&stock).Error; err != nil {
+		return nil, fmt.Errorf("failed to find stock with ticker %s: %w", tickerSymbol, err)
+	}
+	return &stock, nil
+}
+
+// GetStockByID fetches a stock by its ID.
+func (r *StockRepository) GetStockByID(stockID uint) (*models.Stock, error) {
+	var stock models.Stock
+	if err := r.db.First(&stock, stockID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find stock %d: %w", stockID, err)
+	}
+	return &stock, nil
+}
+
 // CreateStock creates a new stock and its initial price history within a transaction
 func (r *StockRepository) CreateStock(stock *models.Stock) error {
 	return r.db.Transaction(func(tx *gorm.DB) error {
@@ -73,6 +95,17 @@ func (r *StockRepository) UpdateCurrentPrice(stockID uint, newPrice float64, tim
 
 		if timestamp != nil {
 			priceHistory.Timestamp = *timestamp
+
+			// A retried update for the same time replaces the price recorded by the first one
+			replaced := tx.Model(&models.PriceHistory{}).
+				Where("stock_id = ? AND timestamp = ?", stock.ID, *timestamp).
+				Update("price", newPrice)
+			if replaced.Error != nil {
+				return replaced.Error
+			}
+			if replaced.RowsAffected > 0 {
+				return nil
+			}
 		}
 
 		if err := tx.Create(&priceHistory).Error; err != nil {
@@ -83,14 +116,284 @@ func (r *StockRepository) UpdateCurrentPrice(stockID uint, newPrice float64, tim
 	})
 }
 
-func (r *StockRepository) GetStockWithHistory(stockID uint) (models.Stock, error) {
+// GetStockWithHistory gets a stock with the prices recorded since a time, oldest first
+func (r *StockRepository) GetStockWithHistory(stockID uint, since time.Time) (models.Stock, error) {
 	var stock models.Stock
-	if err := r.db.Preload("PriceHistories").First(&stock, stockID).Error; err != nil {
+	err := r.db.Preload("PriceHistories", func(db *gorm.DB) *gorm.DB {
+		return db.Where("timestamp >= ?", since).Order("timestamp ASC")
+	}).First(&stock, stockID).Error
+	if err != nil {
 		return models.Stock{}, err
 	}
 	return stock, nil
 }
 
+// GetPriceHistoryRange gets the prices of a stock recorded from one time up to another, oldest first
+func (r *StockRepository) GetPriceHistoryRange(stockID uint, from, to time.Time) ([]models.PriceHistory, error) {
+	var history []models.PriceHistory
+	err := r.db.Select("stock_id", "price", "timestamp").
+		Where("stock_id = ? AND timestamp >= ? AND timestamp < ?", stockID, from, to).
+		Order("timestamp ASC").
+		Find(&history).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch price history of stock %d: %w", stockID, err)
+	}
+	return history, nil
+}
+
+// GetDailyBars gets the daily bars of a stock for the market days from one time up to another, oldest first
+func (r *StockRepository) GetDailyBars(stockID uint, from, to time.Time) ([]models.DailyPriceBar, error) {
+	var bars []models.DailyPriceBar
+	err := r.db.Where("stock_id = ? AND close_at >= ? AND date < ?", stockID, from, to).
+		Order("date ASC").
+		Find(&bars).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch daily bars of stock %d: %w", stockID, err)
+	}
+	return bars, nil
+}
+
+// GetStockIDsWithPriceHistoryBefore gets the stocks, delisted or not, with raw prices recorded before a time
+func (r *StockRepository) GetStockIDsWithPriceHistoryBefore(before time.Time) ([]uint, error) {
+	var stockIDs []uint
+	err := r.db.Model(&models.PriceHistory{}).
+		Where("timestamp < ?", before).
+		Distinct().
+		Order("stock_id ASC").
+		Pluck("stock_id", &stockIDs).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch stocks with price history before %s: %w", before, err)
+	}
+	return stockIDs, nil
+}
+
+// CompactPriceHistory rolls the raw prices of a stock recorded before a time into one bar per market day
+// of location, deletes them and records the compacted range, in a transaction. A day that already has
+// a bar, because late prices arrived after it was compacted, is merged into it. It returns nil when
+// there was nothing to compact.
+func (r *StockRepository) CompactPriceHistory(stockID uint, before time.Time, location *time.Location) (*models.CompactedRange, error) {
+	var compacted *models.CompactedRange
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		// Step 1: Load the raw prices to compact
+		var history []models.PriceHistory
+		if err := tx.Where("stock_id = ? AND timestamp < ?", stockID, before).
+			Order("timestamp ASC, id ASC").
+			Find(&history).Error; err != nil {
+			return err
+		}
+		if len(history) == 0 {
+			return nil
+		}
+
+		// Step 2: Group them by market day
+		var bars []models.DailyPriceBar
+		for _, point := range history {
+			local := point.Timestamp.In(location)
+			day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, location).UTC()
+			last := len(bars) - 1
+			if last < 0 || !bars[last].Date.Equal(day) {
+				bars = append(bars, models.DailyPriceBar{
+					StockID: stockID, Date: day,
+					Open: point.Price, High: point.Price, Low: point.Price, Close: point.Price,
+					OpenAt: point.Timestamp, CloseAt: point.Timestamp, Points: 1,
+				})
+				continue
+			}
+			bar := &bars[last]
+			bar.High = max(bar.High, point.Price)
+			bar.Low = min(bar.Low, point.Price)
+			bar.Close = point.Price
+			bar.CloseAt = point.Timestamp
+			bar.Points++
+		}
+
+		// Step 3: Save the bars, merging into any already stored
+		for _, bar := range bars {
+			var existing models.DailyPriceBar
+			found := tx.Where("stock_id = ? AND date = ?", stockID, bar.Date).Limit(1).Find(&existing)
+			if found.Error != nil {
+				return found.Error
+			}
+			if found.RowsAffected == 0 {
+				if err := tx.Create(&bar).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if bar.OpenAt.Before(existing.OpenAt) {
+				existing.Open, existing.OpenAt = bar.Open, bar.OpenAt
+			}
+			if bar.CloseAt.After(existing.CloseAt) {
+				existing.Close, existing.CloseAt = bar.Close, bar.CloseAt
+			}
+			existing.High = max(existing.High, bar.High)
+			existing.Low = min(existing.Low, bar.Low)
+			existing.Points += bar.Points
+			if err := tx.Save(&existing).Error; err != nil {
+				return err
+			}
+		}
+
+		// Step 4: Drop the raw prices and record the range
+		if err := tx.Where("stock_id = ? AND timestamp < ?", stockID, before).Delete(&models.PriceHistory{}).Error; err != nil {
+			return err
+		}
+		compacted = &models.CompactedRange{
+			StockID:     stockID,
+			From:        history[0].Timestamp,
+			To:          before,
+			Points:      len(history),
+			Bars:        len(bars),
+			CompactedAt: utils.Now(),
+		}
+		return tx.Create(compacted).Error
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to compact price history of stock %d: %w", stockID, err)
+	}
+	return compacted, nil
+}
+
+// GetCompactedRanges gets the compactions of a stock's price history, oldest first
+func (r *StockRepository) GetCompactedRanges(stockID uint) ([]models.CompactedRange, error) {
+	var ranges []models.CompactedRange
+	if err := r.db.Where("stock_id = ?", stockID).Order("compacted_at ASC, id ASC").Find(&ranges).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch compacted ranges of stock %d: %w", stockID, err)
+	}
+	return ranges, nil
+}
+
+// GetUnchangedSince returns when the prices of a stock last became price and have stayed there since,
+// or nil if its latest price is a different one
+func (r *StockRepository) GetUnchangedSince(stockID uint, price float64) (*time.Time, error) {
+	var latest models.PriceHistory
+	found := r.db.Where("stock_id = ?", stockID).Order("timestamp DESC, id DESC").Limit(1).Find(&latest)
+	if found.Error != nil {
+		return nil, fmt.Errorf("failed to fetch latest price of stock %d: %w", stockID, found.Error)
+	}
+	if found.RowsAffected == 0 || latest.Price != price {
+		return nil, nil
+	}
+
+	query := r.db.Model(&models.PriceHistory{}).Where("stock_id = ? AND price = ?", stockID, price)
+	var lastDifferent models.PriceHistory
+	found = r.db.Where("stock_id = ? AND price <> ?", stockID, price).Order("timestamp DESC").Limit(1).Find(&lastDifferent)
+	if found.Error != nil {
+		return nil, fmt.Errorf("failed to fetch price changes of stock %d: %w", stockID, found.Error)
+	}
+	if found.RowsAffected > 0 {
+		query = query.Where("timestamp > ?", lastDifferent.Timestamp)
+	}
+
+	var since models.PriceHistory
+	if err := query.Order("timestamp ASC").Limit(1).Find(&since).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch price changes of stock %d: %w", stockID, err)
+	}
+	return &since.Timestamp, nil
+}
+
+// GetPendingQuarantinedQuote gets the quote of a stock waiting for review, or nil if there is none
+func (r *StockRepository) GetPendingQuarantinedQuote(stockID uint) (*models.QuarantinedQuote, error) {
+	var quote models.QuarantinedQuote
+	found := r.db.Where("stock_id = ? AND status = ?", stockID, models.QuarantinePending).Limit(1).Find(&quote)
+	if found.Error != nil {
+		return nil, fmt.Errorf("failed to fetch quarantined quote of stock %d: %w", stockID, found.Error)
+	}
+	if found.RowsAffected == 0 {
+		return nil, nil
+	}
+	return &quote, nil
+}
+
+// SaveQuarantinedQuote creates or updates a quarantined quote
+func (r *StockRepository) SaveQuarantinedQuote(quote *models.QuarantinedQuote) error {
+	if err := r.db.Save(quote).Error; err != nil {
+		return fmt.Errorf("failed to save quarantined quote: %w", err)
+	}
+	return nil
+}
+
+// GetQuarantinedQuote gets a quarantined quote with its stock
+func (r *StockRepository) GetQuarantinedQuote(quoteID uint) (*models.QuarantinedQuote, error) {
+	var quote models.QuarantinedQuote
+	if err := r.db.Preload("Stock").First(&quote, quoteID).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch quarantined quote %d: %w", quoteID, err)
+	}
+	return &quote, nil
+}
+
+// GetQuarantinedQuotes gets the quarantined quotes with a status, or all of them for an empty status, newest first
+func (r *StockRepository) GetQuarantinedQuotes(status models.QuarantineStatus) ([]models.QuarantinedQuote, error) {
+	query := r.db.Preload("Stock").Order("created_at DESC, id DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	var quotes []models.QuarantinedQuote
+	if err := query.Find(&quotes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch quarantined quotes: %w", err)
+	}
+	return quotes, nil
+}
+
+// ResolveQuarantinedQuote records the review of a pending quarantined quote, in a transaction. An approved
+// quote is added to the price history, and becomes the current price unless a later price has arrived since.
+func (r *StockRepository) ResolveQuarantinedQuote(quote *models.QuarantinedQuote) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Only one review of a quote can win
+		resolved := tx.Model(&models.QuarantinedQuote{}).
+			Where("id = ? AND status = ?", quote.ID, models.QuarantinePending).
+			Updates(map[string]interface{}{"status": quote.Status, "reviewed_by": quote.ReviewedBy, "reviewed_at": quote.ReviewedAt})
+		if resolved.Error != nil {
+			return resolved.Error
+		}
+		if resolved.RowsAffected == 0 {
+			return fmt.Errorf("quote %d has already been reviewed", quote.ID)
+		}
+		if quote.Status != models.QuarantineApproved {
+			return nil
+		}
+		return recordPastPrice(tx, quote.StockID, quote.Price, quote.Timestamp)
+	})
+}
+
+// RecordHistoricalPrice adds a price recorded at a past time, unless the stock already has a price at
+// exactly that time. It reports whether the price was added.
+func (r *StockRepository) RecordHistoricalPrice(stockID uint, price float64, at time.Time) (bool, error) {
+	added := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var existing int64
+		if err := tx.Model(&models.PriceHistory{}).Where("stock_id = ? AND timestamp = ?", stockID, at).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return nil
+		}
+		added = true
+		return recordPastPrice(tx, stockID, price, at)
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to record price of stock %d at %s: %w", stockID, at, err)
+	}
+	return added, nil
+}
+
+// recordPastPrice adds a price to the history, and makes it the current price unless a later price is recorded
+func recordPastPrice(tx *gorm.DB, stockID uint, price float64, at time.Time) error {
+	var later int64
+	if err := tx.Model(&models.PriceHistory{}).
+		Where("stock_id = ? AND timestamp > ?", stockID, at).
+		Count(&later).Error; err != nil {
+		return err
+	}
+	if err := tx.Create(&models.PriceHistory{StockID: stockID, Price: price, Timestamp: at}).Error; err != nil {
+		return err
+	}
+	if later > 0 {
+		return nil
+	}
+	return tx.Model(&models.Stock{}).Where("id = ?", stockID).Update("current_price", price).Error
+}
+
 // GetAllStocks retrieves all stocks from the database.
 func (r *StockRepository) GetAllStocks() ([]models.Stock, error) {
 	var stocks []models.Stock
@@ -99,3 +402,300 @@ func (r *StockRepository) GetAllStocks() ([]models.Stock, error) {
 	}
 	return stocks, nil
 }
+
+// GetActiveStocks retrieves every stock that has not been delisted.
+func (r *StockRepository) GetActiveStocks() ([]models.Stock, error) {
+	var stocks []models.Stock
+	if err := r.db.Where("delisted_at IS NULL").Find(&stocks).Error; err != nil {
+		return nil, err
+	}
+	return stocks, nil
+}
+
+// GetUniverseStocks retrieves the stocks currently in a universe that have not been delisted.
+func (r *StockRepository) GetUniverseStocks(universe string) ([]models.Stock, error) {
+	var stocks []models.Stock
+	err := r.db.
+		Joins("JOIN universe_members ON universe_members.stock_id = stocks.id").
+		Where("universe_members.universe = ? AND universe_members.removed_at IS NULL AND stocks.delisted_at IS NULL", universe).
+		Order("stocks.ticker_symbol ASC").
+		Find(&stocks).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve stocks of universe %s: %w", universe, err)
+	}
+	return stocks, nil
+}
+
+// ApplyUniverse brings the stocks and membership of a universe in line with its rows in a transaction.
+// Ticker changes rename the existing stock so its ID, and everything referencing it, is kept.
+func (r *StockRepository) ApplyUniverse(universe string, rows []UniverseRow, at time.Time) (*UniverseImportResult, error) {
+	result := &UniverseImportResult{
+		Universe: universe,
+		Created:  []string{},
+		Updated:  []string{},
+		Renamed:  []string{},
+		Delisted: []string{},
+		Pending:  []string{},
+		Removed:  []string{},
+		Skipped:  []UniverseSkip{},
+	}
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		members := make(map[uint]bool)
+		for _, row := range rows {
+			skip := func(reason string) {
+				result.Skipped = append(result.Skipped, UniverseSkip{Line: row.Line, Symbol: row.Symbol, Reason: reason})
+			}
+			effective := at
+			if row.EffectiveDate != nil {
+				effective = *row.EffectiveDate
+			}
+
+			stock, err := findStockByTicker(tx, row.Symbol)
+			if err != nil {
+				return err
+			}
+
+			// Step 1: Follow a ticker change onto the stock stored under the old symbol. A change that has not
+			// taken effect yet is recorded as pending, and the stock keeps trading under the old symbol until then.
+			if row.PreviousSymbol != "" && row.PreviousSymbol != row.Symbol {
+				previous, err := findStockByTicker(tx, row.PreviousSymbol)
+				if err != nil {
+					return err
+				}
+				if previous != nil && stock != nil {
+					skip(fmt.Sprintf("both %s and %s exist, merge them by hand", row.PreviousSymbol, row.Symbol))
+					continue
+				}
+				if previous != nil {
+					// Importing the announcement again replaces the pending change rather than adding another
+					if err := tx.Where("stock_id = ? AND pending = ?", previous.ID, true).Delete(&models.TickerChange{}).Error; err != nil {
+						return fmt.Errorf("failed to replace pending ticker change of %s: %w", row.PreviousSymbol, err)
+					}
+					pending := effective.After(at)
+					if !pending {
+						previous.TickerSymbol = row.Symbol
+						if err := tx.Save(previous).Error; err != nil {
+							return fmt.Errorf("failed to rename %s to %s: %w", row.PreviousSymbol, row.Symbol, err)
+						}
+					}
+					change := models.TickerChange{StockID: previous.ID, OldSymbol: row.PreviousSymbol, NewSymbol: row.Symbol, EffectiveDate: effective, Pending: pending}
+					if err := tx.Create(&change).Error; err != nil {
+						return fmt.Errorf("failed to record ticker change of %s: %w", row.PreviousSymbol, err)
+					}
+					if pending {
+						result.Pending = append(result.Pending, fmt.Sprintf("%s->%s on %s", row.PreviousSymbol, row.Symbol, effective.Format(marketdata.DateFormat)))
+					} else {
+						result.Renamed = append(result.Renamed, row.PreviousSymbol+"->"+row.Symbol)
+					}
+					stock = previous
+				}
+			}
+
+			// Step 2: Create or update the stock
+			if stock == nil {
+				if row.Delisted {
+					skip("delisted stock was never listed")
+					continue
+				}
+				stock = &models.Stock{TickerSymbol: row.Symbol}
+				applyUniverseDetails(stock, row)
+				if err := tx.Create(stock).Error; err != nil {
+					return fmt.Errorf("failed to create %s: %w", row.Symbol, err)
+				}
+				result.Created = append(result.Created, row.Symbol)
+			} else {
+				applyUniverseDetails(stock, row)
+				if !row.Delisted {
+					stock.DelistedAt = nil
+					stock.DelistsAt = nil
+				}
+				if err := tx.Save(stock).Error; err != nil {
+					return fmt.Errorf("failed to update %s: %w", row.Symbol, err)
+				}
+				result.Updated = append(result.Updated, row.Symbol)
+			}
+
+			// Step 3: Delisted stocks stay in the database but leave the universe. A delisting that has not
+			// taken effect yet is kept on the stock, which stays in the universe until then.
+			if row.Delisted {
+				if stock.DelistedAt != nil {
+					continue
+				}
+				if !effective.After(at) {
+					stock.DelistedAt = &effective
+					stock.DelistsAt = nil
+					if err := tx.Model(stock).Updates(map[string]interface{}{"delisted_at": effective, "delists_at": nil}).Error; err != nil {
+						return fmt.Errorf("failed to delist %s: %w", row.Symbol, err)
+					}
+					result.Delisted = append(result.Delisted, row.Symbol)
+					continue
+				}
+				stock.DelistsAt = &effective
+				if err := tx.Model(stock).Update("delists_at", effective).Error; err != nil {
+					return fmt.Errorf("failed to schedule the delisting of %s: %w", row.Symbol, err)
+				}
+				result.Pending = append(result.Pending, fmt.Sprintf("%s delisted on %s", row.Symbol, effective.Format(marketdata.DateFormat)))
+			}
+
+			// Step 4: Make sure the stock is a member of the universe
+			var member models.UniverseMember
+			err = tx.Where("universe = ? AND stock_id = ?", universe, stock.ID).Limit(1).Find(&member).Error
+			if err != nil {
+				return fmt.Errorf("failed to read universe membership of %s: %w", row.Symbol, err)
+			}
+			if member.ID == 0 {
+				member = models.UniverseMember{Universe: universe, StockID: stock.ID, AddedAt: at}
+				if err := tx.Create(&member).Error; err != nil {
+					return fmt.Errorf("failed to add %s to %s: %w", row.Symbol, universe, err)
+				}
+			} else if member.RemovedAt != nil {
+				if err := tx.Model(&member).Updates(map[string]interface{}{"removed_at": nil, "added_at": at}).Error; err != nil {
+					return fmt.Errorf("failed to add %s back to %s: %w", row.Symbol, universe, err)
+				}
+			}
+			members[stock.ID] = true
+		}
+
+		// Step 5: Stocks missing from the file, or delisted, leave the universe
+		var current []models.UniverseMember
+		if err := tx.Preload("Stock").Where("universe = ? AND removed_at IS NULL", universe).Find(&current).Error; err != nil {
+			return fmt.Errorf("failed to read members of %s: %w", universe, err)
+		}
+		for _, member := range current {
+			if members[member.StockID] {
+				continue
+			}
+			if err := tx.Model(&models.UniverseMember{}).Where("id = ?", member.ID).Update("removed_at", at).Error; err != nil {
+				return fmt.Errorf("failed to remove %s from %s: %w", member.Stock.TickerSymbol, universe, err)
+			}
+			if member.Stock.DelistedAt == nil {
+				result.Removed = append(result.Removed, member.Stock.TickerSymbol)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ApplyPendingUniverseChanges renames the stocks whose pending ticker change took effect by at, and delists the
+// stocks whose announced delisting did, taking them out of every universe. It returns how many of each it applied.
+func (r *StockRepository) ApplyPendingUniverseChanges(at time.Time) (int, int, error) {
+	renamed, delisted := 0, 0
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var changes []models.TickerChange
+		err := tx.Where("pending = ? AND effective_date <= ?", true, at).Order("effective_date ASC, id ASC").Find(&changes).Error
+		if err != nil {
+			return fmt.Errorf("failed to read pending ticker changes: %w", err)
+		}
+		for _, change := range changes {
+			taken, err := findStockByTicker(tx, change.NewSymbol)
+			if err != nil {
+				return err
+			}
+			if taken != nil {
+				log.Printf("Universe: cannot rename %s to %s, both exist, merge them by hand", change.OldSymbol, change.NewSymbol)
+				continue
+			}
+			if err := tx.Model(&models.Stock{}).Where("id = ?", change.StockID).Update("ticker_symbol", change.NewSymbol).Error; err != nil {
+				return fmt.Errorf("failed to rename %s to %s: %w", change.OldSymbol, change.NewSymbol, err)
+			}
+			if err := tx.Model(&models.TickerChange{}).Where("id = ?", change.ID).Update("pending", false).Error; err != nil {
+				return fmt.Errorf("failed to apply ticker change of %s: %w", change.OldSymbol, err)
+			}
+			renamed++
+		}
+
+		var stocks []models.Stock
+		if err := tx.Where("delisted_at IS NULL AND delists_at <= ?", at).Find(&stocks).Error; err != nil {
+			return fmt.Errorf("failed to read pending delistings: %w", err)
+		}
+		for _, stock := range stocks {
+			err := tx.Model(&models.Stock{}).Where("id = ?", stock.ID).
+				Updates(map[string]interface{}{"delisted_at": *stock.DelistsAt, "delists_at": nil}).Error
+			if err != nil {
+				return fmt.Errorf("failed to delist %s: %w", stock.TickerSymbol, err)
+			}
+			err = tx.Model(&models.UniverseMember{}).Where("stock_id = ? AND removed_at IS NULL", stock.ID).
+				Update("removed_at", *stock.DelistsAt).Error
+			if err != nil {
+				return fmt.Errorf("failed to take %s out of its universes: %w", stock.TickerSymbol, err)
+			}
+			delisted++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+	return renamed, delisted, nil
+}
+
+// findStockByTicker looks a stock up by ticker, returning nil when there is none
+func findStockByTicker(tx *gorm.DB, tickerSymbol string) (*models.Stock, error) {
+	var stocks []models.Stock
+	if err := tx.Where("ticker_symbol = ?", tickerSymbol).Limit(1).Find(&stocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to find stock with ticker %s: %w", tickerSymbol, err)
+	}
+	if len(stocks) == 0 {
+		return nil, nil
+	}
+	return &stocks[0], nil
+}
+
+// applyUniverseDetails copies the details a row has onto a stock, keeping what the row leaves blank
+func applyUniverseDetails(stock *models.Stock, row UniverseRow) {
+	if row.CompanyName != "" {
+		stock.CompanyName = row.CompanyName
+	}
+	if row.Sector != "" {
+		stock.Sector = row.Sector
+	}
+	if row.Industry != "" {
+		stock.Industry = row.Industry
+	}
+	if row.Exchange != "" {
+		stock.Exchange = row.Exchange
+	}
+	if row.MarketCap > 0 {
+		stock.MarketCap = row.MarketCap
+	}
+	if row.LogoURL != "" {
+		stock.LogoURL = row.LogoURL
+	}
+}
+
+// SelectStocks retrieves the stocks still trading that match every criterion of a selection, by ticker.
+func (r *StockRepository) SelectStocks(selection models.StockSelection) ([]models.Stock, error) {
+	query := r.db.Model(&models.Stock{}).Where("stocks.delisted_at IS NULL")
+	if selection.Universe != "" {
+		query = query.
+			Joins("JOIN universe_members ON universe_members.stock_id = stocks.id").
+			Where("universe_members.universe = ? AND universe_members.removed_at IS NULL", selection.Universe)
+	}
+	if len(selection.Sectors) > 0 {
+		sectors := make([]string, len(selection.Sectors))
+		for i, sector := range selection.Sectors {
+			sectors[i] = strings.ToLower(strings.TrimSpace(sector))
+		}
+		query = query.Where("LOWER(stocks.sector) IN ?", sectors)
+	}
+	if selection.MinMarketCap != nil {
+		query = query.Where("stocks.market_cap >= ?", *selection.MinMarketCap)
+	}
+	if selection.MaxMarketCap != nil {
+		query = query.Where("stocks.market_cap <= ?", *selection.MaxMarketCap)
+	}
+	if len(selection.StockIDs) > 0 {
+		query = query.Where("stocks.id IN ?", selection.StockIDs)
+	}
+
+	var stocks []models.Stock
+	if err := query.Order("stocks.ticker_symbol ASC").Find(&stocks).Error; err != nil {
+		return nil, fmt.Errorf("failed to select stocks: %w", err)
+	}
+	return stocks, nil
+}
@@ -1,26 +1,40 @@
 package stock
 
 import (
+	"context"
 	"errors"
+	"log"
+	"math"
 	"time"
 
+	"github.com/market-league/internal/marketdata"
 	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
 )
 
 // StockService handles business logic related to stocks.
 type StockService struct {
-	StockRepo *StockRepository // Reference to the repository layer
+	StockRepo  *StockRepository              // Reference to the repository layer
+	MarketData marketdata.MarketDataProvider // Source of quotes and company profiles
+	Rules      PriceRules                    // Checks quotes pass before RecordQuote records them
 }
 
 // NewStockService creates a new instance of StockService.
-func NewStockService(repo *StockRepository) *StockService {
-	return &StockService{StockRepo: repo}
+func NewStockService(repo *StockRepository, marketData marketdata.MarketDataProvider) *StockService {
+	return &StockService{StockRepo: repo, MarketData: marketData, Rules: PriceRulesFromEnv()}
 }
 
-func (s *StockService) CreateStock(tickerSymbol string, companyName string) (*models.Stock, error) {
+func (s *StockService) CreateStock(tickerSymbol string, companyName string, sector string, industry string) (*models.Stock, error) {
 	stock := &models.Stock{
 		TickerSymbol: tickerSymbol,
 		CompanyName:  companyName,
+		Sector:       sector,
+		Industry:     industry,
+	}
+
+	// Fill in whatever the caller left out from the company profile
+	if stock.CompanyName == "" || stock.Sector == "" || stock.Industry == "" {
+		s.fillFromProfile(stock)
 	}
 
 	err := s.StockRepo.CreateStock(stock)
@@ -36,12 +50,12 @@ func (s *StockService) CreateMultipleStocks(stocks []*models.Stock) error {
 }
 
 func (s *StockService) UpdateStockPrice(stockID uint, newPrice float64, timestamp *time.Time) error {
-	if newPrice < 0 {
-		return errors.New("new price must be non-negative")
+	if newPrice < 0 || math.IsNaN(newPrice) || math.IsInf(newPrice, 0) {
+		return errors.New("new price must be a non-negative number")
 	}
 
 	if timestamp != nil {
-		now := time.Now().UTC()
+		now := utils.Now().UTC()
 		providedTime := timestamp.UTC()
 
 		if providedTime.After(now) {
@@ -56,12 +70,34 @@ func (s *StockService) UpdateStockPrice(stockID uint, newPrice float64, timestam
 	return s.StockRepo.UpdateCurrentPrice(stockID, newPrice, timestamp)
 }
 
+// fillFromProfile copies the company name, sector and industry of a stock from its provider profile, best effort
+func (s *StockService) fillFromProfile(stock *models.Stock) {
+	if s.MarketData == nil {
+		return
+	}
+	profile, err := s.MarketData.GetCompanyProfile(context.Background(), stock.TickerSymbol)
+	if err != nil {
+		log.Printf("Unable to fetch company profile for %s: %v", stock.TickerSymbol, err)
+		return
+	}
+	if stock.CompanyName == "" {
+		stock.CompanyName = profile.Name
+	}
+	if stock.Sector == "" {
+		stock.Sector = profile.Sector
+	}
+	if stock.Industry == "" {
+		stock.Industry = profile.Industry
+	}
+}
+
+// GetStockInfo gets a stock with its last StockInfoHistoryDays of prices, longer charts come from GetCandles
 func (s *StockService) GetStockInfo(stockID uint) (models.Stock, error) {
 	if stockID == 0 {
 		return models.Stock{}, errors.New("invalid stock ID")
 	}
 
-	stock, err := s.StockRepo.GetStockWithHistory(stockID)
+	stock, err := s.StockRepo.GetStockWithHistory(stockID, utils.Now().AddDate(0, 0, -StockInfoHistoryDays))
 	if err != nil {
 		return models.Stock{}, err
 	}
@@ -0,0 +1,196 @@
+package stock
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/market-league/internal/marketdata"
+	"github.com/market-league/internal/utils"
+)
+
+// UniverseRow is one stock of a universe file
+type UniverseRow struct {
+	Line           int    // Line of the file, for reporting
+	Symbol         string // Ticker the stock trades under now
+	PreviousSymbol string // Ticker it traded under before a ticker change, if any
+	CompanyName    string // Blank details leave what is stored alone
+	Sector         string
+	Industry       string
+	Exchange       string
+	MarketCap      float64 // In millions of USD, 0 when unknown
+	LogoURL        string
+	Delisted       bool       // The stock no longer trades
+	EffectiveDate  *time.Time // When a ticker change or delisting took effect, the import time if nil
+}
+
+// UniverseSkip is a row the import could not apply
+type UniverseSkip struct {
+	Line   int    `json:"line"`
+	Symbol string `json:"symbol"`
+	Reason string `json:"reason"`
+}
+
+// UniverseImportResult lists what an import changed, by ticker
+type UniverseImportResult struct {
+	Universe string         `json:"universe"`
+	Created  []string       `json:"created"`
+	Updated  []string       `json:"updated"`
+	Renamed  []string       `json:"renamed"`  // As OLD->NEW
+	Delisted []string       `json:"delisted"` // Stocks marked as no longer trading
+	Pending  []string       `json:"pending"`  // Ticker changes and delistings waiting on their effective date
+	Removed  []string       `json:"removed"`  // Stocks that left the universe but still trade
+	Skipped  []UniverseSkip `json:"skipped"`
+}
+
+// universeColumns maps the accepted header names of each universe field
+var universeColumns = map[string][]string{
+	"symbol":          {"symbol", "ticker", "ticker_symbol"},
+	"previous_symbol": {"previous_symbol", "old_symbol", "previous_ticker"},
+	"name":            {"name", "company_name", "security"},
+	"sector":          {"sector"},
+	"industry":        {"industry", "sub_industry"},
+	"exchange":        {"exchange"},
+	"market_cap":      {"market_cap", "market_cap_millions"},
+	"logo_url":        {"logo_url", "logo"},
+	"status":          {"status"},
+	"effective_date":  {"effective_date", "date"},
+}
+
+// ParseUniverseCSV reads a universe from CSV with a header row. Only a symbol column is required; name,
+// sector, industry, exchange, market_cap (millions of USD) and logo_url fill in stock details. A
+// previous_symbol marks a ticker change and a status of "delisted" marks a delisting, both taking
+// effect on the optional effective_date (YYYY-MM-DD).
+func ParseUniverseCSV(reader io.Reader) ([]UniverseRow, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read universe header: %v", err)
+	}
+	headerIndex := make(map[string]int, len(header))
+	for index, name := range header {
+		headerIndex[strings.ToLower(strings.TrimSpace(name))] = index
+	}
+	columns := make(map[string]int)
+	for field, aliases := range universeColumns {
+		for _, alias := range aliases {
+			if index, ok := headerIndex[alias]; ok {
+				columns[field] = index
+				break
+			}
+		}
+	}
+	if _, ok := columns["symbol"]; !ok {
+		return nil, fmt.Errorf("universe CSV is missing the symbol column")
+	}
+
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return nil, fmt.Errorf("error loading time location: %v", err)
+	}
+
+	var rows []UniverseRow
+	line := 1
+	for {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		line++
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+
+		field := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[index])
+		}
+
+		row := UniverseRow{
+			Line:           line,
+			Symbol:         strings.ToUpper(field("symbol")),
+			PreviousSymbol: strings.ToUpper(field("previous_symbol")),
+			CompanyName:    field("name"),
+			Sector:         field("sector"),
+			Industry:       field("industry"),
+			Exchange:       field("exchange"),
+			LogoURL:        field("logo_url"),
+		}
+		if row.Symbol == "" {
+			return nil, fmt.Errorf("line %d: symbol is required", line)
+		}
+		if value := field("market_cap"); value != "" {
+			row.MarketCap, err = strconv.ParseFloat(strings.ReplaceAll(value, ",", ""), 64)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid market_cap: %v", line, err)
+			}
+		}
+		switch status := strings.ToLower(field("status")); status {
+		case "", "active", "listed":
+		case "delisted":
+			row.Delisted = true
+		default:
+			return nil, fmt.Errorf("line %d: unknown status %q", line, status)
+		}
+		if value := field("effective_date"); value != "" {
+			date, err := time.ParseInLocation(marketdata.DateFormat, value, location)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid effective_date: %v", line, err)
+			}
+			row.EffectiveDate = &date
+		}
+		rows = append(rows, row)
+	}
+
+	return rows, nil
+}
+
+// ImportUniverse creates and updates the stocks of a universe from CSV, applies its ticker changes and
+// delistings, and takes stocks missing from the file out of the universe. Ticker changes and delistings
+// dated in the future wait for ApplyPendingUniverseChanges. Stocks are never deleted, so ownership
+// history and price history stay intact.
+func (s *StockService) ImportUniverse(universe string, reader io.Reader) (*UniverseImportResult, error) {
+	universe = strings.TrimSpace(universe)
+	if universe == "" {
+		return nil, fmt.Errorf("a universe name is required")
+	}
+	rows, err := ParseUniverseCSV(reader)
+	if err != nil {
+		return nil, err
+	}
+	return s.StockRepo.ApplyUniverse(universe, rows, utils.Now())
+}
+
+// ApplyPendingUniverseChanges renames and delists the stocks whose ticker change or delisting has taken effect
+func (s *StockService) ApplyPendingUniverseChanges() error {
+	renamed, delisted, err := s.StockRepo.ApplyPendingUniverseChanges(utils.Now())
+	if err != nil {
+		return err
+	}
+	if renamed > 0 || delisted > 0 {
+		log.Printf("Universe: applied %d ticker changes and %d delistings", renamed, delisted)
+	}
+	return nil
+}
+
+// ImportUniverseFile imports a universe from a CSV file on disk
+func (s *StockService) ImportUniverseFile(universe string, path string) (*UniverseImportResult, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open universe file: %v", err)
+	}
+	defer file.Close()
+
+	return s.ImportUniverse(universe, file)
+}
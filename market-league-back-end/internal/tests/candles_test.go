@@ -0,0 +1,77 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCandles_AggregatesInMarketTime(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}, &models.DailyPriceBar{}))
+	repo := stock.NewStockRepository(db)
+	service := stock.NewStockService(repo, nil)
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+
+	s := models.Stock{TickerSymbol: "AAA"}
+	assert.NoError(t, db.Create(&s).Error)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, location)
+	}
+	for _, point := range []models.PriceHistory{
+		{Timestamp: at(3, 9, 0), Price: 10},
+		{Timestamp: at(3, 9, 30), Price: 12},
+		{Timestamp: at(3, 10, 0), Price: 9},
+		{Timestamp: at(3, 15, 30), Price: 11},
+		{Timestamp: at(4, 15, 30), Price: 13},
+		{Timestamp: at(10, 15, 30), Price: 8},
+	} {
+		point.StockID = s.ID
+		assert.NoError(t, db.Create(&point).Error)
+	}
+
+	from, to := at(3, 0, 0), at(11, 0, 0)
+	daily, err := service.GetCandles(s.ID, models.CandleInterval_Day, &from, &to)
+	assert.NoError(t, err)
+	assert.Equal(t, models.CandleInterval_Day, daily.Interval)
+	assert.Len(t, daily.Candles, 3)
+	assert.Equal(t, models.Candle{Time: at(3, 0, 0), Open: 10, High: 12, Low: 9, Close: 11, Points: 4}, daily.Candles[0])
+
+	hourly, err := service.GetCandles(s.ID, models.CandleInterval_Hour, &from, &to)
+	assert.NoError(t, err)
+	assert.Len(t, hourly.Candles, 5)
+	assert.Equal(t, 2, hourly.Candles[0].Points)
+
+	// Weeks start on Monday the 3rd and the 10th
+	weekly, err := service.GetCandles(s.ID, models.CandleInterval_Week, &from, &to)
+	assert.NoError(t, err)
+	assert.Len(t, weekly.Candles, 2)
+	assert.Equal(t, 13.0, weekly.Candles[0].Close)
+	assert.Equal(t, at(10, 0, 0), weekly.Candles[1].Time)
+
+	_, err = service.GetCandles(s.ID, "5m", &from, &to)
+	assert.Error(t, err)
+	_, err = service.GetCandles(s.ID, models.CandleInterval_Day, &to, &from)
+	assert.Error(t, err)
+}
+
+func TestMergeCandles_DownsamplesLongRanges(t *testing.T) {
+	start := time.Date(2025, time.January, 6, 0, 0, 0, 0, time.UTC)
+	candles := []models.Candle{}
+	for i := 0; i < 5; i++ {
+		price := float64(10 + i)
+		candles = append(candles, models.Candle{Time: start.AddDate(0, 0, 7*i), Open: price, High: price + 1, Low: price - 1, Close: price, Points: 1})
+	}
+
+	merged := stock.MergeCandles(candles, 2)
+	assert.Len(t, merged, 3)
+	assert.Equal(t, models.Candle{Time: start, Open: 10, High: 12, Low: 9, Close: 11, Points: 2}, merged[0])
+	assert.Equal(t, 14.0, merged[2].Close)
+	assert.Equal(t, 1, merged[2].Points)
+}
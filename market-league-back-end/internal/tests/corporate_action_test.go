@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	corporate_action "github.com/market-league/internal/corporate_action"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestApplySplitAndDividend_ScoringWindows(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}, &models.OwnershipHistory{}, &models.ScoringWindow{}, &models.CorporateAction{}, &models.DailyPriceBar{}))
+
+	exDate := time.Date(2025, 6, 10, 0, 0, 0, 0, time.UTC)
+	before := exDate.AddDate(0, 0, -5)
+	assert.NoError(t, db.Create(&models.Stock{ID: 1, TickerSymbol: "AAPL", CurrentPrice: 200}).Error)
+	assert.NoError(t, db.Create(&models.PriceHistory{StockID: 1, Price: 200, Timestamp: before}).Error)
+	assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: 1, StockID: 1, StartingValue: 100, CurrentValue: 200, StartDate: before}).Error)
+
+	repo := corporate_action.NewCorporateActionRepository(db)
+
+	// A 2:1 split with no post-split quote halves every stored price
+	split := &models.CorporateAction{StockID: 1, Type: models.SplitAction, ExDate: exDate, Ratio: 2}
+	created, err := repo.CreateIfNotExists(split)
+	assert.NoError(t, err)
+	assert.True(t, created)
+	assert.NoError(t, repo.ApplySplit(split))
+
+	// Recording the same split again is a no-op
+	created, err = repo.CreateIfNotExists(&models.CorporateAction{StockID: 1, Type: models.SplitAction, ExDate: exDate, Ratio: 2})
+	assert.NoError(t, err)
+	assert.False(t, created)
+
+	dividend := &models.CorporateAction{StockID: 1, Type: models.DividendAction, ExDate: exDate.AddDate(0, 0, 1), Amount: 0.5}
+	_, err = repo.CreateIfNotExists(dividend)
+	assert.NoError(t, err)
+	assert.NoError(t, repo.ApplyDividend(dividend))
+
+	var window models.ScoringWindow
+	assert.NoError(t, db.First(&window).Error)
+	assert.Equal(t, 50.0, window.StartingValue)
+	assert.Equal(t, 100.0, window.CurrentValue)
+	assert.Equal(t, 0.5, window.Dividends)
+
+	var stock models.Stock
+	assert.NoError(t, db.First(&stock, 1).Error)
+	assert.Equal(t, 100.0, stock.CurrentPrice)
+
+	pending, err := repo.GetPendingActions(exDate.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestApplyPendingActions_SplitDayIntradayQuote(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}, &models.OwnershipHistory{}, &models.ScoringWindow{}, &models.CorporateAction{}, &models.DailyPriceBar{}, &models.QuarantinedQuote{}))
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+
+	exDate := time.Date(2025, 6, 10, 0, 0, 0, 0, location)
+	assert.NoError(t, db.Create(&models.Stock{ID: 1, TickerSymbol: "AAPL", CurrentPrice: 200}).Error)
+	assert.NoError(t, db.Create(&models.PriceHistory{StockID: 1, Price: 200, Timestamp: exDate.Add(-9 * time.Hour)}).Error)
+	assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: 1, StockID: 1, StartingValue: 160, CurrentValue: 200, StartDate: exDate.AddDate(0, 0, -7)}).Error)
+	assert.NoError(t, db.Create(&models.CorporateAction{StockID: 1, Type: models.SplitAction, ExDate: exDate, Ratio: 4}).Error)
+
+	// The first poll of the ex-date, hours before the daily run applies the split
+	firstPoll := exDate.Add(8*time.Hour + 35*time.Minute)
+	utils.SetClock(utils.NewSimulatedClock(firstPoll, 1))
+	defer utils.SetClock(nil)
+
+	stockRepo := stock.NewStockRepository(db)
+	stockService := stock.NewStockService(stockRepo, nil)
+	stockService.Rules = stock.DefaultPriceRules()
+	service := corporate_action.NewCorporateActionService(corporate_action.NewCorporateActionRepository(db), stockRepo, nil)
+
+	// Intraday polling applies the split first, so the post-split quote is a small move rather than a 75% drop
+	assert.NoError(t, service.ApplyPendingActions())
+	quote, err := stockService.RecordQuote(1, 51, &firstPoll)
+	assert.NoError(t, err)
+	assert.Nil(t, quote)
+
+	var s models.Stock
+	assert.NoError(t, db.First(&s, 1).Error)
+	assert.Equal(t, 51.0, s.CurrentPrice)
+	var window models.ScoringWindow
+	assert.NoError(t, db.First(&window).Error)
+	assert.Equal(t, 40.0, window.StartingValue)
+
+	// The daily run finds nothing left to apply
+	pending, err := corporate_action.NewCorporateActionRepository(db).GetPendingActions(exDate.Add(15*time.Hour + 30*time.Minute))
+	assert.NoError(t, err)
+	assert.Empty(t, pending)
+}
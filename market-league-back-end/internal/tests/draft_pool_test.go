@@ -0,0 +1,74 @@
+package tests
+
+import (
+	"testing"
+
+	league_portfolio "github.com/market-league/internal/league_portfolio"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/user"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDraftPool_OwnerPicksStocksBeforeDraft(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.User{}, &models.League{}, &models.RosterPosition{},
+		&models.LeaguePortfolio{}, &models.UniverseMember{}))
+	stockRepo := stock.NewStockRepository(db)
+	service := league_portfolio.NewLeaguePortfolioService(league_portfolio.NewLeaguePortfolioRepository(db),
+		stockRepo, nil, nil, nil, nil, user.NewUserRepository(db))
+
+	for _, s := range []models.Stock{
+		{TickerSymbol: "AAA", Sector: "Technology", MarketCap: 3000},
+		{TickerSymbol: "BBB", Sector: "Technology", MarketCap: 800},
+		{TickerSymbol: "CCC", Sector: "Energy", MarketCap: 1200},
+		{TickerSymbol: "DDD", Sector: "Financials", MarketCap: 50},
+	} {
+		assert.NoError(t, db.Create(&s).Error)
+	}
+	owner := models.User{Username: "owner", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	admin := models.User{Username: "admin", Password: "x", IsAdmin: true}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &other, &admin}).Error)
+	league := models.League{LeagueName: "Pool", OwnerID: owner.ID, StartingSlots: 2, LeagueState: models.PreDraft}
+	assert.NoError(t, db.Create(&league).Error)
+
+	// The zero selection drafts from every stock
+	created, err := service.CreateLeaguePortfolio(league.ID, models.StockSelection{})
+	assert.NoError(t, err)
+	pool, err := service.GetLeaguePortfolioInfo(league.ID)
+	assert.NoError(t, err)
+	assert.Len(t, pool.Stocks, 4)
+	assert.Equal(t, created.ID, pool.ID)
+
+	// Criteria are combined, sectors ignore case
+	minCap := 500.0
+	selection := models.StockSelection{Sectors: []string{"technology", "ENERGY"}, MinMarketCap: &minCap}
+	updated, err := service.SetDraftPool(league.ID, owner.ID, selection)
+	assert.NoError(t, err)
+	tickers := []string{}
+	for _, s := range updated.Stocks {
+		tickers = append(tickers, s.TickerSymbol)
+	}
+	assert.ElementsMatch(t, []string{"AAA", "BBB", "CCC"}, tickers)
+	assert.Equal(t, []string{"technology", "ENERGY"}, updated.PoolSelection.Sectors)
+
+	// Only the owner or an admin may change it
+	_, err = service.SetDraftPool(league.ID, other.ID, models.StockSelection{})
+	assert.Error(t, err)
+	_, err = service.SetDraftPool(league.ID, admin.ID, models.StockSelection{})
+	assert.NoError(t, err)
+
+	// The pool has to fill a roster
+	_, err = service.SetDraftPool(league.ID, owner.ID, models.StockSelection{Sectors: []string{"Energy"}})
+	assert.ErrorContains(t, err, "a roster holds 2")
+	maxCap := 100.0
+	_, err = service.SetDraftPool(league.ID, owner.ID, models.StockSelection{MinMarketCap: &minCap, MaxMarketCap: &maxCap})
+	assert.Error(t, err)
+
+	// Once the draft starts the pool is locked
+	assert.NoError(t, db.Model(&league).Update("league_state", models.InDraft).Error)
+	_, err = service.SetDraftPool(league.ID, owner.ID, models.StockSelection{})
+	assert.ErrorContains(t, err, "locked")
+}
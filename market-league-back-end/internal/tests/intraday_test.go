@@ -0,0 +1,76 @@
+package tests
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMarketHours(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+
+	friday := time.Date(2025, 3, 7, 0, 0, 0, 0, location)
+	assert.False(t, utils.IsMarketOpen(friday.Add(8*time.Hour+29*time.Minute)))
+	assert.True(t, utils.IsMarketOpen(friday.Add(8*time.Hour+30*time.Minute)))
+	assert.True(t, utils.IsMarketOpen(friday.Add(14*time.Hour+59*time.Minute)))
+	assert.False(t, utils.IsMarketOpen(friday.Add(15*time.Hour)))
+	assert.False(t, utils.IsMarketOpen(friday.AddDate(0, 0, 1).Add(10*time.Hour)))
+
+	// Before the open it is today's open, after the close it skips the weekend
+	assert.Equal(t, friday.Add(8*time.Hour+30*time.Minute), utils.NextMarketOpen(friday.Add(7*time.Hour)))
+	assert.Equal(t, time.Date(2025, 3, 10, 8, 30, 0, 0, location), utils.NextMarketOpen(friday.Add(16*time.Hour)))
+	assert.Equal(t, time.Date(2025, 3, 10, 8, 30, 0, 0, location), utils.NextMarketOpen(friday.Add(9*time.Hour)))
+}
+
+func TestBroadcastPriceTicks_OnlySubscribedStocks(t *testing.T) {
+	upgrader := websocket.Upgrader{}
+	connected := make(chan *ws.Connection, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawConn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		conn := &ws.Connection{Ws: rawConn, Subscriptions: make(map[uint]bool)}
+		ws.Manager.Register(conn)
+		connected <- conn
+	}))
+	defer server.Close()
+
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	defer client.Close()
+	conn := <-connected
+	defer ws.Manager.Unregister(conn)
+
+	ws.Manager.SubscribeToPrices(conn, []uint{1, 2})
+	ws.Manager.UnsubscribeToPrices(conn, []uint{2})
+
+	at := time.Date(2025, 3, 7, 10, 0, 0, 0, time.UTC)
+	ws.Manager.BroadcastPriceTicks([]models.PriceTick{
+		{StockID: 1, TickerSymbol: "AAA", Price: 101, Timestamp: at},
+		{StockID: 2, TickerSymbol: "BBB", Price: 55, Timestamp: at},
+		{StockID: 3, TickerSymbol: "CCC", Price: 12, Timestamp: at},
+	})
+
+	assert.NoError(t, client.SetReadDeadline(time.Now().Add(5*time.Second)))
+	var message ws.WebsocketMessage
+	assert.NoError(t, client.ReadJSON(&message))
+	assert.Equal(t, ws.MessageType_Stock_PriceTicks, message.Type)
+
+	var ticks []models.PriceTick
+	assert.NoError(t, json.Unmarshal(message.Data, &ticks))
+	if assert.Len(t, ticks, 1) {
+		assert.Equal(t, uint(1), ticks[0].StockID)
+		assert.Equal(t, 101.0, ticks[0].Price)
+	}
+}
@@ -0,0 +1,92 @@
+package tests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/jobs"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunner_CatchesUpMissedRuns(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.JobRun{}))
+	store := jobs.NewRunRepository(db)
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, time.March, day, hour, minute, 0, 0, location)
+	}
+
+	// The last run covered Monday, the server comes back Thursday evening
+	assert.NoError(t, store.SaveRun(jobs.RunResult{Job: "daily", Trigger: jobs.TriggerSchedule, ScheduledFor: at(3, 15, 30), Status: jobs.RunSucceeded}))
+	assert.NoError(t, store.SaveRun(jobs.RunResult{Job: "daily", Trigger: jobs.TriggerManual, ScheduledFor: at(5, 9, 0), Status: jobs.RunSucceeded}))
+	utils.SetClock(utils.NewSimulatedClock(at(6, 18, 0), 1))
+	defer utils.SetClock(nil)
+
+	infos := make(chan jobs.RunInfo, 4)
+	runner := jobs.NewRunner(location)
+	runner.SetStore(store, 10*24*time.Hour)
+	assert.NoError(t, runner.Register(jobs.Job{
+		Name:     "daily",
+		Schedule: jobs.TradingDays(jobs.MustParseCron("30 15 * * *")),
+		CatchUp:  true,
+		Run: func(ctx context.Context) error {
+			info := jobs.Info(ctx)
+			jobs.AddItems(ctx, len(info.Missed))
+			infos <- info
+			return nil
+		},
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, runner.Start(ctx))
+
+	select {
+	case info := <-infos:
+		assert.Equal(t, jobs.TriggerCatchUp, info.Trigger)
+		assert.Equal(t, []time.Time{at(4, 15, 30), at(5, 15, 30), at(6, 15, 30)}, info.Missed)
+		assert.Equal(t, at(6, 15, 30), info.ScheduledFor)
+	case <-time.After(2 * time.Second):
+		t.Fatal("no catch-up run")
+	}
+
+	// The catch-up run is persisted and becomes the latest covered time
+	assert.Eventually(t, func() bool {
+		last, ok, err := store.LastScheduledRun("daily")
+		return err == nil && ok && last.Equal(at(6, 15, 30))
+	}, 2*time.Second, 10*time.Millisecond)
+	runs, err := store.GetRecentRuns("daily", 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "catch_up", runs[0].Trigger)
+	assert.Equal(t, 3, runs[0].Items)
+}
+
+func TestRecordHistoricalPrice_Idempotent(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}))
+	repo := stock.NewStockRepository(db)
+	s := models.Stock{TickerSymbol: "AAA", CurrentPrice: 50}
+	assert.NoError(t, db.Create(&s).Error)
+	monday := time.Date(2025, time.March, 3, 15, 30, 0, 0, time.UTC)
+	assert.NoError(t, db.Create(&models.PriceHistory{StockID: s.ID, Price: 50, Timestamp: monday.AddDate(0, 0, 2)}).Error)
+
+	// A backfilled day before the latest price leaves the current price alone
+	added, err := repo.RecordHistoricalPrice(s.ID, 40, monday)
+	assert.NoError(t, err)
+	assert.True(t, added)
+	added, err = repo.RecordHistoricalPrice(s.ID, 41, monday)
+	assert.NoError(t, err)
+	assert.False(t, added)
+	current, err := repo.GetStockByID(s.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 50.0, current.CurrentPrice)
+	var count int64
+	db.Model(&models.PriceHistory{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,206 @@
+package tests
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/jobs"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// sharedRunStore is the run history several servers share
+type sharedRunStore struct {
+	mutex sync.Mutex
+	runs  []jobs.RunResult
+}
+
+func (s *sharedRunStore) SaveRun(result jobs.RunResult) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.runs = append(s.runs, result)
+	return nil
+}
+
+func (s *sharedRunStore) LastScheduledRun(job string) (time.Time, bool, error) {
+	return time.Time{}, false, nil
+}
+
+func (s *sharedRunStore) LastRun(job string) (jobs.RunResult, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for index := len(s.runs) - 1; index >= 0; index-- {
+		if s.runs[index].Job == job {
+			return s.runs[index], true, nil
+		}
+	}
+	return jobs.RunResult{}, false, nil
+}
+
+func (s *sharedRunStore) Succeeded(key string) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, run := range s.runs {
+		if run.Key == key && run.Status == jobs.RunSucceeded {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// tryLocker grants a lock to one server at a time, like an advisory lock
+type tryLocker struct {
+	mutex sync.Mutex
+	held  map[string]bool
+}
+
+func (l *tryLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.held[name] {
+		return nil, false, nil
+	}
+	l.held[name] = true
+	return func() {
+		l.mutex.Lock()
+		delete(l.held, name)
+		l.mutex.Unlock()
+	}, true, nil
+}
+
+// waitingLocker hands the lock to the next server as soon as it is released, like a server whose clock runs late
+type waitingLocker struct {
+	mutex sync.Mutex
+}
+
+func (l *waitingLocker) TryLock(ctx context.Context, name string) (func(), bool, error) {
+	l.mutex.Lock()
+	return l.mutex.Unlock, true, nil
+}
+
+func TestRunner_OneServerRunsEachScheduledRun(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	defer utils.SetClock(nil)
+
+	for name, locker := range map[string]jobs.Locker{
+		"busy lock":    &tryLocker{held: make(map[string]bool)},
+		"late replica": &waitingLocker{},
+	} {
+		t.Run(name, func(t *testing.T) {
+			utils.SetClock(utils.NewSimulatedClock(time.Date(2025, time.March, 4, 15, 29, 59, 800000000, location), 1))
+			store := &sharedRunStore{}
+			var prices, totals int32
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			// Two servers share the run history and the locks
+			for replica := 0; replica < 2; replica++ {
+				runner := jobs.NewRunner(location)
+				runner.SetStore(store, 0)
+				runner.SetLocker(locker)
+				assert.NoError(t, runner.Register(jobs.Job{
+					Name:     "prices",
+					Schedule: jobs.MustParseCron("30 15 * * *"),
+					Run: func(ctx context.Context) error {
+						atomic.AddInt32(&prices, 1)
+						time.Sleep(50 * time.Millisecond)
+						return nil
+					},
+				}))
+				assert.NoError(t, runner.Register(jobs.Job{
+					Name:      "totals",
+					DependsOn: []string{"prices"},
+					Run: func(ctx context.Context) error {
+						atomic.AddInt32(&totals, 1)
+						return nil
+					},
+				}))
+				assert.NoError(t, runner.Start(ctx))
+			}
+
+			assert.Eventually(t, func() bool { return atomic.LoadInt32(&totals) > 0 }, 2*time.Second, 10*time.Millisecond)
+			time.Sleep(200 * time.Millisecond)
+			assert.Equal(t, int32(1), atomic.LoadInt32(&prices))
+			assert.Equal(t, int32(1), atomic.LoadInt32(&totals))
+
+			// Only the server that ran the jobs records them
+			store.mutex.Lock()
+			defer store.mutex.Unlock()
+			assert.Len(t, store.runs, 2)
+			for _, run := range store.runs {
+				assert.Equal(t, jobs.RunSucceeded, run.Status)
+				assert.Equal(t, jobs.RunKey(run.Job, time.Date(2025, time.March, 4, 15, 30, 0, 0, location)), run.Key)
+			}
+		})
+	}
+}
+
+func TestRunRepository_SucceededByKey(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.JobRun{}))
+	store := jobs.NewRunRepository(db)
+	slot := time.Date(2025, time.March, 4, 15, 30, 0, 0, time.UTC)
+
+	assert.NoError(t, store.SaveRun(jobs.RunResult{Job: "prices", Key: jobs.RunKey("prices", slot), Status: jobs.RunFailed}))
+	done, err := store.Succeeded(jobs.RunKey("prices", slot))
+	assert.NoError(t, err)
+	assert.False(t, done)
+
+	assert.NoError(t, store.SaveRun(jobs.RunResult{Job: "prices", Key: jobs.RunKey("prices", slot), Status: jobs.RunSucceeded}))
+	done, err = store.Succeeded(jobs.RunKey("prices", slot))
+	assert.NoError(t, err)
+	assert.True(t, done)
+	done, err = store.Succeeded(jobs.RunKey("prices", slot.AddDate(0, 0, 1)))
+	assert.NoError(t, err)
+	assert.False(t, done)
+}
+
+func TestLogPortfolioPointsChange_RetryReplacesEntry(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PortfolioPointsHistory{}, &models.PointsBreakdown{}))
+	repo := portfolio.NewPortfolioRepository(db)
+
+	first := []models.PointsBreakdown{{PortfolioID: 1, StockID: 1, Points: 3}, {PortfolioID: 1, StockID: 2, Points: 2}}
+	assert.NoError(t, repo.LogPortfolioPointsChange(1, 5, first, "portfolio-totals:2025-03-04"))
+	retry := []models.PointsBreakdown{{PortfolioID: 1, StockID: 1, Points: 4}}
+	assert.NoError(t, repo.LogPortfolioPointsChange(1, 4, retry, "portfolio-totals:2025-03-04"))
+	assert.NoError(t, repo.LogPortfolioPointsChange(1, 6, nil, "portfolio-totals:2025-03-05"))
+	assert.NoError(t, repo.LogPortfolioPointsChange(1, 6, nil, ""))
+
+	history, err := repo.GetPortfolioPointsHistoryEntry(1)
+	assert.NoError(t, err)
+	assert.Len(t, history, 3)
+	var breakdown []models.PointsBreakdown
+	assert.NoError(t, db.Find(&breakdown).Error)
+	assert.Len(t, breakdown, 1)
+	entry, err := repo.GetPointsHistoryWithBreakdown(1, breakdown[0].PointsHistoryID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, entry.Points)
+}
+
+func TestUpdateCurrentPrice_SameTimeReplacesPrice(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}))
+	repo := stock.NewStockRepository(db)
+	s := models.Stock{TickerSymbol: "AAA", CurrentPrice: 50}
+	assert.NoError(t, db.Create(&s).Error)
+	closeTime := time.Date(2025, time.March, 4, 15, 30, 0, 0, time.UTC)
+
+	assert.NoError(t, repo.UpdateCurrentPrice(s.ID, 51, &closeTime))
+	assert.NoError(t, repo.UpdateCurrentPrice(s.ID, 52, &closeTime))
+	var history []models.PriceHistory
+	assert.NoError(t, db.Where("stock_id = ?", s.ID).Find(&history).Error)
+	assert.Len(t, history, 1)
+	assert.Equal(t, 52.0, history[0].Price)
+	current, err := repo.GetStockByID(s.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 52.0, current.CurrentPrice)
+}
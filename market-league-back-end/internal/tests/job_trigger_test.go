@@ -0,0 +1,164 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/market-league/api"
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/jobs"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/user"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+// connectHandler serves one WebSocket connection signed in as userID and returns the client end and the
+// server's connection
+func connectHandler(t *testing.T, userID uint) (*websocket.Conn, *ws.Connection, func()) {
+	upgrader := websocket.Upgrader{}
+	connected := make(chan *ws.Connection, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawConn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		connected <- &ws.Connection{Ws: rawConn, Subscriptions: make(map[uint]bool), UserID: userID}
+	}))
+	client, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	assert.NoError(t, err)
+	return client, <-connected, func() {
+		client.Close()
+		server.Close()
+	}
+}
+
+// readMessage reads the next message sent to a client
+func readMessage(t *testing.T, client *websocket.Conn) ws.WebsocketMessage {
+	client.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var message ws.WebsocketMessage
+	assert.NoError(t, client.ReadJSON(&message))
+	return message
+}
+
+// readError reads an error sent with ws.SendError, which writes each error to the connection twice
+func readError(t *testing.T, client *websocket.Conn) ws.WebsocketMessage {
+	message := readMessage(t, client)
+	assert.Equal(t, message, readMessage(t, client))
+	return message
+}
+
+func TestJobHandler_TriggerStreamsProgressAndListsRuns(t *testing.T) {
+	db := testutils.SetupTestDB()
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1) // Jobs run in the background, every connection has to see the same in-memory database
+	assert.NoError(t, db.AutoMigrate(&models.User{}, &models.JobRun{}))
+	admin := models.User{Username: "admin", Password: "x", IsAdmin: true}
+	player := models.User{Username: "player", Password: "x"}
+	assert.NoError(t, db.Create(&admin).Error)
+	assert.NoError(t, db.Create(&player).Error)
+
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	utils.SetClock(utils.NewSimulatedClock(time.Date(2025, time.March, 6, 12, 0, 0, 0, location), 1))
+	defer utils.SetClock(nil)
+
+	runs := jobs.NewRunRepository(db)
+	infos := make(chan jobs.RunInfo, 4)
+	newHandler := func() *api.JobHandler {
+		runner := jobs.NewRunner(location)
+		runner.SetStore(runs, 0)
+		assert.NoError(t, runner.Register(jobs.Job{Name: "prices", Dated: true, Run: func(ctx context.Context) error {
+			jobs.AddItems(ctx, 2)
+			infos <- jobs.Info(ctx)
+			return nil
+		}}))
+		assert.NoError(t, runner.Register(jobs.Job{Name: "totals", DependsOn: []string{"prices"}, Run: func(ctx context.Context) error {
+			infos <- jobs.Info(ctx)
+			return nil
+		}}))
+		return api.NewJobHandler(runner, runs, user.NewUserRepository(db))
+	}
+	handler := newHandler()
+	client, conn, closeConn := connectHandler(t, admin.ID)
+	defer closeConn()
+	playerClient, playerConn, closePlayerConn := connectHandler(t, player.ID)
+	defer closePlayerConn()
+
+	// Only a signed-in admin runs jobs, and only for trading days that have closed
+	trigger := func(userID uint, job, date string, leagueID uint) error {
+		data, _ := json.Marshal(map[string]interface{}{"user_id": userID, "job": job, "date": date, "league_id": leagueID})
+		return handler.TriggerJob(conn, data)
+	}
+	data, _ := json.Marshal(map[string]interface{}{"job": "prices"})
+	assert.Error(t, handler.TriggerJob(playerConn, data))
+	assert.Equal(t, ws.MessageType_Admin_TriggerJob, readError(t, playerClient).Type)
+	data, _ = json.Marshal(map[string]interface{}{"user_id": admin.ID, "job": "prices"})
+	assert.Error(t, handler.TriggerJob(playerConn, data)) // Naming the admin does not make the player one
+	readError(t, playerClient)
+	for _, date := range []string{"2025-03-08", "2025-03-06", "03/04/2025"} {
+		assert.Error(t, trigger(admin.ID, "prices", date, 0), date)
+		assert.Contains(t, string(readError(t, client).Data), "message")
+	}
+	assert.Error(t, trigger(admin.ID, "missing", "", 0))
+	readError(t, client)
+
+	// Only dated jobs can be given a day to make up
+	assert.Error(t, trigger(admin.ID, "totals", "2025-03-04", 0))
+	assert.Contains(t, string(readError(t, client).Data), "does not take a date")
+
+	// A run for a past day streams each job as it starts and finishes, then sends every result
+	assert.NoError(t, trigger(admin.ID, "prices", "2025-03-04", 7))
+	var steps []jobs.RunResult
+	for len(steps) < 4 {
+		message := readMessage(t, client)
+		assert.Equal(t, ws.MessageType_Admin_JobProgress, message.Type)
+		var step jobs.RunResult
+		assert.NoError(t, json.Unmarshal(message.Data, &step))
+		steps = append(steps, step)
+	}
+	assert.Equal(t, []string{"prices", "prices", "totals", "totals"}, []string{steps[0].Job, steps[1].Job, steps[2].Job, steps[3].Job})
+	assert.Equal(t, jobs.RunRunning, steps[0].Status)
+	assert.Equal(t, jobs.RunSucceeded, steps[1].Status)
+	assert.Equal(t, 2, steps[1].Items)
+	message := readMessage(t, client)
+	assert.Equal(t, ws.MessageType_Admin_TriggerJob, message.Type)
+	var results []jobs.RunResult
+	assert.NoError(t, json.Unmarshal(message.Data, &results))
+	assert.Len(t, results, 2)
+
+	info := <-infos
+	assert.Equal(t, jobs.TriggerManual, info.Trigger)
+	assert.Equal(t, []time.Time{time.Date(2025, time.March, 4, 15, 30, 0, 0, location)}, info.Missed)
+	assert.Equal(t, uint(7), info.LeagueID)
+	<-infos
+
+	// After a restart a dependency's last run is read back from the run history
+	handler = newHandler()
+	assert.NoError(t, trigger(admin.ID, "totals", "", 0))
+	for index := 0; index < 2; index++ {
+		assert.Equal(t, ws.MessageType_Admin_JobProgress, readMessage(t, client).Type)
+	}
+	message = readMessage(t, client)
+	assert.NoError(t, json.Unmarshal(message.Data, &results))
+	assert.Equal(t, jobs.RunSucceeded, results[0].Status)
+	<-infos
+
+	data, _ = json.Marshal(map[string]interface{}{"limit": 2})
+	assert.NoError(t, handler.GetJobRuns(conn, data))
+	message = readMessage(t, client)
+	assert.Equal(t, ws.MessageType_Admin_GetJobRuns, message.Type)
+	var stored []models.JobRun
+	assert.NoError(t, json.Unmarshal(message.Data, &stored))
+	assert.Len(t, stored, 2)
+	assert.Equal(t, "totals", stored[0].Job)
+	assert.Equal(t, "manual", stored[0].Trigger)
+}
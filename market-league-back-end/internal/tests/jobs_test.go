@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/jobs"
+	"github.com/market-league/internal/utils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron_NextRun(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, location)
+	}
+
+	daily := jobs.MustParseCron("30 15 * * 1-5")
+	assert.Equal(t, at(time.March, 3, 15, 30), daily.Next(at(time.March, 3, 9, 0)))
+	assert.Equal(t, at(time.March, 4, 15, 30), daily.Next(at(time.March, 3, 15, 30)))
+	assert.Equal(t, at(time.March, 10, 15, 30), daily.Next(at(time.March, 7, 16, 0))) // Over the weekend
+
+	steps := jobs.MustParseCron("*/15 8-9 1,15 * *")
+	assert.Equal(t, at(time.March, 15, 8, 0), steps.Next(at(time.March, 1, 9, 45)))
+	assert.Equal(t, at(time.March, 15, 8, 15), steps.Next(at(time.March, 15, 8, 0)))
+
+	// Good Friday is a weekday but not a trading day
+	tradingDays := jobs.TradingDays(daily)
+	assert.Equal(t, at(time.April, 21, 15, 30), tradingDays.Next(at(time.April, 17, 16, 0)))
+
+	// Polling stops at the close and resumes at the next open
+	intraday := jobs.WhileMarketOpen(jobs.Every(5 * time.Minute))
+	assert.Equal(t, at(time.March, 3, 10, 5), intraday.Next(at(time.March, 3, 10, 0)))
+	assert.Equal(t, at(time.March, 4, 8, 30), intraday.Next(at(time.March, 3, 14, 58)))
+
+	for _, spec := range []string{"* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := jobs.ParseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestRunner_DependenciesPanicsAndTimeouts(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	runner := jobs.NewRunner(location)
+
+	var ran []string
+	record := func(name string, err error) func(context.Context) error {
+		return func(ctx context.Context) error {
+			ran = append(ran, name)
+			return err
+		}
+	}
+	pricesErr := error(nil)
+	assert.NoError(t, runner.Register(jobs.Job{Name: "totals", DependsOn: []string{"owners", "scoring"}, Run: record("totals", nil)}))
+	assert.NoError(t, runner.Register(jobs.Job{Name: "prices", Run: func(ctx context.Context) error {
+		ran = append(ran, "prices")
+		return pricesErr
+	}}))
+	assert.NoError(t, runner.Register(jobs.Job{Name: "owners", DependsOn: []string{"prices"}, Run: record("owners", nil)}))
+	assert.NoError(t, runner.Register(jobs.Job{Name: "scoring", DependsOn: []string{"prices"}, Run: func(ctx context.Context) error {
+		ran = append(ran, "scoring")
+		panic("boom")
+	}}))
+	assert.NoError(t, runner.Register(jobs.Job{Name: "slow", Timeout: 20 * time.Millisecond, Run: func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	}}))
+	assert.Error(t, runner.Register(jobs.Job{Name: "prices", Run: record("again", nil)}))
+
+	// A panic fails its own run and skips the jobs after it, the rest of the chain still runs
+	results, err := runner.Trigger(context.Background(), "prices")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prices", "owners", "scoring"}, ran)
+	statuses := map[string]jobs.RunStatus{}
+	for _, result := range results {
+		statuses[result.Job] = result.Status
+	}
+	assert.Equal(t, map[string]jobs.RunStatus{
+		"prices": jobs.RunSucceeded, "owners": jobs.RunSucceeded, "scoring": jobs.RunFailed, "totals": jobs.RunSkipped,
+	}, statuses)
+	last, ok := runner.LastResult("scoring")
+	assert.True(t, ok)
+	assert.Contains(t, last.Error, "boom")
+
+	// Nothing depending on a failed job runs
+	ran = nil
+	pricesErr = errors.New("provider down")
+	results, err = runner.Trigger(context.Background(), "prices")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"prices"}, ran)
+	assert.Len(t, results, 4)
+
+	// A hung job times out without holding up the runner
+	start := time.Now()
+	results, err = runner.Trigger(context.Background(), "slow")
+	assert.NoError(t, err)
+	assert.Equal(t, jobs.RunTimedOut, results[0].Status)
+	assert.Less(t, time.Since(start), 500*time.Millisecond)
+
+	_, err = runner.Trigger(context.Background(), "missing")
+	assert.Error(t, err)
+}
+
+func TestRunner_RejectsBadDependencies(t *testing.T) {
+	runner := jobs.NewRunner(time.UTC)
+	noop := func(ctx context.Context) error { return nil }
+	assert.NoError(t, runner.Register(jobs.Job{Name: "a", DependsOn: []string{"b"}, Run: noop}))
+	assert.NoError(t, runner.Register(jobs.Job{Name: "b", DependsOn: []string{"a"}, Run: noop}))
+	assert.Error(t, runner.Start(context.Background()))
+
+	runner = jobs.NewRunner(time.UTC)
+	assert.NoError(t, runner.Register(jobs.Job{Name: "a", DependsOn: []string{"missing"}, Run: noop}))
+	assert.Error(t, runner.Start(context.Background()))
+}
+
+func TestRunner_TimedOutRunBlocksTheNextUntilItReturns(t *testing.T) {
+	runner := jobs.NewRunner(time.UTC)
+	release := make(chan struct{})
+	var runs int32
+	assert.NoError(t, runner.Register(jobs.Job{Name: "stuck", Timeout: 10 * time.Millisecond, Run: func(ctx context.Context) error {
+		atomic.AddInt32(&runs, 1)
+		<-release
+		return nil
+	}}))
+
+	results, err := runner.Trigger(context.Background(), "stuck")
+	assert.NoError(t, err)
+	assert.Equal(t, jobs.RunTimedOut, results[0].Status)
+
+	// The abandoned run is still going, so the next one is skipped rather than run alongside it
+	results, err = runner.Trigger(context.Background(), "stuck")
+	assert.NoError(t, err)
+	assert.Equal(t, jobs.RunSkipped, results[0].Status)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&runs))
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		results, err := runner.Trigger(context.Background(), "stuck")
+		return err == nil && results[0].Status == jobs.RunSucceeded
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestIn_ReadsScheduleInItsLocation(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	daily := jobs.In(location, jobs.MustParseCron("30 15 * * *"))
+
+	// Read in UTC the run still falls at 15:30 market time
+	next := daily.Next(time.Date(2025, time.March, 3, 12, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.UTC, next.Location())
+	assert.Equal(t, time.Date(2025, time.March, 3, 15, 30, 0, 0, location), next.In(location))
+}
+
+func TestEvery_AlignsToTheClock(t *testing.T) {
+	every := jobs.Every(5 * time.Minute)
+
+	// Servers started at different times land on the same run times
+	assert.Equal(t, time.Date(2025, time.March, 3, 10, 5, 0, 0, time.UTC), every.Next(time.Date(2025, time.March, 3, 10, 1, 17, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, time.March, 3, 10, 5, 0, 0, time.UTC), every.Next(time.Date(2025, time.March, 3, 10, 3, 42, 0, time.UTC)))
+	assert.Equal(t, time.Date(2025, time.March, 3, 10, 10, 0, 0, time.UTC), every.Next(time.Date(2025, time.March, 3, 10, 5, 0, 0, time.UTC)))
+}
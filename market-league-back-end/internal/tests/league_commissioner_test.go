@@ -0,0 +1,166 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/league"
+	"github.com/market-league/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCommissioner_KickAndTransferOwnership(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	stock := models.Stock{TickerSymbol: "AAA"}
+	assert.NoError(t, db.Create(&stock).Error)
+	owner := models.User{Username: "owner", Password: "x"}
+	member := models.User{Username: "member", Password: "x"}
+	kicked := models.User{Username: "kicked", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &member, &kicked}).Error)
+
+	now := time.Now()
+	drafted := models.League{LeagueName: "Drafted", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	portfolios := createLeagueWithPlayers(t, db, &drafted, []*models.User{&owner, &member, &kicked}, models.DraftReady)
+	pool := models.LeaguePortfolio{LeagueID: drafted.ID, Name: "Pool"}
+	assert.NoError(t, db.Omit("League").Create(&pool).Error)
+	assert.NoError(t, db.Model(&models.Portfolio{ID: portfolios[2].ID}).Association("Stocks").Append(&stock))
+
+	// Only the owner removes players, and never themselves
+	assert.ErrorContains(t, leagueService.KickMember(drafted.ID, member.ID, kicked.ID), "only the league owner")
+	assert.ErrorContains(t, leagueService.KickMember(drafted.ID, owner.ID, owner.ID), "cannot be removed")
+	assert.NoError(t, leagueService.KickMember(drafted.ID, owner.ID, kicked.ID))
+	var returned models.LeaguePortfolio
+	assert.NoError(t, db.Preload("Stocks").First(&returned, pool.ID).Error)
+	assert.Len(t, returned.Stocks, 1)
+	var portfolioCount int64
+	assert.NoError(t, db.Model(&models.Portfolio{}).Where("league_id = ? AND left_at IS NULL", drafted.ID).Count(&portfolioCount).Error)
+	assert.Equal(t, int64(2), portfolioCount)
+
+	// Ownership only goes to a player in the league, and the old owner loses the commissioner tools
+	assert.ErrorContains(t, leagueService.TransferOwnership(drafted.ID, owner.ID, kicked.ID), "must be a player")
+	assert.ErrorContains(t, leagueService.TransferOwnership(drafted.ID, member.ID, member.ID), "only the league owner")
+	assert.NoError(t, leagueService.TransferOwnership(drafted.ID, owner.ID, member.ID))
+	var current models.League
+	assert.NoError(t, db.First(&current, drafted.ID).Error)
+	assert.Equal(t, member.ID, current.OwnerID)
+	assert.ErrorContains(t, leagueService.RemoveLeague(drafted.ID, owner.ID), "only the league owner")
+	assert.NoError(t, leagueService.RemoveLeague(drafted.ID, member.ID))
+	assert.Error(t, db.First(&models.League{}, drafted.ID).Error)
+}
+
+func TestCommissioner_UpdateSettings(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	member := models.User{Username: "member", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &member}).Error)
+
+	now := time.Now()
+	waiting := models.League{LeagueName: "Waiting", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &waiting, []*models.User{&owner, &member}, models.DraftNotReady)
+	drafted := models.League{LeagueName: "Drafted", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &drafted, []*models.User{&owner}, models.DraftReady)
+
+	// Before the draft everything can change
+	name := "  Renamed  "
+	public := true
+	three := 3
+	draftTime := now.Add(time.Hour)
+	updated, err := leagueService.UpdateSettings(waiting.ID, owner.ID, league.LeagueSettingsUpdate{LeagueName: &name, Public: &public, MaxPlayers: &three, DraftTime: &draftTime})
+	assert.NoError(t, err)
+	assert.Equal(t, "Renamed", updated.LeagueName)
+	var stored models.League
+	assert.NoError(t, db.First(&stored, waiting.ID).Error)
+	assert.Equal(t, "Renamed", stored.LeagueName)
+	assert.True(t, stored.Public)
+	assert.Equal(t, 3, *stored.MaxPlayers)
+	assert.NotNil(t, stored.DraftTime)
+
+	one := 1
+	_, err = leagueService.UpdateSettings(waiting.ID, owner.ID, league.LeagueSettingsUpdate{MinPlayers: &one, MaxPlayers: &one})
+	assert.ErrorContains(t, err, "already has 2 players")
+	_, err = leagueService.UpdateSettings(waiting.ID, member.ID, league.LeagueSettingsUpdate{LeagueName: &name})
+	assert.ErrorContains(t, err, "only the league owner")
+
+	// After the draft the schedule and player limits are fixed, but the season can still be extended
+	start := now.Add(time.Hour)
+	_, err = leagueService.UpdateSettings(drafted.ID, owner.ID, league.LeagueSettingsUpdate{StartDate: &start})
+	assert.ErrorContains(t, err, "fixed once the draft starts")
+	_, err = leagueService.UpdateSettings(drafted.ID, owner.ID, league.LeagueSettingsUpdate{MaxPlayers: &three})
+	assert.ErrorContains(t, err, "fixed once the draft starts")
+	past := now.Add(-time.Hour)
+	_, err = leagueService.UpdateSettings(drafted.ID, owner.ID, league.LeagueSettingsUpdate{EndDate: &past})
+	assert.ErrorContains(t, err, "into the past")
+	later := now.AddDate(0, 2, 0)
+	_, err = leagueService.UpdateSettings(drafted.ID, owner.ID, league.LeagueSettingsUpdate{EndDate: &later})
+	assert.NoError(t, err)
+	var extended models.League
+	assert.NoError(t, db.First(&extended, drafted.ID).Error)
+	assert.WithinDuration(t, later, extended.EndDate, time.Second)
+	assert.Equal(t, models.PostDraft, extended.LeagueState)
+}
+
+func TestCommissionerHandlers_ActAsTheSignedInUser(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	member := models.User{Username: "member", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &member}).Error)
+
+	now := time.Now()
+	waiting := models.League{LeagueName: "Waiting", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &waiting, []*models.User{&owner, &member}, models.DraftNotReady)
+	handler := league.NewLeagueHandler(leagueService, portfolioService, nil)
+	memberClient, memberConn, closeMember := connectHandler(t, member.ID)
+	defer closeMember()
+
+	// A member gets none of the commissioner tools, whether or not they name the owner
+	for _, userID := range []uint{owner.ID, 0} {
+		fields := map[string]interface{}{"league_id": waiting.ID, "user_id": userID}
+		kick := map[string]interface{}{"league_id": waiting.ID, "user_id": userID, "member_id": owner.ID}
+		transfer := map[string]interface{}{"league_id": waiting.ID, "user_id": userID, "new_owner_id": member.ID}
+		rename := map[string]interface{}{"league_id": waiting.ID, "user_id": userID, "league_name": "Taken"}
+		assert.Error(t, handler.KickMember(memberConn, jsonPayload(t, kick)))
+		readError(t, memberClient)
+		assert.Error(t, handler.TransferOwnership(memberConn, jsonPayload(t, transfer)))
+		readError(t, memberClient)
+		assert.Error(t, handler.UpdateSettings(memberConn, jsonPayload(t, rename)))
+		readError(t, memberClient)
+		assert.Error(t, handler.RemoveLeague(memberConn, jsonPayload(t, fields)))
+		readError(t, memberClient)
+	}
+	var current models.League
+	assert.NoError(t, db.First(&current, waiting.ID).Error)
+	assert.Equal(t, owner.ID, current.OwnerID)
+	assert.Equal(t, "Waiting", current.LeagueName)
+
+	// The signed-in owner can
+	ownerClient, ownerConn, closeOwner := connectHandler(t, owner.ID)
+	defer closeOwner()
+	assert.NoError(t, handler.UpdateSettings(ownerConn, jsonPayload(t, map[string]interface{}{"league_id": waiting.ID, "league_name": "Renamed"})))
+	assert.Equal(t, ws.MessageType_League_UpdateSettings, readMessage(t, ownerClient).Type)
+	assert.NoError(t, db.First(&current, waiting.ID).Error)
+	assert.Equal(t, "Renamed", current.LeagueName)
+}
+
+func TestCreateLeagueHandler_OwnedByTheSignedInUser(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	creator := models.User{Username: "creator", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&creator, &other}).Error)
+	handler := league.NewLeagueHandler(leagueService, portfolioService, nil)
+	endDate := time.Now().AddDate(0, 1, 0).Format(time.RFC3339)
+
+	// Nobody creates a league in someone else's name, or without signing in
+	client, conn, closeConn := connectHandler(t, creator.ID)
+	defer closeConn()
+	assert.Error(t, handler.CreateLeague(conn, jsonPayload(t, map[string]interface{}{"league_name": "Theirs", "owner_user": other.ID, "end_date": endDate})))
+	assert.Equal(t, ws.MessageType_League_CreateLeague, readError(t, client).Type)
+	anonymousClient, anonymousConn, closeAnonymous := connectHandler(t, 0)
+	defer closeAnonymous()
+	assert.Error(t, handler.CreateLeague(anonymousConn, jsonPayload(t, map[string]interface{}{"league_name": "Nobody's", "end_date": endDate})))
+	readError(t, anonymousClient)
+
+	var leagues int64
+	assert.NoError(t, db.Model(&models.League{}).Count(&leagues).Error)
+	assert.Equal(t, int64(0), leagues)
+}
@@ -0,0 +1,142 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/league"
+	"github.com/market-league/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDirectory_ListsOpenPublicLeagues(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	member := models.User{Username: "member", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &member}).Error)
+
+	now := time.Now()
+	one := 1
+	open := models.League{LeagueName: "Open Tech", Description: "Semiconductors only", Public: true, OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &open, []*models.User{&owner}, models.DraftNotReady)
+	private := models.League{LeagueName: "Private", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &private, []*models.User{&owner}, models.DraftNotReady)
+	full := models.League{LeagueName: "Full", Public: true, MaxPlayers: &one, OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &full, []*models.User{&owner}, models.DraftNotReady)
+	drafted := models.League{LeagueName: "Drafted", Public: true, OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &drafted, []*models.User{&owner}, models.DraftReady)
+	joined := models.League{LeagueName: "Joined", Public: true, OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &joined, []*models.User{&owner, &member}, models.DraftNotReady)
+
+	// Private, full and drafted leagues stay out of the directory
+	leagues, err := leagueService.GetDirectory("", 0, 0, 0)
+	assert.NoError(t, err)
+	names := []string{}
+	for _, l := range leagues {
+		names = append(names, l.LeagueName)
+	}
+	assert.ElementsMatch(t, []string{"Open Tech", "Joined"}, names)
+
+	// A user does not see leagues they are already in, and a search matches the description
+	leagues, err = leagueService.GetDirectory("", member.ID, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, leagues, 1)
+	assert.Equal(t, "owner", leagues[0].OwnerUsername)
+	assert.Equal(t, 1, leagues[0].Players)
+	leagues, err = leagueService.GetDirectory("semiconductor", 0, 0, 0)
+	assert.NoError(t, err)
+	assert.Len(t, leagues, 1)
+	assert.Equal(t, open.ID, leagues[0].ID)
+	leagues, err = leagueService.GetDirectory("", 0, 1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, leagues, 1)
+}
+
+func TestJoinRequests_OwnerApprovesOrDenies(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	approved := models.User{Username: "approved", Password: "x"}
+	denied := models.User{Username: "denied", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &approved, &denied}).Error)
+
+	now := time.Now()
+	public := models.League{LeagueName: "Public", Public: true, OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &public, []*models.User{&owner}, models.DraftNotReady)
+	private := models.League{LeagueName: "Private", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &private, []*models.User{&owner}, models.DraftNotReady)
+
+	_, err := leagueService.RequestToJoin(private.ID, approved.ID, "")
+	assert.ErrorContains(t, err, "not public")
+	first, err := leagueService.RequestToJoin(public.ID, approved.ID, "let me in")
+	assert.NoError(t, err)
+	_, err = leagueService.RequestToJoin(public.ID, approved.ID, "")
+	assert.ErrorContains(t, err, "already asked")
+	second, err := leagueService.RequestToJoin(public.ID, denied.ID, "")
+	assert.NoError(t, err)
+
+	// Only the owner sees and answers the requests
+	_, err = leagueService.GetJoinRequests(public.ID, approved.ID)
+	assert.ErrorContains(t, err, "only the league owner")
+	requests, err := leagueService.GetJoinRequests(public.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.Len(t, requests, 2)
+	assert.Equal(t, "approved", requests[0].Username)
+	assert.Equal(t, "let me in", requests[0].Message)
+	_, err = leagueService.DecideJoinRequest(first.ID, denied.ID, true)
+	assert.ErrorContains(t, err, "only the league owner")
+
+	answered, err := leagueService.DecideJoinRequest(first.ID, owner.ID, true)
+	assert.NoError(t, err)
+	assert.Equal(t, models.JoinRequest_Approved, answered.Status)
+	answered, err = leagueService.DecideJoinRequest(second.ID, owner.ID, false)
+	assert.NoError(t, err)
+	assert.Equal(t, models.JoinRequest_Denied, answered.Status)
+	_, err = leagueService.DecideJoinRequest(second.ID, owner.ID, true)
+	assert.ErrorContains(t, err, "already been answered")
+
+	// The approved user has a portfolio, the denied one does not
+	var portfolios int64
+	assert.NoError(t, db.Model(&models.Portfolio{}).Where("league_id = ? AND user_id = ?", public.ID, approved.ID).Count(&portfolios).Error)
+	assert.Equal(t, int64(1), portfolios)
+	assert.NoError(t, db.Model(&models.Portfolio{}).Where("league_id = ? AND user_id = ?", public.ID, denied.ID).Count(&portfolios).Error)
+	assert.Equal(t, int64(0), portfolios)
+	requests, err = leagueService.GetJoinRequests(public.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, requests)
+}
+
+func TestJoinRequestHandlers_ActAsTheSignedInUser(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	player := models.User{Username: "player", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &player}).Error)
+
+	now := time.Now()
+	public := models.League{LeagueName: "Public", Public: true, OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &public, []*models.User{&owner}, models.DraftNotReady)
+	handler := league.NewLeagueHandler(leagueService, portfolioService, nil)
+	playerClient, playerConn, closePlayer := connectHandler(t, player.ID)
+	defer closePlayer()
+	ownerClient, ownerConn, closeOwner := connectHandler(t, owner.ID)
+	defer closeOwner()
+
+	// The request is made for the signed-in player
+	assert.NoError(t, handler.RequestToJoin(playerConn, jsonPayload(t, map[string]interface{}{"league_id": public.ID})))
+	var joinRequest models.LeagueJoinRequest
+	assert.NoError(t, json.Unmarshal(readMessage(t, playerClient).Data, &joinRequest))
+	assert.Equal(t, player.ID, joinRequest.UserID)
+
+	// Naming the owner does not let the player see or answer requests
+	assert.Error(t, handler.GetJoinRequests(playerConn, jsonPayload(t, map[string]interface{}{"league_id": public.ID, "user_id": owner.ID})))
+	readError(t, playerClient)
+	assert.Error(t, handler.ApproveJoinRequest(playerConn, jsonPayload(t, map[string]interface{}{"request_id": joinRequest.ID, "user_id": owner.ID})))
+	readError(t, playerClient)
+	assert.Error(t, handler.ApproveJoinRequest(playerConn, jsonPayload(t, map[string]interface{}{"request_id": joinRequest.ID})))
+	readError(t, playerClient)
+
+	assert.NoError(t, handler.ApproveJoinRequest(ownerConn, jsonPayload(t, map[string]interface{}{"request_id": joinRequest.ID})))
+	var answered models.LeagueJoinRequest
+	assert.NoError(t, json.Unmarshal(readMessage(t, ownerClient).Data, &answered))
+	assert.Equal(t, models.JoinRequest_Approved, answered.Status)
+}
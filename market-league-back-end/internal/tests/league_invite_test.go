@@ -0,0 +1,151 @@
+package tests
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/league"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/portfolio"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedeemInvite_JoinsLeagueWithinLimits(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	leagueService.SetInviteLinkBase("https://example.com/join/")
+	owner := models.User{Username: "owner", Password: "x"}
+	first := models.User{Username: "first", Password: "x"}
+	second := models.User{Username: "second", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &first, &second}).Error)
+
+	now := time.Now()
+	league := models.League{LeagueName: "Invites", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &league, []*models.User{&owner}, models.DraftNotReady)
+
+	// Only the owner hands out invites
+	one := 1
+	_, err := leagueService.CreateInvite(league.ID, first.ID, nil, &one)
+	assert.ErrorContains(t, err, "only the league owner")
+	invite, err := leagueService.CreateInvite(league.ID, owner.ID, nil, &one)
+	assert.NoError(t, err)
+	assert.Len(t, invite.Code, 8)
+	assert.Equal(t, "https://example.com/join/"+invite.Code, invite.Link)
+
+	// Redeeming creates the membership, the player and the portfolio together
+	portfolio, err := leagueService.RedeemInvite(invite.Code, first.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, league.ID, portfolio.LeagueID)
+	assert.Equal(t, first.ID, portfolio.UserID)
+	var players, members int64
+	assert.NoError(t, db.Model(&models.LeaguePlayer{}).Where("league_id = ? AND player_id = ?", league.ID, first.ID).Count(&players).Error)
+	assert.Equal(t, int64(1), players)
+	assert.NoError(t, db.Table("user_leagues").Where("league_id = ? AND user_id = ?", league.ID, first.ID).Count(&members).Error)
+	assert.Equal(t, int64(1), members)
+
+	// The single use is gone, and a used up invite is no longer outstanding
+	_, err = leagueService.RedeemInvite(invite.Code, second.ID)
+	assert.ErrorContains(t, err, "used up")
+	invites, err := leagueService.GetInvites(league.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, invites)
+
+	// A member cannot join twice, and the failed attempt does not count as a use
+	unlimited, err := leagueService.CreateInvite(league.ID, owner.ID, nil, nil)
+	assert.NoError(t, err)
+	_, err = leagueService.RedeemInvite(unlimited.Code, first.ID)
+	assert.ErrorContains(t, err, "already in league")
+	var stored models.LeagueInvite
+	assert.NoError(t, db.First(&stored, unlimited.ID).Error)
+	assert.Equal(t, 0, stored.Uses)
+}
+
+func TestRedeemInvite_RejectsExpiredAndRevoked(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &other}).Error)
+
+	now := time.Now()
+	league := models.League{LeagueName: "Invites", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &league, []*models.User{&owner}, models.DraftNotReady)
+
+	past := now.Add(-time.Hour)
+	_, err := leagueService.CreateInvite(league.ID, owner.ID, &past, nil)
+	assert.ErrorContains(t, err, "expire in the future")
+
+	expired := models.LeagueInvite{LeagueID: league.ID, Code: "EXPIRED2", CreatedBy: owner.ID, ExpiresAt: &past}
+	assert.NoError(t, db.Create(&expired).Error)
+	_, err = leagueService.RedeemInvite("expired2", other.ID)
+	assert.ErrorContains(t, err, "expired")
+
+	invite, err := leagueService.CreateInvite(league.ID, owner.ID, nil, nil)
+	assert.NoError(t, err)
+	_, err = leagueService.RevokeInvite(invite.ID, other.ID)
+	assert.ErrorContains(t, err, "only the league owner")
+	revoked, err := leagueService.RevokeInvite(invite.ID, owner.ID)
+	assert.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+	_, err = leagueService.RedeemInvite(invite.Code, other.ID)
+	assert.ErrorContains(t, err, "revoked")
+
+	// Adding a user directly is for the owner alone
+	_, err = leagueService.AddUserToLeague(other.ID, other.ID, league.ID)
+	assert.ErrorContains(t, err, "only the league owner")
+	_, err = leagueService.AddUserToLeague(owner.ID, other.ID, league.ID)
+	assert.NoError(t, err)
+}
+
+// jsonPayload marshals the fields of a websocket request
+func jsonPayload(t *testing.T, fields map[string]interface{}) json.RawMessage {
+	data, err := json.Marshal(fields)
+	assert.NoError(t, err)
+	return data
+}
+
+func TestInviteHandlers_ActAsTheSignedInUser(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	player := models.User{Username: "player", Password: "x"}
+	outsider := models.User{Username: "outsider", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &player, &outsider}).Error)
+
+	now := time.Now()
+	invited := models.League{LeagueName: "Invites", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &invited, []*models.User{&owner}, models.DraftNotReady)
+	handler := league.NewLeagueHandler(leagueService, portfolioService, nil)
+	payload := func(fields map[string]interface{}) json.RawMessage { return jsonPayload(t, fields) }
+
+	// A player cannot hand out invites by naming the owner
+	playerClient, playerConn, closePlayer := connectHandler(t, player.ID)
+	defer closePlayer()
+	assert.Error(t, handler.CreateInvite(playerConn, payload(map[string]interface{}{"league_id": invited.ID, "user_id": owner.ID})))
+	assert.Equal(t, ws.MessageType_League_CreateInvite, readError(t, playerClient).Type)
+	assert.Error(t, handler.CreateInvite(playerConn, payload(map[string]interface{}{"league_id": invited.ID})))
+	readError(t, playerClient)
+
+	ownerClient, ownerConn, closeOwner := connectHandler(t, owner.ID)
+	defer closeOwner()
+	assert.NoError(t, handler.CreateInvite(ownerConn, payload(map[string]interface{}{"league_id": invited.ID})))
+	var invite models.LeagueInvite
+	assert.NoError(t, json.Unmarshal(readMessage(t, ownerClient).Data, &invite))
+
+	// An invite joins the signed-in user, not whoever the payload names
+	assert.Error(t, handler.RedeemInvite(playerConn, payload(map[string]interface{}{"code": invite.Code, "user_id": outsider.ID})))
+	readError(t, playerClient)
+	assert.NoError(t, handler.RedeemInvite(playerConn, payload(map[string]interface{}{"code": invite.Code})))
+	var joined models.Portfolio
+	assert.NoError(t, json.Unmarshal(readMessage(t, playerClient).Data, &joined))
+	assert.Equal(t, player.ID, joined.UserID)
+
+	// Creating a portfolio is no way into a league
+	outsiderClient, outsiderConn, closeOutsider := connectHandler(t, outsider.ID)
+	defer closeOutsider()
+	portfolioHandler := portfolio.NewPortfolioHandler(portfolioService)
+	assert.Error(t, portfolioHandler.CreatePortfolio(outsiderConn, payload(map[string]interface{}{"league_id": invited.ID})))
+	readError(t, outsiderClient)
+	var portfolios int64
+	assert.NoError(t, db.Model(&models.Portfolio{}).Where("user_id = ?", outsider.ID).Count(&portfolios).Error)
+	assert.Equal(t, int64(0), portfolios)
+}
@@ -0,0 +1,235 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/league"
+	league_portfolio "github.com/market-league/internal/league_portfolio"
+	"github.com/market-league/internal/lineup"
+	"github.com/market-league/internal/models"
+	ownership_history "github.com/market-league/internal/ownership_history"
+	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/trade"
+	"github.com/market-league/internal/user"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// newLeagueServices wires the league service the way the server does, on a database every goroutine shares
+func newLeagueServices(t *testing.T) (*gorm.DB, *league.LeagueService, *portfolio.PortfolioService) {
+	db := testutils.SetupTestDB()
+	sqlDB, err := db.DB()
+	assert.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1) // The draft runs in the background, every connection has to see the same in-memory database
+	assert.NoError(t, db.AutoMigrate(&models.User{}, &models.League{}, &models.LeaguePlayer{}, &models.RosterPosition{},
+		&models.LeaguePortfolio{}, &models.UniverseMember{}, &models.OwnershipHistory{}, &models.ScoringWindow{},
+		&models.PortfolioPointsHistory{}, &models.PointsBreakdown{}, &models.LeagueAward{}, &models.LeagueStanding{}, &models.LeagueInvite{}, &models.LeagueJoinRequest{}, &models.Trade{}))
+
+	stockRepo := stock.NewStockRepository(db)
+	userRepo := user.NewUserRepository(db)
+	portfolioRepo := portfolio.NewPortfolioRepository(db)
+	portfolioService := portfolio.NewPortfolioService(portfolioRepo, ownership_history.NewOwnershipHistoryRepository(db), lineup.NewLineupRepository(db))
+	leagueService := league.NewLeagueService(league.NewLeagueRepository(db), userRepo, portfolioRepo, nil)
+	leaguePortfolioService := league_portfolio.NewLeaguePortfolioService(league_portfolio.NewLeaguePortfolioRepository(db), stockRepo, portfolioRepo,
+		ownership_history.NewOwnershipHistoryService(ownership_history.NewOwnershipHistoryRepository(db), stockRepo),
+		lineup.NewLineupService(lineup.NewLineupRepository(db), stockRepo), leagueService, userRepo)
+	leagueService.SetLeaguePortfolioService(leaguePortfolioService)
+	return db, leagueService, portfolioService
+}
+
+// createLeagueWithPlayers creates a league with a portfolio and a league player for each user
+func createLeagueWithPlayers(t *testing.T, db *gorm.DB, league *models.League, users []*models.User, status models.DraftStatus) []models.Portfolio {
+	league.Users = nil
+	for _, u := range users {
+		league.Users = append(league.Users, *u)
+	}
+	assert.NoError(t, db.Create(league).Error)
+	var portfolios []models.Portfolio
+	for _, u := range users {
+		assert.NoError(t, db.Create(&models.LeaguePlayer{LeagueID: league.ID, PlayerID: u.ID, DraftStatus: status}).Error)
+		p := models.Portfolio{UserID: u.ID, LeagueID: league.ID}
+		assert.NoError(t, db.Create(&p).Error)
+		portfolios = append(portfolios, p)
+	}
+	return portfolios
+}
+
+func TestStartDueDrafts_AutopicksAbsentPlayers(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	for _, s := range []models.Stock{{TickerSymbol: "AAA", CurrentPrice: 10}, {TickerSymbol: "BBB", CurrentPrice: 20}, {TickerSymbol: "CCC", CurrentPrice: 30}} {
+		assert.NoError(t, db.Create(&s).Error)
+	}
+	owner := models.User{Username: "owner", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &other}).Error)
+
+	now := time.Now()
+	due := now.Add(-time.Minute)
+	later := now.Add(time.Hour)
+	draft := models.League{LeagueName: "Due", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, DraftTime: &due, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &draft, []*models.User{&owner, &other}, models.DraftNotReady)
+	waiting := models.League{LeagueName: "Later", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, DraftTime: &later, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &waiting, []*models.User{&owner}, models.DraftNotReady)
+
+	leaguePortfolioService := league_portfolio.NewLeaguePortfolioService(league_portfolio.NewLeaguePortfolioRepository(db), stock.NewStockRepository(db), nil, nil, nil, nil, nil)
+	_, err := leaguePortfolioService.CreateLeaguePortfolio(draft.ID, models.StockSelection{})
+	assert.NoError(t, err)
+
+	// Only the draft whose time has come starts, and nobody who queued up is waited for
+	started, err := leagueService.StartDueDrafts(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, started)
+	var players []models.LeaguePlayer
+	assert.NoError(t, db.Where("league_id = ?", draft.ID).Find(&players).Error)
+	for _, player := range players {
+		assert.Equal(t, models.DraftAutoPick, player.DraftStatus)
+	}
+
+	assert.Eventually(t, func() bool {
+		var current models.League
+		return db.First(&current, draft.ID).Error == nil && current.LeagueState == models.PostDraft
+	}, 5*time.Second, 20*time.Millisecond)
+	portfolios, err := leagueService.GetPlayerPortfoliosInLeague(draft.ID)
+	assert.NoError(t, err)
+	for _, p := range portfolios {
+		assert.Len(t, p.Stocks, 1)
+	}
+
+	// A started draft is not started again, and the state machine refuses to go backwards
+	started, err = leagueService.StartDueDrafts(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, started)
+	moved, err := leagueService.Transition(draft.ID, league.Event_StartDraft)
+	assert.NoError(t, err)
+	assert.False(t, moved)
+	var untouched models.League
+	assert.NoError(t, db.First(&untouched, waiting.ID).Error)
+	assert.Equal(t, models.PreDraft, untouched.LeagueState)
+}
+
+func TestLockDueRosters_ClosesTrades(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &other}).Error)
+
+	now := time.Now()
+	locking := models.League{LeagueName: "Locking", StartingSlots: 1, LeagueState: models.PostDraft, LockRosters: true, StartDate: now.Add(-time.Hour), EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &locking, []*models.User{&owner, &other}, models.DraftReady)
+	open := models.League{LeagueName: "Open", StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now.Add(-time.Hour), EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &open, []*models.User{&owner}, models.DraftReady)
+
+	locked, err := leagueService.LockDueRosters(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, locked)
+	locked, err = leagueService.LockDueRosters(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, locked)
+
+	var lockedLeague, openLeague models.League
+	assert.NoError(t, db.First(&lockedLeague, locking.ID).Error)
+	assert.NotNil(t, lockedLeague.RostersLockedAt)
+	assert.NoError(t, db.First(&openLeague, open.ID).Error)
+	assert.Nil(t, openLeague.RostersLockedAt)
+
+	tradeService := trade.NewTradeService(trade.NewTradeRepository(db), stock.NewStockRepository(db), portfolio.NewPortfolioRepository(db), user.NewUserRepository(db), nil, nil)
+	_, err = tradeService.CreateTrade(locking.ID, owner.ID, other.ID, nil, nil)
+	assert.ErrorContains(t, err, "rosters locked")
+
+	// Stocks can no longer be added, removed or swapped in and out of the lineup either
+	var lockedPortfolio models.Portfolio
+	assert.NoError(t, db.Where("league_id = ? AND user_id = ?", locking.ID, owner.ID).First(&lockedPortfolio).Error)
+	portfolioService := portfolio.NewPortfolioService(portfolio.NewPortfolioRepository(db), ownership_history.NewOwnershipHistoryRepository(db), lineup.NewLineupRepository(db))
+	assert.ErrorContains(t, portfolioService.AddStockToPortfolio(lockedPortfolio.ID, 1), "rosters locked")
+	assert.ErrorContains(t, portfolioService.RemoveStockFromPortfolio(lockedPortfolio.ID, 1), "rosters locked")
+	lineupService := lineup.NewLineupService(lineup.NewLineupRepository(db), stock.NewStockRepository(db))
+	_, err = lineupService.SwapStocks(lockedPortfolio.ID, owner.ID, 1, 0)
+	assert.ErrorContains(t, err, "rosters are locked")
+}
+
+func TestCompleteDueLeagues_FinalScoringAndAwards(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	stocks := []models.Stock{{TickerSymbol: "AAA"}, {TickerSymbol: "BBB"}, {TickerSymbol: "CCC"}}
+	for index := range stocks {
+		assert.NoError(t, db.Create(&stocks[index]).Error)
+	}
+	first := models.User{Username: "first", Password: "x"}
+	second := models.User{Username: "second", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&first, &second}).Error)
+
+	now := time.Now()
+	ended := models.League{LeagueName: "Ended", StartingSlots: 2, LeagueState: models.PostDraft, StartDate: now.AddDate(0, -1, 0), EndDate: now.Add(-time.Hour)}
+	portfolios := createLeagueWithPlayers(t, db, &ended, []*models.User{&first, &second}, models.DraftReady)
+	running := models.League{LeagueName: "Running", StartingSlots: 2, LeagueState: models.PostDraft, StartDate: now.AddDate(0, -1, 0), EndDate: now.AddDate(0, 0, 2)}
+	createLeagueWithPlayers(t, db, &running, []*models.User{&first}, models.DraftReady)
+
+	// The first portfolio gains 10% on one stock, the second 5% on each of two, so they tie for champion
+	holdings := map[uint][]models.Stock{portfolios[0].ID: {stocks[0]}, portfolios[1].ID: {stocks[1], stocks[2]}}
+	windows := []models.ScoringWindow{
+		{PortfolioID: portfolios[0].ID, StockID: stocks[0].ID, StartingValue: 100, CurrentValue: 110},
+		{PortfolioID: portfolios[1].ID, StockID: stocks[1].ID, StartingValue: 100, CurrentValue: 105},
+		{PortfolioID: portfolios[1].ID, StockID: stocks[2].ID, StartingValue: 100, CurrentValue: 105},
+	}
+	for portfolioID, held := range holdings {
+		assert.NoError(t, db.Model(&models.Portfolio{ID: portfolioID}).Association("Stocks").Append(held))
+	}
+	assert.NoError(t, db.Create(&windows).Error)
+
+	// The day was already scored, the final scoring takes the place of its entries so the last day counts once
+	assert.NoError(t, portfolioService.CalculateLeaguePortfolioTotalValues(ended.ID, "portfolio-totals:today"))
+	completed, err := leagueService.CompleteDueLeagues(now.Add(time.Hour), "portfolio-totals:today", portfolioService)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, completed)
+
+	league, _, err := leagueService.GetLeagueDetails(ended.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Completed, league.LeagueState)
+	champions := []uint{}
+	for _, award := range league.Awards {
+		switch award.Award {
+		case models.Award_Champion:
+			champions = append(champions, award.UserID)
+			assert.Equal(t, 10.0, award.Points)
+		case models.Award_BestPick:
+			assert.Equal(t, first.ID, award.UserID)
+			assert.Equal(t, stocks[0].ID, *award.StockID)
+		}
+	}
+	assert.ElementsMatch(t, []uint{first.ID, second.ID}, champions)
+	assert.Len(t, league.Awards, 3)
+
+	// Completing again changes nothing, the final scores and awards are replaced rather than added
+	moved, err := leagueService.CompleteLeague(ended.ID, "portfolio-totals:today", portfolioService)
+	assert.NoError(t, err)
+	assert.False(t, moved)
+	var awards, entries int64
+	assert.NoError(t, db.Model(&models.LeagueAward{}).Where("league_id = ?", ended.ID).Count(&awards).Error)
+	assert.Equal(t, int64(3), awards)
+	assert.NoError(t, db.Model(&models.PortfolioPointsHistory{}).Count(&entries).Error)
+	assert.Equal(t, int64(2), entries)
+
+	var current models.League
+	assert.NoError(t, db.First(&current, running.ID).Error)
+	assert.Equal(t, models.PostDraft, current.LeagueState)
+}
+
+func TestCompleteLeague_WaitsForTheDraftToFinish(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	now := time.Now()
+	drafting := models.League{LeagueName: "Drafting", StartingSlots: 1, LeagueState: models.InDraft, StartDate: now.AddDate(0, -1, 0), EndDate: now.Add(-time.Hour)}
+	assert.NoError(t, db.Create(&drafting).Error)
+
+	completed, err := leagueService.CompleteDueLeagues(now, "portfolio-totals:today", portfolioService)
+	assert.ErrorContains(t, err, "still drafting")
+	assert.Equal(t, 0, completed)
+	moved, err := leagueService.Transition(drafting.ID, league.Event_Complete)
+	assert.NoError(t, err)
+	assert.False(t, moved)
+
+	var current models.League
+	assert.NoError(t, db.First(&current, drafting.ID).Error)
+	assert.Equal(t, models.InDraft, current.LeagueState)
+}
@@ -0,0 +1,170 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	ws "github.com/market-league/api/websocket"
+	"github.com/market-league/internal/league"
+	"github.com/market-league/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMembershipRules_CapacityAndDraftState(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	first := models.User{Username: "first", Password: "x"}
+	second := models.User{Username: "second", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &first, &second}).Error)
+
+	now := time.Now()
+	two := 2
+	small := models.League{LeagueName: "Small", OwnerID: owner.ID, MaxPlayers: &two, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &small, []*models.User{&owner}, models.DraftNotReady)
+	drafted := models.League{LeagueName: "Drafted", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &drafted, []*models.User{&owner}, models.DraftReady)
+
+	// The last open slot goes to the first user to redeem, every way in applies the same limit
+	invite, err := leagueService.CreateInvite(small.ID, owner.ID, nil, nil)
+	assert.NoError(t, err)
+	_, err = leagueService.RedeemInvite(invite.Code, first.ID)
+	assert.NoError(t, err)
+	_, err = leagueService.RedeemInvite(invite.Code, second.ID)
+	assert.ErrorContains(t, err, "the league is full")
+	_, err = leagueService.AddUserToLeague(owner.ID, second.ID, small.ID)
+	assert.ErrorContains(t, err, "the league is full")
+	var stored models.LeagueInvite
+	assert.NoError(t, db.First(&stored, invite.ID).Error)
+	assert.Equal(t, 1, stored.Uses)
+
+	// Nobody joins once the draft has started
+	_, err = leagueService.AddUserToLeague(owner.ID, second.ID, drafted.ID)
+	assert.ErrorContains(t, err, "before the draft starts")
+	_, err = leagueService.CreateInvite(drafted.ID, owner.ID, nil, nil)
+	assert.ErrorContains(t, err, "before the draft starts")
+}
+
+func TestStartDueDrafts_WaitsForMinimumPlayers(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	assert.NoError(t, db.Create(&owner).Error)
+
+	now := time.Now()
+	due := now.Add(-time.Minute)
+	lonely := models.League{LeagueName: "Lonely", OwnerID: owner.ID, StartingSlots: 1, MinPlayers: models.DefaultMinPlayers, LeagueState: models.PreDraft, DraftTime: &due, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &lonely, []*models.User{&owner}, models.DraftNotReady)
+
+	started, err := leagueService.StartDueDrafts(now)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, started)
+	var current models.League
+	assert.NoError(t, db.First(&current, lonely.ID).Error)
+	assert.Equal(t, models.PreDraft, current.LeagueState)
+
+	// A league stored without a minimum, like the ones from before it was added, can still draft alone
+	var unset models.League
+	assert.NoError(t, db.Exec("INSERT INTO leagues (league_name, owner_id, starting_slots, start_date, end_date) VALUES (?, ?, ?, ?, ?)",
+		"Solo", owner.ID, 1, now, now.AddDate(0, 1, 0)).Error)
+	assert.NoError(t, db.Where("league_name = ?", "Solo").First(&unset).Error)
+	assert.Equal(t, 1, unset.MinPlayers)
+}
+
+func TestLeaveLeague_ReturnsStocksToPool(t *testing.T) {
+	db, leagueService, _ := newLeagueServices(t)
+	stocks := []models.Stock{{TickerSymbol: "AAA"}, {TickerSymbol: "BBB"}}
+	for index := range stocks {
+		assert.NoError(t, db.Create(&stocks[index]).Error)
+	}
+	owner := models.User{Username: "owner", Password: "x"}
+	leaver := models.User{Username: "leaver", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &leaver}).Error)
+
+	now := time.Now()
+	league := models.League{LeagueName: "Drafted", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	portfolios := createLeagueWithPlayers(t, db, &league, []*models.User{&owner, &leaver}, models.DraftReady)
+	pool := models.LeaguePortfolio{LeagueID: league.ID, Name: "Pool"}
+	assert.NoError(t, db.Omit("League").Create(&pool).Error)
+	assert.NoError(t, db.Model(&models.Portfolio{ID: portfolios[0].ID}).Association("Stocks").Append(&stocks[0]))
+	assert.NoError(t, db.Model(&models.Portfolio{ID: portfolios[1].ID}).Association("Stocks").Append(&stocks[1]))
+	assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: portfolios[1].ID, StockID: stocks[1].ID, StartingValue: 10, CurrentValue: 11}).Error)
+	trade := models.Trade{LeagueID: league.ID, User1ID: owner.ID, User2ID: leaver.ID, Portfolio1ID: portfolios[0].ID, Portfolio2ID: portfolios[1].ID, Status: "pending"}
+	assert.NoError(t, db.Omit("User1", "User2").Create(&trade).Error)
+	completed := models.Trade{LeagueID: league.ID, User1ID: owner.ID, User2ID: leaver.ID, Portfolio1ID: portfolios[0].ID, Portfolio2ID: portfolios[1].ID, Status: "completed"}
+	assert.NoError(t, db.Omit("User1", "User2").Create(&completed).Error)
+
+	assert.ErrorContains(t, leagueService.LeaveLeague(league.ID, owner.ID), "owner cannot leave")
+	assert.NoError(t, leagueService.LeaveLeague(league.ID, leaver.ID))
+	assert.ErrorContains(t, leagueService.LeaveLeague(league.ID, leaver.ID), "is not in league")
+
+	// The stock is back in the pool, and the player and their pending trade are gone from the league
+	var returned models.LeaguePortfolio
+	assert.NoError(t, db.Preload("Stocks").First(&returned, pool.ID).Error)
+	assert.Len(t, returned.Stocks, 1)
+	assert.Equal(t, stocks[1].ID, returned.Stocks[0].ID)
+	remaining := []struct {
+		table  string
+		column string
+		id     uint
+	}{
+		{"portfolio_stocks", "portfolio_id", portfolios[1].ID},
+		{"league_players", "player_id", leaver.ID},
+		{"user_leagues", "user_id", leaver.ID},
+		{"trades", "id", trade.ID},
+	}
+	for _, r := range remaining {
+		var count int64
+		assert.NoError(t, db.Table(r.table).Where(r.column+" = ?", r.id).Count(&count).Error)
+		assert.Equal(t, int64(0), count, r.table)
+	}
+
+	// Their drafted portfolio stays on record for the completed trade, closed and out of the standings
+	var left models.Portfolio
+	assert.NoError(t, db.First(&left, portfolios[1].ID).Error)
+	assert.NotNil(t, left.LeftAt)
+	var window models.ScoringWindow
+	assert.NoError(t, db.Where("portfolio_id = ?", portfolios[1].ID).First(&window).Error)
+	assert.NotNil(t, window.EndDate)
+	assert.NoError(t, db.First(&completed, completed.ID).Error)
+	assert.Equal(t, portfolios[1].ID, completed.Portfolio2ID)
+	standings, err := leagueService.GetPlayerPortfoliosInLeague(league.ID)
+	assert.NoError(t, err)
+	assert.Len(t, standings, 1)
+
+	// Before the draft there is nothing to keep, the portfolio goes with the player
+	waiting := models.League{LeagueName: "Waiting", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	waitingPortfolios := createLeagueWithPlayers(t, db, &waiting, []*models.User{&owner, &leaver}, models.DraftNotReady)
+	assert.NoError(t, leagueService.LeaveLeague(waiting.ID, leaver.ID))
+	var waitingCount int64
+	assert.NoError(t, db.Model(&models.Portfolio{}).Where("id = ?", waitingPortfolios[1].ID).Count(&waitingCount).Error)
+	assert.Equal(t, int64(0), waitingCount)
+
+	// Players stay for the whole draft
+	drafting := models.League{LeagueName: "Drafting", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.InDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &drafting, []*models.User{&owner, &leaver}, models.DraftReady)
+	assert.ErrorContains(t, leagueService.LeaveLeague(drafting.ID, leaver.ID), "during the draft")
+}
+
+func TestLeaveLeagueHandler_OnlyTakesOutTheSignedInUser(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	owner := models.User{Username: "owner", Password: "x"}
+	player := models.User{Username: "player", Password: "x"}
+	other := models.User{Username: "other", Password: "x"}
+	assert.NoError(t, db.Create(&[]*models.User{&owner, &player, &other}).Error)
+
+	now := time.Now()
+	waiting := models.League{LeagueName: "Waiting", OwnerID: owner.ID, StartingSlots: 1, LeagueState: models.PreDraft, StartDate: now, EndDate: now.AddDate(0, 1, 0)}
+	createLeagueWithPlayers(t, db, &waiting, []*models.User{&owner, &player, &other}, models.DraftNotReady)
+	handler := league.NewLeagueHandler(leagueService, portfolioService, nil)
+	client, conn, closeConn := connectHandler(t, player.ID)
+	defer closeConn()
+
+	// Naming another player is refused, and without a user_id the signed-in player leaves
+	assert.Error(t, handler.LeaveLeague(conn, jsonPayload(t, map[string]interface{}{"league_id": waiting.ID, "user_id": other.ID})))
+	readError(t, client)
+	assert.NoError(t, handler.LeaveLeague(conn, jsonPayload(t, map[string]interface{}{"league_id": waiting.ID})))
+	assert.Equal(t, ws.MessageType_League_LeaveLeague, readMessage(t, client).Type)
+
+	var members []uint
+	assert.NoError(t, db.Table("user_leagues").Where("league_id = ?", waiting.ID).Order("user_id").Pluck("user_id", &members).Error)
+	assert.Equal(t, []uint{owner.ID, other.ID}, members)
+}
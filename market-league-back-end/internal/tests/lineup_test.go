@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/lineup"
+	"github.com/market-league/internal/models"
+	ownership_history "github.com/market-league/internal/ownership_history"
+	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackfillScoringWindows_CapsStartersAtStartingSlots(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.League{}, &models.OwnershipHistory{}, &models.ScoringWindow{}))
+
+	draftedAt := time.Date(2025, 3, 3, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, db.Create(&models.League{ID: 1, LeagueName: "Backfill", StartingSlots: 2}).Error)
+	assert.NoError(t, db.Create(&models.Portfolio{ID: 1, LeagueID: 1, UserID: 1}).Error)
+	soldAt := draftedAt.Add(time.Hour)
+	assert.NoError(t, db.Create(&models.OwnershipHistory{PortfolioID: 1, StockID: 9, StartDate: draftedAt, EndDate: &soldAt}).Error)
+	for stockID := uint(1); stockID <= 3; stockID++ {
+		assert.NoError(t, db.Create(&models.OwnershipHistory{PortfolioID: 1, StockID: stockID, StartDate: draftedAt.Add(time.Duration(stockID) * time.Minute)}).Error)
+	}
+
+	service := lineup.NewLineupService(lineup.NewLineupRepository(db), stock.NewStockRepository(db))
+	assert.NoError(t, service.BackfillScoringWindows())
+
+	// The sold stock keeps its history and only the first two stocks drafted start
+	var windows []models.ScoringWindow
+	assert.NoError(t, db.Order("stock_id").Find(&windows).Error)
+	assert.Len(t, windows, 3)
+	assert.Equal(t, uint(1), windows[0].StockID)
+	assert.Equal(t, uint(2), windows[1].StockID)
+	assert.Equal(t, uint(9), windows[2].StockID)
+	assert.NotNil(t, windows[2].EndDate)
+}
+
+func TestGetLineup_RejectsAnotherUsersPortfolio(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.League{}, &models.ScoringWindow{}))
+
+	assert.NoError(t, db.Create(&models.League{ID: 1, LeagueName: "Lineups"}).Error)
+	assert.NoError(t, db.Create(&models.Portfolio{ID: 1, LeagueID: 1, UserID: 1}).Error)
+
+	service := lineup.NewLineupService(lineup.NewLineupRepository(db), stock.NewStockRepository(db))
+
+	_, err := service.GetLineup(1, 2)
+	assert.Error(t, err)
+	_, err = service.SwapStocks(1, 2, 1, 0)
+	assert.Error(t, err)
+
+	found, err := service.GetLineup(1, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), found.PortfolioID)
+}
+
+func TestRemoveStockFromPortfolio_FreesTheStartingSlot(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.League{}, &models.RosterPosition{}, &models.ScoringWindow{}))
+
+	assert.NoError(t, db.Create(&models.League{ID: 1, LeagueName: "Roster Moves", StartingSlots: 1, LeagueState: models.PostDraft}).Error)
+	assert.NoError(t, db.Create(&models.Portfolio{ID: 1, LeagueID: 1, UserID: 1}).Error)
+	for _, s := range []models.Stock{{ID: 1, TickerSymbol: "AAA", CurrentPrice: 10}, {ID: 2, TickerSymbol: "BBB", CurrentPrice: 20}, {ID: 3, TickerSymbol: "CCC", CurrentPrice: 30}} {
+		assert.NoError(t, db.Create(&s).Error)
+	}
+
+	service := portfolio.NewPortfolioService(portfolio.NewPortfolioRepository(db), ownership_history.NewOwnershipHistoryRepository(db), lineup.NewLineupRepository(db))
+	lineupRepo := lineup.NewLineupRepository(db)
+
+	// The first stock added starts, the second sits on the bench
+	assert.NoError(t, service.AddStockToPortfolio(1, 1))
+	assert.NoError(t, service.AddStockToPortfolio(1, 2))
+	active, err := lineupRepo.GetActiveWindowsForPortfolio(1)
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+	assert.Equal(t, uint(1), active[0].StockID)
+
+	// Removing the starter closes its window, so it stops scoring and the next stock added takes its slot
+	assert.NoError(t, service.RemoveStockFromPortfolio(1, 1))
+	windows, err := lineupRepo.GetAllWindowsByStockIDAndPortfolioID(1, 1)
+	assert.NoError(t, err)
+	assert.Len(t, windows, 1)
+	assert.NotNil(t, windows[0].EndDate)
+
+	assert.NoError(t, service.AddStockToPortfolio(1, 3))
+	active, err = lineupRepo.GetActiveWindowsForPortfolio(1)
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+	assert.Equal(t, uint(3), active[0].StockID)
+	assert.Equal(t, 30.0, active[0].StartingValue)
+
+	// Removing a bench stock leaves the lineup alone
+	assert.NoError(t, service.RemoveStockFromPortfolio(1, 2))
+	active, err = lineupRepo.GetActiveWindowsForPortfolio(1)
+	assert.NoError(t, err)
+	assert.Len(t, active, 1)
+}
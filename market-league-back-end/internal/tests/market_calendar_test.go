@@ -0,0 +1,101 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/lineup"
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/scoring"
+	"github.com/market-league/internal/trade"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuiltinCalendar_HolidaysAndEarlyCloses(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+
+	goodFriday := time.Date(2025, 4, 18, 10, 0, 0, 0, location)
+	assert.False(t, utils.IsTradingDay(goodFriday))
+	assert.False(t, utils.IsMarketOpen(goodFriday))
+	name, ok := utils.Calendar().Holiday(goodFriday)
+	assert.True(t, ok)
+	assert.Equal(t, "Good Friday", name)
+
+	// The day after Thanksgiving closes at noon Chicago time
+	halfDay := time.Date(2025, 11, 28, 0, 0, 0, 0, location)
+	_, close := utils.MarketHours(halfDay)
+	assert.Equal(t, time.Date(2025, 11, 28, 12, 0, 0, 0, location), close)
+	assert.True(t, utils.IsMarketOpen(halfDay.Add(11*time.Hour)))
+	assert.False(t, utils.IsMarketOpen(halfDay.Add(12*time.Hour+30*time.Minute)))
+
+	// Thursday's run is followed by Monday's, skipping the holiday and the weekend
+	thursdayEvening := time.Date(2025, 4, 17, 16, 0, 0, 0, location)
+	assert.Equal(t, time.Date(2025, 4, 21, utils.DailyRunHour, utils.DailyRunMinute, 0, 0, location), utils.NextDailyRun(thursdayEvening))
+	assert.Equal(t, time.Date(2025, 4, 21, 8, 30, 0, 0, location), utils.NextMarketOpen(thursdayEvening))
+	assert.Equal(t, time.Date(2025, 4, 17, 0, 0, 0, 0, location), utils.LastTradingDay(time.Date(2025, 4, 20, 12, 0, 0, 0, location)))
+}
+
+func TestReadMarketCalendarCSV(t *testing.T) {
+	calendar, err := utils.ReadMarketCalendarCSV(strings.NewReader("# test calendar\ndate,type,close,name\n2030-01-02,holiday,,Snow Day\n2030-01-03,early_close,11:15,Half Day\n"))
+	assert.NoError(t, err)
+
+	utils.SetCalendar(calendar)
+	defer utils.SetCalendar(nil)
+
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	assert.False(t, utils.IsTradingDay(time.Date(2030, 1, 2, 0, 0, 0, 0, location)))
+	_, close := utils.MarketHours(time.Date(2030, 1, 3, 0, 0, 0, 0, location))
+	assert.Equal(t, time.Date(2030, 1, 3, 11, 15, 0, 0, location), close)
+
+	// The built-in holidays are gone with the custom calendar
+	assert.True(t, utils.IsTradingDay(time.Date(2025, 4, 18, 0, 0, 0, 0, location)))
+
+	_, err = utils.ReadMarketCalendarCSV(strings.NewReader("date,type,close,name\n2030-01-02,closed,,Snow Day\n"))
+	assert.Error(t, err)
+	_, err = utils.ReadMarketCalendarCSV(strings.NewReader("date,type,close,name\n2030-01-03,early_close,noon,Half Day\n"))
+	assert.Error(t, err)
+}
+
+func TestMarketCalendar_LineupLocksAndTradeDeadline(t *testing.T) {
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+
+	// Lineups stay open on a holiday
+	locked, _, err := lineup.LineupLockStatus(time.Date(2025, 4, 18, 10, 0, 0, 0, location))
+	assert.NoError(t, err)
+	assert.False(t, locked)
+	locked, _, err = lineup.LineupLockStatus(time.Date(2025, 4, 17, 10, 0, 0, 0, location))
+	assert.NoError(t, err)
+	assert.True(t, locked)
+
+	// A league ending on Easter weekend stops trading at Thursday's close
+	deadline, err := trade.TradeDeadline(time.Date(2025, 4, 19, 12, 0, 0, 0, location))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 4, 17, 15, 0, 0, 0, location), deadline)
+
+	// And a league ending on a half day at its early close
+	deadline, err = trade.TradeDeadline(time.Date(2025, 11, 28, 12, 0, 0, 0, location))
+	assert.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 11, 28, 12, 0, 0, 0, location), deadline)
+}
+
+func TestRecompute_SkipsMarketHolidays(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.League{}, &models.PriceHistory{}, &models.OwnershipHistory{}, &models.ScoringWindow{}, &models.CorporateAction{}, &models.PortfolioPointsHistory{}, &models.PointsBreakdown{}))
+
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	monday := time.Date(2025, 4, 14, 8, 0, 0, 0, location)
+	assert.NoError(t, db.Create(&models.League{ID: 1, LeagueName: "Easter", StartDate: monday}).Error)
+	assert.NoError(t, db.Create(&models.Portfolio{ID: 1, LeagueID: 1}).Error)
+
+	service := scoring.NewScoringService(scoring.NewScoringRepository(db))
+	result, err := service.Recompute(1, "2025-04-14", "2025-04-20", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, result.Days)
+}
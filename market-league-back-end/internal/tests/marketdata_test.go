@@ -0,0 +1,100 @@
+package tests
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/marketdata"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewProvider_SelectsFromConfig(t *testing.T) {
+	provider, err := marketdata.NewProvider(marketdata.Config{Provider: marketdata.ProviderFinnhub, FinnhubAPIKey: "key"})
+	assert.NoError(t, err)
+	assert.Equal(t, "finnhub", provider.Name())
+
+	_, err = marketdata.NewProvider(marketdata.Config{Provider: marketdata.ProviderFinnhub})
+	assert.Error(t, err)
+
+	_, err = marketdata.NewProvider(marketdata.Config{Provider: "bloomberg"})
+	assert.Error(t, err)
+}
+
+// fixedClock is a clock that only moves when the test moves it
+type fixedClock struct{ now time.Time }
+
+func (c *fixedClock) Now() time.Time        { return c.now }
+func (c *fixedClock) Sleep(d time.Duration) { c.now = c.now.Add(d) }
+
+func TestReplayProvider_QuotesFollowTheClock(t *testing.T) {
+	bars, err := marketdata.ReadReplayCSV(strings.NewReader(`symbol,timestamp,price
+AAPL,2024-03-04,100
+AAPL,2024-03-05,102
+AAPL,2024-03-11,110
+`))
+	assert.NoError(t, err)
+
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	clock := &fixedClock{now: time.Date(2024, 3, 4, 9, 0, 0, 0, location)}
+	provider := marketdata.NewReplayProvider(bars, clock)
+
+	// Before the first close there is nothing to quote
+	_, err = provider.GetQuote(context.Background(), "AAPL")
+	assert.ErrorIs(t, err, marketdata.ErrNoData)
+
+	// The next morning's run sees the previous close
+	clock.Sleep(24 * time.Hour)
+	quote, err := provider.GetQuote(context.Background(), "aapl")
+	assert.NoError(t, err)
+	assert.Equal(t, 100.0, quote.Current)
+
+	clock.Sleep(24 * time.Hour)
+	quote, err = provider.GetQuote(context.Background(), "AAPL")
+	assert.NoError(t, err)
+	assert.Equal(t, 102.0, quote.Current)
+	assert.Equal(t, 100.0, quote.PreviousClose)
+
+	// Weekly candles only include bars the clock has reached
+	clock.Sleep(7 * 24 * time.Hour)
+	candles, err := provider.GetCandles(context.Background(), "AAPL", marketdata.ResolutionWeek, clock.now.AddDate(0, -1, 0), clock.now.AddDate(0, 1, 0))
+	assert.NoError(t, err)
+	assert.Len(t, candles, 2)
+	assert.Equal(t, 100.0, candles[0].Open)
+	assert.Equal(t, 102.0, candles[0].Close)
+	assert.Equal(t, 110.0, candles[1].Close)
+}
+
+// profileProvider answers every symbol with the same company profile
+type profileProvider struct {
+	marketdata.MarketDataProvider
+	profile marketdata.CompanyProfile
+}
+
+func (p *profileProvider) GetCompanyProfile(ctx context.Context, symbol string) (*marketdata.CompanyProfile, error) {
+	return &p.profile, nil
+}
+
+func TestCreateStock_FillsSectorFromProfile(t *testing.T) {
+	db := testutils.SetupTestDB()
+	provider := &profileProvider{profile: marketdata.CompanyProfile{Name: "Apple Inc", Sector: "Information Technology", Industry: "Technology"}}
+	service := stock.NewStockService(stock.NewStockRepository(db), provider)
+
+	created, err := service.CreateStock("AAPL", "", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Apple Inc", created.CompanyName)
+	assert.Equal(t, "Information Technology", created.Sector)
+	assert.Equal(t, "Technology", created.Industry)
+
+	// Without a provider the stock is created from what the caller gave
+	unavailable := stock.NewStockService(stock.NewStockRepository(db), marketdata.NewUnavailableProvider(assert.AnError))
+	created, err = unavailable.CreateStock("MSFT", "Microsoft", "", "")
+	assert.NoError(t, err)
+	assert.Equal(t, "Microsoft", created.CompanyName)
+	assert.Empty(t, created.Sector)
+}
@@ -0,0 +1,88 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/scoring"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompactPriceHistory_RollsOldPricesIntoDailyBars(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}, &models.DailyPriceBar{}, &models.CompactedRange{}))
+	repo := stock.NewStockRepository(db)
+	service := stock.NewStockService(repo, nil)
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	at := func(month time.Month, day, hour int) time.Time {
+		return time.Date(2025, month, day, hour, 0, 0, 0, location)
+	}
+	utils.SetClock(utils.NewSimulatedClock(at(time.June, 16, 12), 1))
+	defer utils.SetClock(nil)
+
+	s := models.Stock{TickerSymbol: "AAA"}
+	assert.NoError(t, db.Create(&s).Error)
+	record := func(when time.Time, price float64) {
+		assert.NoError(t, db.Create(&models.PriceHistory{StockID: s.ID, Timestamp: when, Price: price}).Error)
+	}
+	record(at(time.March, 3, 9), 10)
+	record(at(time.March, 3, 12), 14)
+	record(at(time.March, 3, 15), 12)
+	record(at(time.March, 4, 15), 11)
+	record(at(time.June, 10, 15), 30)
+
+	// Everything before May 17th goes into one bar per day
+	result, err := service.CompactPriceHistory(30)
+	assert.NoError(t, err)
+	assert.Equal(t, at(time.May, 17, 0), result.Before)
+	assert.Equal(t, 1, result.Stocks)
+	assert.Equal(t, 4, result.Points)
+	assert.Equal(t, 2, result.Bars)
+
+	var raw int64
+	db.Model(&models.PriceHistory{}).Count(&raw)
+	assert.Equal(t, int64(1), raw)
+	var bars []models.DailyPriceBar
+	assert.NoError(t, db.Order("date ASC").Find(&bars).Error)
+	assert.Len(t, bars, 2)
+	assert.Equal(t, []float64{10, 14, 10, 12}, []float64{bars[0].Open, bars[0].High, bars[0].Low, bars[0].Close})
+	assert.Equal(t, 3, bars[0].Points)
+	ranges, err := repo.GetCompactedRanges(s.ID)
+	assert.NoError(t, err)
+	assert.Len(t, ranges, 1)
+	assert.True(t, ranges[0].From.Equal(at(time.March, 3, 9)))
+
+	// Running it again finds nothing, a late price is merged into its day
+	result, err = service.CompactPriceHistory(30)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Stocks)
+	record(at(time.March, 3, 14), 20)
+	_, err = service.CompactPriceHistory(30)
+	assert.NoError(t, err)
+	assert.NoError(t, db.Order("date ASC").Find(&bars).Error)
+	assert.Len(t, bars, 2)
+	assert.Equal(t, 20.0, bars[0].High)
+	assert.Equal(t, 12.0, bars[0].Close) // The 15:00 price is still the last of the day
+	assert.Equal(t, 4, bars[0].Points)
+
+	// Charts and recomputes read compacted days alongside raw prices
+	from, to := at(time.March, 1, 0), at(time.June, 16, 0)
+	series, err := service.GetCandles(s.ID, models.CandleInterval_Day, &from, &to)
+	assert.NoError(t, err)
+	assert.Len(t, series.Candles, 3)
+	assert.Equal(t, models.Candle{Time: at(time.March, 3, 0), Open: 10, High: 20, Low: 10, Close: 12, Points: 4}, series.Candles[0])
+	assert.Equal(t, 30.0, series.Candles[2].Close)
+
+	history, err := scoring.NewScoringRepository(db).GetPriceHistory([]uint{s.ID}, to)
+	assert.NoError(t, err)
+	prices := []float64{}
+	for _, point := range history {
+		prices = append(prices, point.Price)
+	}
+	assert.Equal(t, []float64{10, 12, 11, 30}, prices)
+}
@@ -0,0 +1,95 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/marketdata"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyProvider fails each symbol a set number of times before quoting it
+type flakyProvider struct {
+	marketdata.MarketDataProvider
+	mutex    sync.Mutex
+	failures map[string]int
+	calls    map[string]int
+	inFlight int32
+	peak     int32
+}
+
+func (p *flakyProvider) GetQuote(ctx context.Context, symbol string) (*marketdata.Quote, error) {
+	current := atomic.AddInt32(&p.inFlight, 1)
+	defer atomic.AddInt32(&p.inFlight, -1)
+	for {
+		peak := atomic.LoadInt32(&p.peak)
+		if current <= peak || atomic.CompareAndSwapInt32(&p.peak, peak, current) {
+			break
+		}
+	}
+	time.Sleep(time.Millisecond)
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.calls[symbol]++
+	if symbol == "GONE" {
+		return nil, marketdata.ErrNoData
+	}
+	if p.failures[symbol] > 0 {
+		p.failures[symbol]--
+		return nil, errors.New("503 service unavailable")
+	}
+	return &marketdata.Quote{Symbol: symbol, Current: 10}, nil
+}
+
+func TestQuoteFetcher_RetriesTransientFailures(t *testing.T) {
+	provider := &flakyProvider{
+		failures: map[string]int{"AAPL": 2, "MSFT": 10},
+		calls:    map[string]int{},
+	}
+	fetcher := marketdata.NewQuoteFetcher(provider, marketdata.FetcherConfig{
+		Concurrency: 3,
+		MaxAttempts: 3,
+		BaseBackoff: time.Millisecond,
+		MaxBackoff:  5 * time.Millisecond,
+	})
+
+	var handled sync.Map
+	symbols := []string{"AAPL", "MSFT", "GONE", "JNJ", "JPM", "XOM", "NVDA"}
+	summary := fetcher.FetchQuotes(context.Background(), symbols, func(symbol string, quote *marketdata.Quote) error {
+		handled.Store(symbol, quote.Current)
+		return nil
+	})
+
+	assert.Equal(t, 7, summary.Requested)
+	assert.Equal(t, 5, summary.Succeeded)
+	assert.Equal(t, 4, summary.Retries) // AAPL twice, MSFT twice before giving up
+	assert.Len(t, summary.Failures, 2)
+	assert.Equal(t, "GONE", summary.Failures[0].Symbol)
+	assert.Equal(t, 1, summary.Failures[0].Attempts) // Unknown symbols are not retried
+	assert.Equal(t, "MSFT", summary.Failures[1].Symbol)
+	assert.Equal(t, 3, summary.Failures[1].Attempts)
+
+	_, ok := handled.Load("AAPL")
+	assert.True(t, ok)
+	assert.Equal(t, 3, provider.calls["AAPL"])
+	assert.LessOrEqual(t, atomic.LoadInt32(&provider.peak), int32(3))
+}
+
+func TestTokenBucket_LimitsRate(t *testing.T) {
+	// 600 a minute is one every 100ms once the burst of 2 is spent
+	bucket := marketdata.NewTokenBucket(600, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, bucket.Wait(context.Background()))
+	}
+	assert.GreaterOrEqual(t, time.Since(start), 150*time.Millisecond)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Error(t, bucket.Wait(ctx))
+}
@@ -0,0 +1,83 @@
+package tests
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecordQuote_QuarantinesSuspiciousPrices(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.PriceHistory{}, &models.QuarantinedQuote{}))
+	repo := stock.NewStockRepository(db)
+	service := stock.NewStockService(repo, nil)
+	service.Rules = stock.DefaultPriceRules()
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	at := func(day, hour int) *time.Time {
+		when := time.Date(2025, time.March, day, hour, 0, 0, 0, location)
+		return &when
+	}
+	utils.SetClock(utils.NewSimulatedClock(*at(20, 12), 1))
+	defer utils.SetClock(nil)
+
+	s := models.Stock{TickerSymbol: "AAA", CurrentPrice: 100}
+	assert.NoError(t, db.Create(&s).Error)
+	assert.NoError(t, db.Create(&models.PriceHistory{StockID: s.ID, Price: 100, Timestamp: *at(3, 15)}).Error)
+	currentPrice := func() float64 {
+		stock, err := repo.GetStockByID(s.ID)
+		assert.NoError(t, err)
+		return stock.CurrentPrice
+	}
+
+	// A zero and then a NaN share one pending quote, which cannot be approved
+	zero, err := service.RecordQuote(s.ID, 0, at(4, 9))
+	assert.ErrorIs(t, err, stock.ErrQuoteQuarantined)
+	assert.Equal(t, models.QuoteRule_InvalidPrice, zero.Rule)
+	nan, err := service.RecordQuote(s.ID, math.NaN(), at(4, 10))
+	assert.ErrorIs(t, err, stock.ErrQuoteQuarantined)
+	assert.Equal(t, zero.ID, nan.ID)
+	assert.Equal(t, 2, nan.Occurrences)
+	_, err = service.ReviewQuarantinedQuote(nan.ID, 1, true)
+	assert.Error(t, err)
+	rejected, err := service.ReviewQuarantinedQuote(nan.ID, 1, false)
+	assert.NoError(t, err)
+	assert.Equal(t, models.QuarantineRejected, rejected.Status)
+	assert.Equal(t, 100.0, currentPrice())
+
+	// An 80% jump waits for review, approving it records the price
+	jump, err := service.RecordQuote(s.ID, 180, at(4, 15))
+	assert.ErrorIs(t, err, stock.ErrQuoteQuarantined)
+	assert.Equal(t, models.QuoteRule_MaxMove, jump.Rule)
+	assert.Equal(t, 100.0, jump.PreviousPrice)
+	assert.Equal(t, 100.0, currentPrice())
+	approved, err := service.ReviewQuarantinedQuote(jump.ID, 1, true)
+	assert.NoError(t, err)
+	assert.Equal(t, models.QuarantineApproved, approved.Status)
+	assert.Equal(t, uint(1), *approved.ReviewedBy)
+	assert.Equal(t, 180.0, currentPrice())
+	_, err = service.ReviewQuarantinedQuote(jump.ID, 1, false)
+	assert.Error(t, err)
+
+	// The same price is fine for three trading days, the fourth is stale
+	for _, day := range []int{5, 6, 7} {
+		_, err = service.RecordQuote(s.ID, 180, at(day, 15))
+		assert.NoError(t, err)
+	}
+	stale, err := service.RecordQuote(s.ID, 180, at(10, 15))
+	assert.ErrorIs(t, err, stock.ErrQuoteQuarantined)
+	assert.Equal(t, models.QuoteRule_Stale, stale.Rule)
+
+	pending, err := service.GetQuarantinedQuotes(models.QuarantinePending)
+	assert.NoError(t, err)
+	assert.Len(t, pending, 1)
+	all, err := service.GetQuarantinedQuotes("")
+	assert.NoError(t, err)
+	assert.Len(t, all, 3)
+}
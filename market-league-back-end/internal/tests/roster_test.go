@@ -0,0 +1,63 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/roster"
+	"github.com/stretchr/testify/assert"
+)
+
+func sectorLeague() *models.League {
+	return &models.League{
+		StartingSlots: 5,
+		RosterPositions: []models.RosterPosition{
+			{Sector: "Technology", Count: 1},
+			{Sector: "Healthcare", Count: 1},
+			{Sector: "Financials", Count: 1},
+			{Sector: models.FlexPosition, Count: 2},
+		},
+	}
+}
+
+func TestValidateRoster_RejectsAllTech(t *testing.T) {
+	league := sectorLeague()
+	stocks := []models.Stock{
+		{ID: 1, Sector: "Technology"},
+		{ID: 2, Sector: "Technology"},
+		{ID: 3, Sector: "Technology"},
+	}
+
+	// Three tech stocks fill one tech and both flex slots, leaving room for healthcare and financials
+	assert.NoError(t, roster.ValidateRoster(league, stocks))
+
+	// A fourth tech stock leaves only one open slot for two required sectors
+	stocks = append(stocks, models.Stock{ID: 4, Sector: "Technology"})
+	assert.Error(t, roster.ValidateRoster(league, stocks))
+}
+
+func TestValidateRoster_TradeAwayRequiredSector(t *testing.T) {
+	league := sectorLeague()
+	stocks := []models.Stock{
+		{ID: 1, Sector: "Technology"},
+		{ID: 2, Sector: "healthcare"},
+		{ID: 3, Sector: "Financials"},
+		{ID: 4, Sector: "Energy"},
+		{ID: 5, Sector: "Energy"},
+	}
+	assert.NoError(t, roster.ValidateRoster(league, stocks))
+
+	// Swapping the only financials stock for another energy stock breaks the full roster
+	traded := roster.ApplyMove(stocks, []models.Stock{{ID: 3}}, []models.Stock{{ID: 6, Sector: "Energy"}})
+	assert.Len(t, traded, 5)
+	assert.Error(t, roster.ValidateRoster(league, traded))
+}
+
+func TestValidatePositions_ExceedsRosterSize(t *testing.T) {
+	positions := []models.RosterPosition{
+		{Sector: "Technology", Count: 3},
+		{Sector: "Healthcare", Count: 3},
+	}
+	assert.Error(t, roster.ValidatePositions(positions, 5))
+	assert.NoError(t, roster.ValidatePositions(positions, 6))
+}
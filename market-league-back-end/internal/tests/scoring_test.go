@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/lineup"
+	"github.com/market-league/internal/models"
+	ownership_history "github.com/market-league/internal/ownership_history"
+	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/scoring"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecompute_DryRunThenIdempotentRebuild(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.League{}, &models.PriceHistory{}, &models.OwnershipHistory{}, &models.ScoringWindow{}, &models.CorporateAction{}, &models.PortfolioPointsHistory{}, &models.PointsBreakdown{}, &models.DailyPriceBar{}))
+
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	monday := time.Date(2025, 3, 3, 8, 0, 0, 0, location)
+
+	assert.NoError(t, db.Create(&models.League{ID: 1, LeagueName: "Replay", StartDate: monday}).Error)
+	assert.NoError(t, db.Create(&models.Portfolio{ID: 1, LeagueID: 1}).Error)
+	assert.NoError(t, db.Create(&models.Stock{ID: 1, TickerSymbol: "MSFT"}).Error)
+	for day, price := range []float64{100, 110, 121} {
+		assert.NoError(t, db.Create(&models.PriceHistory{StockID: 1, Price: price, Timestamp: monday.AddDate(0, 0, day)}).Error)
+	}
+	assert.NoError(t, db.Create(&models.OwnershipHistory{PortfolioID: 1, StockID: 1, StartingValue: 100, CurrentValue: 121, StartDate: monday}).Error)
+	assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: 1, StockID: 1, StartingValue: 100, CurrentValue: 121, StartDate: monday}).Error)
+
+	// A bad price on Tuesday was recorded as 50 points
+	assert.NoError(t, db.Create(&models.PortfolioPointsHistory{PortfolioID: 1, Points: 50, RecordedAt: monday.AddDate(0, 0, 1).Add(time.Hour)}).Error)
+
+	service := scoring.NewScoringService(scoring.NewScoringRepository(db))
+
+	result, err := service.Recompute(1, "2025-03-03", "2025-03-05", true)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result.Days)
+	assert.Len(t, result.Changes, 3)
+	assert.Nil(t, result.Changes[0].OldPoints)
+	assert.Equal(t, 0, *result.Changes[0].NewPoints)
+	assert.Equal(t, 50, *result.Changes[1].OldPoints)
+	assert.Equal(t, 10, *result.Changes[1].NewPoints)
+	assert.Equal(t, 21, *result.Changes[2].NewPoints)
+
+	// The dry run wrote nothing
+	var count int64
+	db.Model(&models.PortfolioPointsHistory{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	_, err = service.Recompute(1, "2025-03-03", "2025-03-05", false)
+	assert.NoError(t, err)
+	db.Model(&models.PortfolioPointsHistory{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+
+	var stored models.Portfolio
+	assert.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, 21, stored.Points)
+
+	// Running it again finds nothing to change
+	result, err = service.Recompute(1, "2025-03-03", "2025-03-05", true)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Changes)
+
+	// Wednesday's points drill down into the one window that earned them
+	portfolioService := portfolio.NewPortfolioService(portfolio.NewPortfolioRepository(db), ownership_history.NewOwnershipHistoryRepository(db), lineup.NewLineupRepository(db))
+	report, err := portfolioService.GetPointsBreakdown(1, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 21, report.Points)
+	assert.Equal(t, 10, *report.PreviousPoints)
+	assert.Equal(t, 11, report.PointsChange)
+	assert.Len(t, report.Components, 1)
+	assert.Equal(t, "MSFT", report.Components[0].TickerSymbol)
+	assert.Equal(t, 100.0, report.Components[0].StartPrice)
+	assert.Equal(t, 121.0, report.Components[0].EndPrice)
+	assert.InDelta(t, 11.0, report.Components[0].PointsChange, 0.0001)
+
+	db.Model(&models.PointsBreakdown{}).Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestRecompute_LeavesCompletedLeaguesAlone(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.League{}, &models.PriceHistory{}, &models.OwnershipHistory{}, &models.ScoringWindow{}, &models.CorporateAction{}, &models.PortfolioPointsHistory{}, &models.PointsBreakdown{}, &models.DailyPriceBar{}))
+
+	location, err := utils.MarketLocation()
+	assert.NoError(t, err)
+	monday := time.Date(2025, 3, 3, 8, 0, 0, 0, location)
+	tuesday := monday.AddDate(0, 0, 1).Add(8 * time.Hour)
+
+	// The league ended on Tuesday after the day's run, with its holdings closed and 10 final points
+	assert.NoError(t, db.Create(&models.League{ID: 1, LeagueName: "Ended", StartDate: monday, EndDate: tuesday, LeagueState: models.Completed}).Error)
+	assert.NoError(t, db.Create(&models.League{ID: 2, LeagueName: "Ending", StartDate: monday, EndDate: tuesday, LeagueState: models.PostDraft}).Error)
+	assert.NoError(t, db.Create(&models.Stock{ID: 1, TickerSymbol: "MSFT"}).Error)
+	for day, price := range []float64{100, 110, 121} {
+		assert.NoError(t, db.Create(&models.PriceHistory{StockID: 1, Price: price, Timestamp: monday.AddDate(0, 0, day)}).Error)
+	}
+	for id := uint(1); id <= 2; id++ {
+		assert.NoError(t, db.Create(&models.Portfolio{ID: id, LeagueID: id, Points: 10}).Error)
+		assert.NoError(t, db.Create(&models.OwnershipHistory{PortfolioID: id, StockID: 1, StartingValue: 100, CurrentValue: 110, StartDate: monday, EndDate: &tuesday}).Error)
+		assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: id, StockID: 1, StartingValue: 100, CurrentValue: 110, StartDate: monday, EndDate: &tuesday}).Error)
+		assert.NoError(t, db.Create(&models.PortfolioPointsHistory{PortfolioID: id, Points: 10, RecordedAt: tuesday.Add(-time.Hour)}).Error)
+	}
+
+	service := scoring.NewScoringService(scoring.NewScoringRepository(db))
+
+	// A completed league is refused and its points and history stay as they were
+	_, err = service.Recompute(1, "2025-03-03", "2025-03-05", false)
+	assert.ErrorContains(t, err, "has completed")
+	var stored models.Portfolio
+	assert.NoError(t, db.First(&stored, 1).Error)
+	assert.Equal(t, 10, stored.Points)
+	var history []models.PortfolioPointsHistory
+	assert.NoError(t, db.Where("portfolio_id = ?", 1).Find(&history).Error)
+	assert.Len(t, history, 1)
+	assert.Equal(t, 10, history[0].Points)
+
+	// A league not yet completed is only rescored up to its end date
+	result, err := service.Recompute(2, "2025-03-03", "2025-03-05", false)
+	assert.NoError(t, err)
+	assert.Equal(t, "2025-03-04", result.To)
+	var ending models.Portfolio
+	assert.NoError(t, db.First(&ending, 2).Error)
+	assert.Equal(t, 10, ending.Points)
+}
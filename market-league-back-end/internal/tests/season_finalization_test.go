@@ -0,0 +1,81 @@
+package tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/market-league/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleteLeague_FreezesSeasonAndArchivesResults(t *testing.T) {
+	db, leagueService, portfolioService := newLeagueServices(t)
+	stocks := []models.Stock{{TickerSymbol: "AAA"}, {TickerSymbol: "BBB"}, {TickerSymbol: "CCC"}}
+	for index := range stocks {
+		assert.NoError(t, db.Create(&stocks[index]).Error)
+	}
+	users := []*models.User{{Username: "ann", Password: "x"}, {Username: "bob", Password: "x"}, {Username: "cat", Password: "x"}}
+	assert.NoError(t, db.Create(&users).Error)
+
+	now := time.Now()
+	endDate := now.Add(-time.Hour).Truncate(time.Second)
+	ended := models.League{LeagueName: "Ended", StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now.AddDate(0, -1, 0), EndDate: endDate}
+	portfolios := createLeagueWithPlayers(t, db, &ended, users, models.DraftReady)
+	running := models.League{LeagueName: "Running", StartingSlots: 1, LeagueState: models.PostDraft, StartDate: now.AddDate(0, -1, 0), EndDate: now.AddDate(0, 0, 7)}
+	runningPortfolios := createLeagueWithPlayers(t, db, &running, users[:1], models.DraftReady)
+
+	// bob and cat tie for second behind ann
+	for index, change := range []float64{20, 10, 10} {
+		assert.NoError(t, db.Model(&portfolios[index]).Association("Stocks").Append(&stocks[index]))
+		assert.NoError(t, db.Create(&models.OwnershipHistory{PortfolioID: portfolios[index].ID, StockID: stocks[index].ID, StartingValue: 100, CurrentValue: 100 + change, StartDate: ended.StartDate}).Error)
+		assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: portfolios[index].ID, StockID: stocks[index].ID, StartingValue: 100, CurrentValue: 100 + change, StartDate: ended.StartDate}).Error)
+	}
+	assert.NoError(t, db.Model(&runningPortfolios[0]).Association("Stocks").Append(&stocks[0]))
+	assert.NoError(t, db.Create(&models.ScoringWindow{PortfolioID: runningPortfolios[0].ID, StockID: stocks[0].ID, StartingValue: 100, CurrentValue: 100, StartDate: running.StartDate}).Error)
+
+	_, err := leagueService.GetLeagueResults(ended.ID)
+	assert.ErrorContains(t, err, "not completed")
+	completed, err := leagueService.CompleteDueLeagues(now, "portfolio-totals:today", portfolioService)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, completed)
+
+	// Every holding is closed at the end date
+	var open int64
+	assert.NoError(t, db.Model(&models.OwnershipHistory{}).Where("end_date IS NULL").Count(&open).Error)
+	assert.Equal(t, int64(0), open)
+	var windows []models.ScoringWindow
+	assert.NoError(t, db.Where("portfolio_id IN ?", []uint{portfolios[0].ID, portfolios[1].ID, portfolios[2].ID}).Find(&windows).Error)
+	for _, window := range windows {
+		if assert.NotNil(t, window.EndDate) {
+			assert.True(t, window.EndDate.Equal(endDate))
+		}
+	}
+
+	// Later scoring runs leave the completed league alone
+	assert.NoError(t, db.Model(&models.ScoringWindow{}).Where("1 = 1").Update("current_value", 150).Error)
+	assert.NoError(t, portfolioService.CalculateAllPortfolioTotalValues("portfolio-totals:next-day"))
+	var annPortfolio, runningPortfolio models.Portfolio
+	assert.NoError(t, db.First(&annPortfolio, portfolios[0].ID).Error)
+	assert.Equal(t, 20, annPortfolio.Points)
+	assert.NoError(t, db.First(&runningPortfolio, runningPortfolios[0].ID).Error)
+	assert.Equal(t, 50, runningPortfolio.Points)
+
+	// The archived results list the final standings, rosters and champion
+	results, err := leagueService.GetLeagueResults(ended.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Ended", results.LeagueName)
+	if assert.Len(t, results.Standings, 3) {
+		assert.Equal(t, []int{1, 2, 2}, []int{results.Standings[0].Rank, results.Standings[1].Rank, results.Standings[2].Rank})
+		assert.Equal(t, []string{"ann", "bob", "cat"}, []string{results.Standings[0].Username, results.Standings[1].Username, results.Standings[2].Username})
+		assert.Equal(t, 20, results.Standings[0].Points)
+		assert.Len(t, results.Standings[0].Stocks, 1)
+	}
+	champions := 0
+	for _, award := range results.Awards {
+		if award.Award == models.Award_Champion {
+			champions++
+			assert.Equal(t, users[0].ID, award.UserID)
+		}
+	}
+	assert.Equal(t, 1, champions)
+}
@@ -0,0 +1,156 @@
+package tests
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/market-league/internal/models"
+	"github.com/market-league/internal/stock"
+	"github.com/market-league/internal/utils"
+	"github.com/market-league/internal/utils/testutils"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportUniverse_TickerChangesAndDelistings(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.OwnershipHistory{}, &models.UniverseMember{}, &models.TickerChange{}))
+	repo := stock.NewStockRepository(db)
+	service := stock.NewStockService(repo, nil)
+
+	result, err := service.ImportUniverse("index", strings.NewReader(
+		"symbol,name,sector,industry,exchange,market_cap,logo_url\n"+
+			"AAA,Alpha,Technology,Software,NASDAQ,\"1,500\",https://logos.example/aaa.png\n"+
+			"bbb,Beta,Energy,Oil & Gas,NYSE,900,\n"+
+			"CCC,Gamma,Financials,Banks,NYSE,300,\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"AAA", "BBB", "CCC"}, result.Created)
+
+	alpha, err := repo.GetStockByTicker("AAA")
+	assert.NoError(t, err)
+	assert.Equal(t, "Technology", alpha.Sector)
+	assert.Equal(t, "NASDAQ", alpha.Exchange)
+	assert.Equal(t, 1500.0, alpha.MarketCap)
+	assert.Equal(t, "https://logos.example/aaa.png", alpha.LogoURL)
+
+	beta, err := repo.GetStockByTicker("BBB")
+	assert.NoError(t, err)
+	assert.NoError(t, db.Create(&models.OwnershipHistory{PortfolioID: 1, StockID: beta.ID, StartingValue: 10, CurrentValue: 10}).Error)
+
+	// BBB becomes BBX, CCC is delisted, AAA leaves the index and DDD joins it
+	result, err = service.ImportUniverse("index", strings.NewReader(
+		"symbol,previous_symbol,name,status,effective_date\n"+
+			"BBX,BBB,,,2025-03-03\n"+
+			"CCC,,,delisted,2025-03-04\n"+
+			"DDD,,Delta,,\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"BBB->BBX"}, result.Renamed)
+	assert.Equal(t, []string{"CCC"}, result.Delisted)
+	assert.Equal(t, []string{"AAA"}, result.Removed)
+	assert.Equal(t, []string{"DDD"}, result.Created)
+
+	// The renamed stock keeps its ID and details, so ownership history still points at it
+	renamed, err := repo.GetStockByTicker("BBX")
+	assert.NoError(t, err)
+	assert.Equal(t, beta.ID, renamed.ID)
+	assert.Equal(t, "Beta", renamed.CompanyName)
+	var history models.OwnershipHistory
+	assert.NoError(t, db.Preload("Stock").First(&history).Error)
+	assert.Equal(t, "BBX", history.Stock.TickerSymbol)
+	var change models.TickerChange
+	assert.NoError(t, db.First(&change).Error)
+	assert.Equal(t, "BBB", change.OldSymbol)
+	assert.Equal(t, "2025-03-03", change.EffectiveDate.Format("2006-01-02"))
+
+	// Delisted stocks are kept but no longer quoted or drafted
+	gamma, err := repo.GetStockByTicker("CCC")
+	assert.NoError(t, err)
+	assert.NotNil(t, gamma.DelistedAt)
+	active, err := repo.GetActiveStocks()
+	assert.NoError(t, err)
+	activeSymbols := []string{}
+	for _, s := range active {
+		activeSymbols = append(activeSymbols, s.TickerSymbol)
+	}
+	assert.ElementsMatch(t, []string{"AAA", "BBX", "DDD"}, activeSymbols)
+
+	members, err := repo.GetUniverseStocks("index")
+	assert.NoError(t, err)
+	if assert.Len(t, members, 2) {
+		assert.Equal(t, "BBX", members[0].TickerSymbol)
+		assert.Equal(t, "DDD", members[1].TickerSymbol)
+	}
+
+	// A ticker change onto a symbol that already exists is left for a person to sort out
+	result, err = service.ImportUniverse("index", strings.NewReader("symbol,previous_symbol\nDDD,BBX\n"))
+	assert.NoError(t, err)
+	if assert.Len(t, result.Skipped, 1) {
+		assert.Equal(t, 2, result.Skipped[0].Line)
+	}
+	assert.Empty(t, result.Renamed)
+}
+
+func TestParseUniverseCSV_Errors(t *testing.T) {
+	_, err := stock.ParseUniverseCSV(strings.NewReader("name,sector\nAlpha,Technology\n"))
+	assert.Error(t, err)
+	_, err = stock.ParseUniverseCSV(strings.NewReader("symbol,status\nAAA,suspended\n"))
+	assert.Error(t, err)
+	_, err = stock.ParseUniverseCSV(strings.NewReader("symbol,market_cap\nAAA,lots\n"))
+	assert.Error(t, err)
+}
+
+func TestImportUniverse_FutureChangesWaitForTheirEffectiveDate(t *testing.T) {
+	db := testutils.SetupTestDB()
+	assert.NoError(t, db.AutoMigrate(&models.UniverseMember{}, &models.TickerChange{}))
+	repo := stock.NewStockRepository(db)
+	service := stock.NewStockService(repo, nil)
+
+	_, err := service.ImportUniverse("index", strings.NewReader("symbol,name\nAAA,Alpha\nBBB,Beta\n"))
+	assert.NoError(t, err)
+
+	// AAA becomes AAX and BBB is delisted next week
+	nextWeek := utils.Now().AddDate(0, 0, 7).Format("2006-01-02")
+	csv := "symbol,previous_symbol,status,effective_date\n" +
+		"AAX,AAA,," + nextWeek + "\n" +
+		"BBB,,delisted," + nextWeek + "\n"
+	result, err := service.ImportUniverse("index", strings.NewReader(csv))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Renamed)
+	assert.Empty(t, result.Delisted)
+	assert.Len(t, result.Pending, 2)
+
+	// Until then both keep trading as before, and importing the announcement again does not repeat it
+	_, err = service.ImportUniverse("index", strings.NewReader(csv))
+	assert.NoError(t, err)
+	alpha, err := repo.GetStockByTicker("AAA")
+	assert.NoError(t, err)
+	members, err := repo.GetUniverseStocks("index")
+	assert.NoError(t, err)
+	assert.Len(t, members, 2)
+	var pending int64
+	db.Model(&models.TickerChange{}).Where("pending = ?", true).Count(&pending)
+	assert.Equal(t, int64(1), pending)
+
+	renamed, delisted, err := repo.ApplyPendingUniverseChanges(utils.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, renamed)
+	assert.Equal(t, 0, delisted)
+
+	// Once the date comes the stock keeps its ID under the new symbol and the delisted one leaves the universe
+	renamed, delisted, err = repo.ApplyPendingUniverseChanges(utils.Now().AddDate(0, 0, 8))
+	assert.NoError(t, err)
+	assert.Equal(t, 1, renamed)
+	assert.Equal(t, 1, delisted)
+
+	moved, err := repo.GetStockByTicker("AAX")
+	assert.NoError(t, err)
+	assert.Equal(t, alpha.ID, moved.ID)
+	beta, err := repo.GetStockByTicker("BBB")
+	assert.NoError(t, err)
+	assert.NotNil(t, beta.DelistedAt)
+	assert.Nil(t, beta.DelistsAt)
+	members, err = repo.GetUniverseStocks("index")
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "AAX", members[0].TickerSymbol)
+	}
+}
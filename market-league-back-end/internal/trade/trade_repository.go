@@ -87,6 +87,15 @@ func (r *TradeRepository) GetTradeByID(tradeID uint) (*models.Trade, error) {
 	return &trade, nil
 }
 
+// GetLeague fetches the league a trade belongs to
+func (r *TradeRepository) GetLeague(leagueID uint) (*models.League, error) {
+	var league models.League
+	if err := r.db.First(&league, leagueID).Error; err != nil {
+		return nil, fmt.Errorf("failed to find league with ID %d: %w", leagueID, err)
+	}
+	return &league, nil
+}
+
 func (r *TradeRepository) UpdateTrade(trade *models.Trade) error {
 	return r.db.Save(trade).Error
 }
@@ -2,14 +2,18 @@ package trade
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"time"
 
+	"github.com/market-league/internal/lineup"
 	"github.com/market-league/internal/models"
 	ownership_history "github.com/market-league/internal/ownership_history"
 	"github.com/market-league/internal/portfolio"
+	"github.com/market-league/internal/roster"
 	"github.com/market-league/internal/stock"
 	"github.com/market-league/internal/user"
+	"github.com/market-league/internal/utils"
 	"gorm.io/gorm"
 )
 
@@ -20,6 +24,7 @@ type TradeService struct {
 	PortfolioRepo       *portfolio.PortfolioRepository
 	UserRepo            *user.UserRepository
 	OwnerHistoryService ownership_history.OwnershipHistoryServiceInterface
+	LineupService       lineup.LineupServiceInterface
 }
 
 // NewTradeService creates a new instance of TradeService
@@ -29,6 +34,7 @@ func NewTradeService(
 	portfolioRepo *portfolio.PortfolioRepository,
 	userRepo *user.UserRepository,
 	ownerHistoryService ownership_history.OwnershipHistoryServiceInterface,
+	lineupService lineup.LineupServiceInterface,
 ) *TradeService {
 	return &TradeService{
 		TradeRepo:           tradeRepo,
@@ -36,11 +42,16 @@ func NewTradeService(
 		PortfolioRepo:       portfolioRepo,
 		UserRepo:            userRepo,
 		OwnerHistoryService: ownerHistoryService,
+		LineupService:       lineupService,
 	}
 }
 
 // CreateTrade initializes a new trade between two users.
 func (s *TradeService) CreateTrade(leagueID, user1ID, user2ID uint, stocks1IDs, stocks2IDs []uint) (*models.SanitizedTrade, error) {
+	// No new trades once the league's last trading day has closed
+	if err := s.checkTradeDeadline(leagueID); err != nil {
+		return nil, err
+	}
 
 	// Fetch stock details from the repository
 	stocks1, err := s.StockRepo.GetStocksByIDs(stocks1IDs)
@@ -78,6 +89,11 @@ func (s *TradeService) CreateTrade(leagueID, user1ID, user2ID uint, stocks1IDs,
 	}
 	log.Printf("Portfolio 2: %v", portfolio2ID)
 
+	// Reject trades that would leave either roster unable to fill its sector positions
+	if err := s.validateTradeRosters(portfolio1ID, portfolio2ID, stocks1, stocks2); err != nil {
+		return nil, err
+	}
+
 	trade := &models.Trade{
 		LeagueID:     leagueID,
 		User1:        user1,
@@ -89,8 +105,8 @@ func (s *TradeService) CreateTrade(leagueID, user1ID, user2ID uint, stocks1IDs,
 		Stocks1:      stocks1,
 		Stocks2:      stocks2,
 		Status:       "pending",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
+		CreatedAt:    utils.Now(),
+		UpdatedAt:    utils.Now(),
 	}
 
 	if err := s.TradeRepo.CreateTrade(trade); err != nil {
@@ -160,6 +176,11 @@ func (s *TradeService) ConfirmTrade(tradeID uint, userID uint) error {
 		return errors.New("trade is already confirmed")
 	}
 
+	// Pending trades expire with the trade deadline
+	if err := s.checkTradeDeadline(trade.LeagueID); err != nil {
+		return err
+	}
+
 	// Determine which user is confirming and update the respective flag
 	if trade.User1ID == userID {
 		if trade.User1Confirmed {
@@ -182,12 +203,16 @@ func (s *TradeService) ConfirmTrade(tradeID uint, userID uint) error {
 
 	// If both users have confirmed, execute the trade
 	if trade.User1Confirmed && trade.User2Confirmed {
+		// Rosters may have changed since the trade was proposed
+		if err := s.validateTradeRosters(trade.Portfolio1ID, trade.Portfolio2ID, trade.Stocks1, trade.Stocks2); err != nil {
+			return err
+		}
 		if err := s.TradeRepo.SwapStocks(trade); err != nil {
 			return err
 		}
 		// Update the ownership history of the stocks
 		var user1Stocks []models.Stock = trade.Stocks1
-		currentTime := time.Now()
+		currentTime := utils.Now()
 		for _, stock := range user1Stocks {
 			// End ownership for User1
 			s.OwnerHistoryService.UpdateOwnershipHistory(trade.Portfolio1ID, stock.ID, stock.CurrentPrice, &currentTime)
@@ -201,7 +226,78 @@ func (s *TradeService) ConfirmTrade(tradeID uint, userID uint) error {
 			// Create ownership for User1
 			s.OwnerHistoryService.CreateOwnershipHistory(trade.Portfolio1ID, stock.ID, stock.CurrentPrice, currentTime)
 		}
+
+		// Take the traded stocks out of both lineups before slotting them in, so freed starting slots can be reused
+		for _, stock := range user1Stocks {
+			if err := s.LineupService.ReleaseStock(trade.Portfolio1ID, stock.ID, stock.CurrentPrice, currentTime); err != nil {
+				log.Printf("error releasing stock %d from lineup: %v", stock.ID, err)
+			}
+		}
+		for _, stock := range user2Stocks {
+			if err := s.LineupService.ReleaseStock(trade.Portfolio2ID, stock.ID, stock.CurrentPrice, currentTime); err != nil {
+				log.Printf("error releasing stock %d from lineup: %v", stock.ID, err)
+			}
+		}
+		for _, stock := range user1Stocks {
+			if err := s.LineupService.AssignStock(trade.Portfolio2ID, stock.ID, stock.CurrentPrice, currentTime); err != nil {
+				log.Printf("error assigning stock %d to lineup: %v", stock.ID, err)
+			}
+		}
+		for _, stock := range user2Stocks {
+			if err := s.LineupService.AssignStock(trade.Portfolio1ID, stock.ID, stock.CurrentPrice, currentTime); err != nil {
+				log.Printf("error assigning stock %d to lineup: %v", stock.ID, err)
+			}
+		}
 	}
 
 	return nil
 }
+
+// TradeDeadline is the close of the last trading day on or before a league's end date, in the market timezone
+func TradeDeadline(endDate time.Time) (time.Time, error) {
+	location, err := utils.MarketLocation()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error loading time location: %v", err)
+	}
+	_, close := utils.MarketHours(utils.LastTradingDay(endDate.In(location)))
+	return close, nil
+}
+
+// checkTradeDeadline rejects trades in a league whose rosters are locked or whose trade deadline has passed
+func (s *TradeService) checkTradeDeadline(leagueID uint) error {
+	league, err := s.TradeRepo.GetLeague(leagueID)
+	if err != nil {
+		return err
+	}
+	if err := roster.CheckUnlocked(league); err != nil {
+		return err
+	}
+	deadline, err := TradeDeadline(league.EndDate)
+	if err != nil {
+		return err
+	}
+	if !utils.Now().Before(deadline) {
+		return fmt.Errorf("the trade deadline passed at %s", deadline.Format("2006-01-02 15:04 MST"))
+	}
+	return nil
+}
+
+// validateTradeRosters checks that both portfolios can still fill their sector positions after the trade
+func (s *TradeService) validateTradeRosters(portfolio1ID, portfolio2ID uint, stocks1, stocks2 []models.Stock) error {
+	portfolio1, err := s.PortfolioRepo.GetPortfolioWithID(portfolio1ID)
+	if err != nil {
+		return err
+	}
+	portfolio2, err := s.PortfolioRepo.GetPortfolioWithID(portfolio2ID)
+	if err != nil {
+		return err
+	}
+
+	if err := roster.ValidateRoster(&portfolio1.League, roster.ApplyMove(portfolio1.Stocks, stocks1, stocks2)); err != nil {
+		return fmt.Errorf("%s: %v", portfolio1.User.Username, err)
+	}
+	if err := roster.ValidateRoster(&portfolio2.League, roster.ApplyMove(portfolio2.Stocks, stocks2, stocks1)); err != nil {
+		return fmt.Errorf("%s: %v", portfolio2.User.Username, err)
+	}
+	return nil
+}
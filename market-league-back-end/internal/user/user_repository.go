@@ -72,3 +72,22 @@ func (r *UserRepository) GetUserPortfolios(userID uint) ([]models.Portfolio, err
 	}
 	return portfolios, nil
 }
+
+// IsAdmin reports whether the user may run admin operations.
+func (r *UserRepository) IsAdmin(userID uint) (bool, error) {
+	var count int64
+	err := r.db.Model(&models.User{}).Where("id = ? AND is_admin = ?", userID, true).Count(&count).Error
+	if err != nil {
+		return false, fmt.Errorf("failed to check admin status of user %d: %w", userID, err)
+	}
+	return count > 0, nil
+}
+
+// GrantAdmin marks the users with the given usernames as admins.
+func (r *UserRepository) GrantAdmin(usernames []string) (int64, error) {
+	result := r.db.Model(&models.User{}).Where("username IN ?", usernames).Update("is_admin", true)
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to grant admin: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
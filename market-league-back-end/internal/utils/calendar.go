@@ -0,0 +1,181 @@
+package utils
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// calendarDateFormat is the YYYY-MM-DD layout of calendar dates
+const calendarDateFormat = "2006-01-02"
+
+// Calendar entry types
+const (
+	CalendarHoliday    = "holiday"
+	CalendarEarlyClose = "early_close"
+)
+
+//go:embed market_calendar.csv
+var builtinCalendarCSV string
+
+// MarketCalendar knows the days the exchange is closed or closes early, by market date
+type MarketCalendar struct {
+	holidays    map[string]string
+	earlyCloses map[string]earlyClose
+}
+
+// earlyClose is a shortened session, closing at hour:minute market time
+type earlyClose struct {
+	hour   int
+	minute int
+	name   string
+}
+
+// NewMarketCalendar creates a calendar where every weekday trades
+func NewMarketCalendar() *MarketCalendar {
+	return &MarketCalendar{
+		holidays:    make(map[string]string),
+		earlyCloses: make(map[string]earlyClose),
+	}
+}
+
+// AddHoliday closes the market for the whole of a YYYY-MM-DD date
+func (c *MarketCalendar) AddHoliday(date string, name string) error {
+	if _, err := time.Parse(calendarDateFormat, date); err != nil {
+		return fmt.Errorf("invalid holiday date %q: %w", date, err)
+	}
+	c.holidays[date] = name
+	return nil
+}
+
+// AddEarlyClose closes the market at hour:minute market time on a YYYY-MM-DD date
+func (c *MarketCalendar) AddEarlyClose(date string, hour int, minute int, name string) error {
+	if _, err := time.Parse(calendarDateFormat, date); err != nil {
+		return fmt.Errorf("invalid early close date %q: %w", date, err)
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 {
+		return fmt.Errorf("invalid early close time %02d:%02d on %s", hour, minute, date)
+	}
+	c.earlyCloses[date] = earlyClose{hour: hour, minute: minute, name: name}
+	return nil
+}
+
+// Holiday returns the name of the holiday on the day of t, which should be in the market timezone
+func (c *MarketCalendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[t.Format(calendarDateFormat)]
+	return name, ok
+}
+
+// EarlyClose returns when the market closes on the day of t if it closes early
+func (c *MarketCalendar) EarlyClose(t time.Time) (time.Time, bool) {
+	early, ok := c.earlyCloses[t.Format(calendarDateFormat)]
+	if !ok {
+		return time.Time{}, false
+	}
+	return time.Date(t.Year(), t.Month(), t.Day(), early.hour, early.minute, 0, 0, t.Location()), true
+}
+
+// ReadMarketCalendarCSV reads date, type, close and name columns, where type is holiday or early_close and
+// close is the HH:MM early closing time in market time. Lines starting with # are comments.
+func ReadMarketCalendarCSV(reader io.Reader) (*MarketCalendar, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.Comment = '#'
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read calendar header: %w", err)
+	}
+	columns := make(map[string]int)
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, name := range []string{"date", "type"} {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("calendar is missing the %q column", name)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	calendar := NewMarketCalendar()
+	for {
+		record, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read calendar: %w", err)
+		}
+
+		date, name := field(record, "date"), field(record, "name")
+		switch strings.ToLower(field(record, "type")) {
+		case CalendarHoliday:
+			err = calendar.AddHoliday(date, name)
+		case CalendarEarlyClose:
+			closeAt, parseErr := time.Parse("15:04", field(record, "close"))
+			if parseErr != nil {
+				return nil, fmt.Errorf("invalid early close time on %s: %w", date, parseErr)
+			}
+			err = calendar.AddEarlyClose(date, closeAt.Hour(), closeAt.Minute(), name)
+		default:
+			err = fmt.Errorf("unknown calendar entry type %q on %s", field(record, "type"), date)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return calendar, nil
+}
+
+// LoadMarketCalendar reads a calendar CSV file
+func LoadMarketCalendar(path string) (*MarketCalendar, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open calendar %s: %w", path, err)
+	}
+	defer file.Close()
+	return ReadMarketCalendarCSV(file)
+}
+
+// BuiltinCalendar is the NYSE calendar shipped with the backend
+func BuiltinCalendar() *MarketCalendar {
+	calendar, err := ReadMarketCalendarCSV(strings.NewReader(builtinCalendarCSV))
+	if err != nil {
+		panic(fmt.Sprintf("built-in market calendar is invalid: %v", err))
+	}
+	return calendar
+}
+
+var (
+	calendarMutex   sync.RWMutex
+	currentCalendar = BuiltinCalendar()
+)
+
+// SetCalendar replaces the calendar that market rules consult, nil restores the built-in one
+func SetCalendar(calendar *MarketCalendar) {
+	calendarMutex.Lock()
+	defer calendarMutex.Unlock()
+	if calendar == nil {
+		calendar = BuiltinCalendar()
+	}
+	currentCalendar = calendar
+}
+
+// Calendar is the calendar that market rules consult
+func Calendar() *MarketCalendar {
+	calendarMutex.RLock()
+	defer calendarMutex.RUnlock()
+	return currentCalendar
+}
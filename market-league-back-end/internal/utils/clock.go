@@ -0,0 +1,72 @@
+package utils
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells the time that market rules run on, so a replay can drive them on simulated time
+type Clock interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+// systemClock is the wall clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time        { return time.Now() }
+func (systemClock) Sleep(d time.Duration) { time.Sleep(d) }
+
+var (
+	clockMutex   sync.RWMutex
+	currentClock Clock = systemClock{}
+)
+
+// SetClock replaces the clock returned by Now, nil restores the wall clock
+func SetClock(clock Clock) {
+	clockMutex.Lock()
+	defer clockMutex.Unlock()
+	if clock == nil {
+		clock = systemClock{}
+	}
+	currentClock = clock
+}
+
+// Now is the current market time
+func Now() time.Time {
+	clockMutex.RLock()
+	defer clockMutex.RUnlock()
+	return currentClock.Now()
+}
+
+// Sleep waits for d of market time to pass
+func Sleep(d time.Duration) {
+	clockMutex.RLock()
+	clock := currentClock
+	clockMutex.RUnlock()
+	clock.Sleep(d)
+}
+
+// SimulatedClock starts at a chosen moment and runs speed times faster than the wall clock
+type SimulatedClock struct {
+	start     time.Time
+	realStart time.Time
+	speed     float64
+}
+
+// NewSimulatedClock creates a clock that reads start now and advances speed seconds per real second
+func NewSimulatedClock(start time.Time, speed float64) *SimulatedClock {
+	if speed <= 0 {
+		speed = 1
+	}
+	return &SimulatedClock{start: start, realStart: time.Now(), speed: speed}
+}
+
+func (c *SimulatedClock) Now() time.Time {
+	elapsed := time.Since(c.realStart)
+	return c.start.Add(time.Duration(float64(elapsed) * c.speed))
+}
+
+func (c *SimulatedClock) Sleep(d time.Duration) {
+	time.Sleep(time.Duration(float64(d) / c.speed))
+}
@@ -0,0 +1,12 @@
+package utils
+
+import "math"
+
+// PercentChange is the percent return from a starting to an ending value that points are scored on
+func PercentChange(startingValue float64, endingValue float64) float64 {
+	// Check for 0 and replace with 1 to avoid infinity
+	if startingValue == 0 {
+		startingValue = 1
+	}
+	return ((endingValue - startingValue) / math.Abs(startingValue)) * 100
+}
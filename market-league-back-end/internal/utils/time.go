@@ -0,0 +1,90 @@
+package utils
+
+import "time"
+
+// MarketTimezone is the timezone the scheduler and all market-clock rules run on
+const MarketTimezone = "America/Chicago"
+
+// DailyRunHour and DailyRunMinute are when, in the market timezone, the scheduler records the day's official
+// prices and points, half an hour after the close so closing prices have settled
+const (
+	DailyRunHour   = 15
+	DailyRunMinute = 30
+)
+
+// MarketOpenHour and MarketOpenMinute are when regular trading opens in the market timezone
+const (
+	MarketOpenHour   = 8
+	MarketOpenMinute = 30
+)
+
+// MarketCloseHour is when regular trading closes in the market timezone
+const MarketCloseHour = 15
+
+// MarketLocation loads the market timezone
+func MarketLocation() (*time.Location, error) {
+	return time.LoadLocation(MarketTimezone)
+}
+
+// IsTradingDay reports whether the market trades on the day of t, which should be in the market timezone.
+// Weekends and the holidays of the market calendar are not trading days.
+func IsTradingDay(t time.Time) bool {
+	if t.Weekday() == time.Saturday || t.Weekday() == time.Sunday {
+		return false
+	}
+	_, holiday := Calendar().Holiday(t)
+	return !holiday
+}
+
+// MarketHours returns when trading opens and closes on the day of t, which should be in the market timezone.
+// The close is earlier on the half days of the market calendar.
+func MarketHours(t time.Time) (time.Time, time.Time) {
+	open := time.Date(t.Year(), t.Month(), t.Day(), MarketOpenHour, MarketOpenMinute, 0, 0, t.Location())
+	if close, ok := Calendar().EarlyClose(t); ok {
+		return open, close
+	}
+	close := time.Date(t.Year(), t.Month(), t.Day(), MarketCloseHour, 0, 0, 0, t.Location())
+	return open, close
+}
+
+// DailyRunTime is when the scheduler records the official prices and points of the day of t
+func DailyRunTime(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), DailyRunHour, DailyRunMinute, 0, 0, t.Location())
+}
+
+// NextDailyRun returns the first daily run after t, on a trading day
+func NextDailyRun(t time.Time) time.Time {
+	for day := t; ; day = day.AddDate(0, 0, 1) {
+		if run := DailyRunTime(day); IsTradingDay(day) && run.After(t) {
+			return run
+		}
+	}
+}
+
+// LastTradingDay returns midnight of the latest trading day on or before the day of t
+func LastTradingDay(t time.Time) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	for !IsTradingDay(day) {
+		day = day.AddDate(0, 0, -1)
+	}
+	return day
+}
+
+// IsMarketOpen reports whether t falls within regular trading hours
+func IsMarketOpen(t time.Time) bool {
+	if !IsTradingDay(t) {
+		return false
+	}
+	open, close := MarketHours(t)
+	return !t.Before(open) && t.Before(close)
+}
+
+// NextMarketOpen returns the first time trading opens after t
+func NextMarketOpen(t time.Time) time.Time {
+	for day := t; ; day = day.AddDate(0, 0, 1) {
+		open, _ := MarketHours(day)
+		if IsTradingDay(day) && open.After(t) {
+			return open
+		}
+	}
+}
@@ -1,13 +1,34 @@
 package main
 
 import (
+	"log"
+	"os"
+
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 	"github.com/market-league/api"
 	"github.com/market-league/internal/db"
+	"github.com/market-league/internal/utils"
 )
 
 func main() {
+	// Swap in another exchange calendar before anything consults it
+	if path := os.Getenv("MARKET_CALENDAR_PATH"); path != "" {
+		calendar, err := utils.LoadMarketCalendar(path)
+		if err != nil {
+			log.Fatalf("Failed to load market calendar: %v", err)
+		}
+		utils.SetCalendar(calendar)
+	}
+
+	// Maintenance subcommands run against the database and exit
+	if handled, err := runCommand(os.Args[1:]); handled {
+		if err != nil {
+			log.Fatalf("%s failed: %v", os.Args[1], err)
+		}
+		return
+	}
+
 	// Initialize the database
 	db.InitDB()
 	// Initializes Gin router instance with default middleware attached